@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"time"
 
 	"github.com/NetSys/quilt/api"
 	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -30,31 +33,83 @@ type Client interface {
 	Close() error
 
 	// QueryMachines retrieves the machines tracked by the Quilt daemon.
-	QueryMachines() ([]db.Machine, error)
+	QueryMachines(filters ...Filter) ([]db.Machine, error)
 
 	// QueryContainers retrieves the containers tracked by the Quilt daemon.
-	QueryContainers() ([]db.Container, error)
+	QueryContainers(filters ...Filter) ([]db.Container, error)
 
 	// QueryEtcd retrieves the etcd information tracked by the Quilt daemon.
-	QueryEtcd() ([]db.Etcd, error)
+	QueryEtcd(filters ...Filter) ([]db.Etcd, error)
 
 	// QueryConnections retrieves the connection information tracked by the
 	// Quilt daemon.
-	QueryConnections() ([]db.Connection, error)
+	QueryConnections(filters ...Filter) ([]db.Connection, error)
 
 	// QueryLabels retrieves the label information tracked by the Quilt daemon.
-	QueryLabels() ([]db.Label, error)
+	QueryLabels(filters ...Filter) ([]db.Label, error)
 
 	// QueryClusters retrieves cluster information tracked by the Quilt daemon.
-	QueryClusters() ([]db.Cluster, error)
+	QueryClusters(filters ...Filter) ([]db.Cluster, error)
 
-	// Deploy makes a request to the Quilt daemon to deploy the given deployment.
-	Deploy(deployment string) error
+	// QueryPlacements retrieves the placement constraints tracked by the Quilt
+	// daemon.
+	QueryPlacements(filters ...Filter) ([]db.Placement, error)
+
+	// QueryACLs retrieves the ACL information tracked by the Quilt daemon.
+	QueryACLs(filters ...Filter) ([]db.ACL, error)
+
+	// QueryMinions retrieves the minion information tracked by the Quilt daemon.
+	QueryMinions(filters ...Filter) ([]db.Minion, error)
+
+	// QueryDeployments retrieves the deployment history of the Quilt daemon.
+	QueryDeployments(filters ...Filter) ([]db.Deployment, error)
+
+	// QueryHistory retrieves the recent transactions committed to the database of
+	// the Quilt daemon.  If 'table' is non-empty, only the changes made to that
+	// table are included.
+	QueryHistory(table db.TableType) ([]db.TxnRecord, error)
+
+	// Watch streams the changes committed to 'tables' on the Quilt daemon, or to
+	// every table if none are given.  The first ChangeSet lists every row in the
+	// tables as inserted, and each subsequent ChangeSet describes a single
+	// transaction.  The returned channel is closed once 'stop' is closed or the
+	// stream fails.
+	Watch(stop <-chan struct{}, tables ...db.TableType) (<-chan db.ChangeSet,
+		error)
+
+	// Deploy makes a request to the Quilt daemon to deploy the given deployment,
+	// recording 'message' in the deployment history.  It returns the revision
+	// assigned to the deployment.
+	Deploy(deployment, message string) (int, error)
+
+	// PlanDeployment asks the Quilt daemon for the changes that deploying the
+	// given deployment would make, without deploying it.
+	PlanDeployment(deployment string) (engine.Plan, error)
+
+	// Drain marks the minion the Client is connected to as draining, so that its
+	// containers are migrated to other minions, or clears the mark if 'draining'
+	// is false.
+	Drain(draining bool) error
 
 	// Host returns the server address the Client is connected to.
 	Host() string
 }
 
+// A Filter restricts the rows returned by a query, so that only the rows of interest
+// are sent by the Quilt daemon.  When multiple Filters are passed to a query, a row
+// must satisfy all of them to be returned.
+type Filter struct {
+	// Fields maps the name of a row's field to the values it may take.  A row
+	// matches if, for every entry, the string representation of the field is one
+	// of the listed values.  Slice fields match if any of their elements do.
+	Fields map[string][]string
+
+	// Offset and Limit select a window of the matching rows, ordered by ID.  A
+	// Limit of zero returns all rows after the Offset.
+	Offset int
+	Limit  int
+}
+
 // Getter provides methods for obtaining Quilt clients connected to various servers.
 type Getter interface {
 	// Client obtains a client connected to the given address.
@@ -74,17 +129,27 @@ type clientImpl struct {
 	serverHost string
 }
 
-// New creates a new Quilt client connected to `lAddr`.
-func New(lAddr string) (Client, error) {
+// New creates a new Quilt client connected to `lAddr`.  Connections over TCP are
+// authenticated with 'creds', unless it's empty.
+func New(lAddr string, creds auth.Credentials) (Client, error) {
 	proto, addr, err := api.ParseListenAddress(lAddr)
 	if err != nil {
 		return nil, err
 	}
 
+	if proto != "tcp" {
+		creds = auth.Credentials{}
+	}
+
+	credsOpt, err := creds.DialOption()
+	if err != nil {
+		return nil, err
+	}
+
 	dialer := func(dialAddr string, t time.Duration) (net.Conn, error) {
 		return net.DialTimeout(proto, dialAddr, t)
 	}
-	cc, err := grpc.Dial(addr, grpc.WithDialer(dialer), grpc.WithInsecure(),
+	cc, err := grpc.Dial(addr, grpc.WithDialer(dialer), credsOpt,
 		grpc.WithBlock(), grpc.WithTimeout(connectTimeout))
 	if err != nil {
 		return nil, err
@@ -99,9 +164,11 @@ func New(lAddr string) (Client, error) {
 	}, err
 }
 
-func query(pbClient pb.APIClient, table db.TableType) (interface{}, error) {
+func query(pbClient pb.APIClient, table db.TableType, filters []Filter) (
+	interface{}, error) {
+
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	reply, err := pbClient.Query(ctx, &pb.DBQuery{Table: string(table)})
+	reply, err := pbClient.Query(ctx, toDBQuery(table, filters))
 	if err != nil {
 		return nil, err
 	}
@@ -144,19 +211,70 @@ func query(pbClient pb.APIClient, table db.TableType) (interface{}, error) {
 			return nil, err
 		}
 		return clusters, nil
+	case db.PlacementTable:
+		var placements []db.Placement
+		if err := json.Unmarshal(replyBytes, &placements); err != nil {
+			return nil, err
+		}
+		return placements, nil
+	case db.ACLTable:
+		var acls []db.ACL
+		if err := json.Unmarshal(replyBytes, &acls); err != nil {
+			return nil, err
+		}
+		return acls, nil
+	case db.MinionTable:
+		var minions []db.Minion
+		if err := json.Unmarshal(replyBytes, &minions); err != nil {
+			return nil, err
+		}
+		return minions, nil
+	case db.DeploymentTable:
+		var deployments []db.Deployment
+		if err := json.Unmarshal(replyBytes, &deployments); err != nil {
+			return nil, err
+		}
+		return deployments, nil
 	default:
 		panic(fmt.Sprintf("unsupported table type: %s", table))
 	}
 }
 
+// toDBQuery combines 'filters' into a single query of 'table'.  The offset and limit
+// of the last Filter that sets them take precedence.
+func toDBQuery(table db.TableType, filters []Filter) *pb.DBQuery {
+	dbQuery := &pb.DBQuery{Table: string(table)}
+	for _, f := range filters {
+		var fields []string
+		for field := range f.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			dbQuery.Filters = append(dbQuery.Filters,
+				&pb.Filter{Field: field, Values: f.Fields[field]})
+		}
+
+		if f.Offset != 0 {
+			dbQuery.Offset = int32(f.Offset)
+		}
+
+		if f.Limit != 0 {
+			dbQuery.Limit = int32(f.Limit)
+		}
+	}
+	return dbQuery
+}
+
 // Close the grpc connection.
 func (c clientImpl) Close() error {
 	return c.cc.Close()
 }
 
 // QueryMachines retrieves the machines tracked by the Quilt daemon.
-func (c clientImpl) QueryMachines() ([]db.Machine, error) {
-	rows, err := query(c.pbClient, db.MachineTable)
+func (c clientImpl) QueryMachines(filters ...Filter) ([]db.Machine, error) {
+	rows, err := query(c.pbClient, db.MachineTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -165,8 +283,8 @@ func (c clientImpl) QueryMachines() ([]db.Machine, error) {
 }
 
 // QueryContainers retrieves the containers tracked by the Quilt daemon.
-func (c clientImpl) QueryContainers() ([]db.Container, error) {
-	rows, err := query(c.pbClient, db.ContainerTable)
+func (c clientImpl) QueryContainers(filters ...Filter) ([]db.Container, error) {
+	rows, err := query(c.pbClient, db.ContainerTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -175,8 +293,8 @@ func (c clientImpl) QueryContainers() ([]db.Container, error) {
 }
 
 // QueryEtcd retrieves the etcd information tracked by the Quilt daemon.
-func (c clientImpl) QueryEtcd() ([]db.Etcd, error) {
-	rows, err := query(c.pbClient, db.EtcdTable)
+func (c clientImpl) QueryEtcd(filters ...Filter) ([]db.Etcd, error) {
+	rows, err := query(c.pbClient, db.EtcdTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -185,8 +303,8 @@ func (c clientImpl) QueryEtcd() ([]db.Etcd, error) {
 }
 
 // QueryConnections retrieves the connection information tracked by the Quilt daemon.
-func (c clientImpl) QueryConnections() ([]db.Connection, error) {
-	rows, err := query(c.pbClient, db.ConnectionTable)
+func (c clientImpl) QueryConnections(filters ...Filter) ([]db.Connection, error) {
+	rows, err := query(c.pbClient, db.ConnectionTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -195,8 +313,8 @@ func (c clientImpl) QueryConnections() ([]db.Connection, error) {
 }
 
 // QueryLabels retrieves the label information tracked by the Quilt daemon.
-func (c clientImpl) QueryLabels() ([]db.Label, error) {
-	rows, err := query(c.pbClient, db.LabelTable)
+func (c clientImpl) QueryLabels(filters ...Filter) ([]db.Label, error) {
+	rows, err := query(c.pbClient, db.LabelTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -205,8 +323,8 @@ func (c clientImpl) QueryLabels() ([]db.Label, error) {
 }
 
 // QueryClusters retrieves the cluster information tracked by the Quilt daemon.
-func (c clientImpl) QueryClusters() ([]db.Cluster, error) {
-	rows, err := query(c.pbClient, db.ClusterTable)
+func (c clientImpl) QueryClusters(filters ...Filter) ([]db.Cluster, error) {
+	rows, err := query(c.pbClient, db.ClusterTable, filters)
 	if err != nil {
 		return nil, err
 	}
@@ -214,10 +332,96 @@ func (c clientImpl) QueryClusters() ([]db.Cluster, error) {
 	return rows.([]db.Cluster), nil
 }
 
-// Deploy makes a request to the Quilt daemon to deploy the given deployment.
-func (c clientImpl) Deploy(deployment string) error {
+// QueryPlacements retrieves the placement constraints tracked by the Quilt daemon.
+func (c clientImpl) QueryPlacements(filters ...Filter) ([]db.Placement, error) {
+	rows, err := query(c.pbClient, db.PlacementTable, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Placement), nil
+}
+
+// QueryACLs retrieves the ACL information tracked by the Quilt daemon.
+func (c clientImpl) QueryACLs(filters ...Filter) ([]db.ACL, error) {
+	rows, err := query(c.pbClient, db.ACLTable, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.ACL), nil
+}
+
+// QueryMinions retrieves the minion information tracked by the Quilt daemon.
+func (c clientImpl) QueryMinions(filters ...Filter) ([]db.Minion, error) {
+	rows, err := query(c.pbClient, db.MinionTable, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Minion), nil
+}
+
+// QueryDeployments retrieves the deployment history of the Quilt daemon.
+func (c clientImpl) QueryDeployments(filters ...Filter) ([]db.Deployment, error) {
+	rows, err := query(c.pbClient, db.DeploymentTable, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows.([]db.Deployment), nil
+}
+
+// QueryHistory retrieves the recent transactions committed to the database of the
+// Quilt daemon.
+func (c clientImpl) QueryHistory(table db.TableType) ([]db.TxnRecord, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.QueryHistory(ctx,
+		&pb.HistoryQuery{Table: string(table)})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []db.TxnRecord
+	if err := json.Unmarshal([]byte(reply.Records), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Deploy makes a request to the Quilt daemon to deploy the given deployment,
+// recording 'message' in the deployment history.  It returns the revision assigned
+// to the deployment.
+func (c clientImpl) Deploy(deployment, message string) (int, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.Deploy(ctx,
+		&pb.DeployRequest{Deployment: deployment, Message: message})
+	if err != nil {
+		return 0, err
+	}
+	return int(reply.Revision), nil
+}
+
+// PlanDeployment asks the Quilt daemon for the changes that deploying the given
+// deployment would make, without deploying it.
+func (c clientImpl) PlanDeployment(deployment string) (engine.Plan, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.Deploy(ctx,
+		&pb.DeployRequest{Deployment: deployment, DryRun: true})
+	if err != nil {
+		return engine.Plan{}, err
+	}
+
+	var plan engine.Plan
+	err = json.Unmarshal([]byte(reply.Plan), &plan)
+	return plan, err
+}
+
+// Drain marks the minion the Client is connected to as draining, or clears the mark
+// if 'draining' is false.
+func (c clientImpl) Drain(draining bool) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	_, err := c.pbClient.Deploy(ctx, &pb.DeployRequest{Deployment: deployment})
+	_, err := c.pbClient.Drain(ctx, &pb.DrainRequest{Draining: draining})
 	return err
 }
 
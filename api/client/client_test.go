@@ -29,6 +29,24 @@ func (c mockAPIClient) Deploy(ctx context.Context, in *pb.DeployRequest,
 	return &pb.DeployReply{}, nil
 }
 
+func (c mockAPIClient) Watch(ctx context.Context, in *pb.WatchRequest,
+	opts ...grpc.CallOption) (pb.API_WatchClient, error) {
+
+	return nil, c.mockError
+}
+
+func (c mockAPIClient) QueryHistory(ctx context.Context, in *pb.HistoryQuery,
+	opts ...grpc.CallOption) (*pb.HistoryReply, error) {
+
+	return &pb.HistoryReply{Records: c.mockResponse}, c.mockError
+}
+
+func (c mockAPIClient) Drain(ctx context.Context, in *pb.DrainRequest,
+	opts ...grpc.CallOption) (*pb.DrainReply, error) {
+
+	return &pb.DrainReply{}, c.mockError
+}
+
 func TestUnmarshalMachine(t *testing.T) {
 	t.Parallel()
 
@@ -130,3 +148,64 @@ func TestGrpcError(t *testing.T) {
 			exp.Error(), err.Error())
 	}
 }
+
+func TestUnmarshalHistory(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"Revision":3,"Time":"0001-01-01T00:00:00Z",` +
+			`"Reason":"api: deploy","Rows":[{"Table":"db.Cluster",` +
+			`"ID":1,"Change":"modify","Row":"Cluster-1{}",` +
+			`"Fields":[{"Field":"Spec","Old":"a","New":"b"}]}]}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryHistory(db.ClusterTable)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+		return
+	}
+
+	exp := []db.TxnRecord{{
+		Revision: 3,
+		Reason:   "api: deploy",
+		Rows: []db.RowDiff{{
+			Table:  db.ClusterTable,
+			ID:     1,
+			Change: db.RowModified,
+			Row:    "Cluster-1{}",
+			Fields: []db.FieldDiff{{Field: "Spec", Old: "a", New: "b"}},
+		}},
+	}}
+
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Bad unmarshalling of history: expected %v, got %v.",
+			exp, res)
+	}
+}
+
+func TestToDBQuery(t *testing.T) {
+	t.Parallel()
+
+	query := toDBQuery(db.MachineTable, []Filter{
+		{Fields: map[string][]string{
+			"Role":     {"Master"},
+			"Provider": {"Amazon", "Google"},
+		}},
+		{Offset: 2, Limit: 5},
+		{Limit: 3},
+	})
+
+	exp := &pb.DBQuery{
+		Table: string(db.MachineTable),
+		Filters: []*pb.Filter{
+			{Field: "Provider", Values: []string{"Amazon", "Google"}},
+			{Field: "Role", Values: []string{"Master"}},
+		},
+		Offset: 2,
+		Limit:  3,
+	}
+
+	if !reflect.DeepEqual(exp, query) {
+		t.Errorf("Bad DBQuery: expected %v, got %v.", exp, query)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/NetSys/quilt/api"
 	"github.com/NetSys/quilt/api/client"
 	"github.com/NetSys/quilt/api/util"
+	"github.com/NetSys/quilt/auth"
 )
 
 // New returns an implementation of the Getter interface.
@@ -29,7 +30,14 @@ type clientGetterImpl struct {
 }
 
 func (getter addrClientGetterImpl) Client(host string) (client.Client, error) {
-	c, err := client.New(host)
+	// Remote daemons only accept clients with credentials signed by the CA of the
+	// local daemon, which stores them in the default directory.
+	creds, err := auth.Load(auth.DefaultDir())
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := client.New(host, creds)
 	if err != nil {
 		return nil, daemonConnectError{
 			host:         host,
@@ -117,7 +125,9 @@ func (getter clientGetterImpl) getLeaderIP(localClient client.Client,
 // getPublicIP returns the public IP associated with the machine with the
 // given private IP.
 func getPublicIP(c client.Client, privateIP string) (string, error) {
-	machines, err := c.QueryMachines()
+	machines, err := c.QueryMachines(client.Filter{
+		Fields: map[string][]string{"PrivateIP": {privateIP}},
+	})
 	if err != nil {
 		return "", err
 	}
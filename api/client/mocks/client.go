@@ -1,24 +1,47 @@
 package mocks
 
 import (
+	"github.com/NetSys/quilt/api/client"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
 )
 
 // Client implements a mocked version of a Quilt client.
 type Client struct {
-	MachineReturn   []db.Machine
-	ContainerReturn []db.Container
-	EtcdReturn      []db.Etcd
-	ClusterReturn   []db.Cluster
-	HostReturn      string
-	DeployArg       string
+	MachineReturn    []db.Machine
+	ContainerReturn  []db.Container
+	EtcdReturn       []db.Etcd
+	ClusterReturn    []db.Cluster
+	PlacementReturn  []db.Placement
+	ACLReturn        []db.ACL
+	MinionReturn     []db.Minion
+	DeploymentReturn []db.Deployment
+	HistoryReturn    []db.TxnRecord
+	HostReturn       string
+	DeployArg        string
+	MessageArg       string
+	RevisionReturn   int
+	PlanReturn       engine.Plan
+	PlanArg          string
+	HistoryArg       db.TableType
+	DrainArg         *bool
+
+	// The ChangeSets delivered by Watch.
+	WatchReturn []db.ChangeSet
+	WatchArg    []db.TableType
+
+	// The filters passed to the most recent query.
+	Filters []client.Filter
 
 	MachineErr, ContainerErr, EtcdErr, ClusterErr, HostErr error
-	DeployErr, ConnectionErr                               error
+	DeployErr, ConnectionErr, HistoryErr, PlanErr          error
+	PlacementErr, ACLErr, MinionErr, WatchErr              error
+	DeploymentErr, DrainErr                                error
 }
 
 // QueryMachines retrieves the machines tracked by the Quilt daemon.
-func (c *Client) QueryMachines() ([]db.Machine, error) {
+func (c *Client) QueryMachines(filters ...client.Filter) ([]db.Machine, error) {
+	c.Filters = filters
 	if c.MachineErr != nil {
 		return nil, c.MachineErr
 	}
@@ -26,7 +49,8 @@ func (c *Client) QueryMachines() ([]db.Machine, error) {
 }
 
 // QueryContainers retrieves the containers tracked by the Quilt daemon.
-func (c *Client) QueryContainers() ([]db.Container, error) {
+func (c *Client) QueryContainers(filters ...client.Filter) ([]db.Container, error) {
+	c.Filters = filters
 	if c.ContainerErr != nil {
 		return nil, c.ContainerErr
 	}
@@ -34,7 +58,8 @@ func (c *Client) QueryContainers() ([]db.Container, error) {
 }
 
 // QueryEtcd retrieves the etcd information tracked by the Quilt daemon.
-func (c *Client) QueryEtcd() ([]db.Etcd, error) {
+func (c *Client) QueryEtcd(filters ...client.Filter) ([]db.Etcd, error) {
+	c.Filters = filters
 	if c.EtcdErr != nil {
 		return nil, c.EtcdErr
 	}
@@ -43,7 +68,8 @@ func (c *Client) QueryEtcd() ([]db.Etcd, error) {
 
 // QueryConnections retrieves the connection information tracked by the
 // Quilt daemon.
-func (c *Client) QueryConnections() ([]db.Connection, error) {
+func (c *Client) QueryConnections(filters ...client.Filter) ([]db.Connection, error) {
+	c.Filters = filters
 	if c.ConnectionErr != nil {
 		return nil, c.ConnectionErr
 	}
@@ -51,29 +77,121 @@ func (c *Client) QueryConnections() ([]db.Connection, error) {
 }
 
 // QueryLabels retrieves the label information tracked by the Quilt daemon.
-func (c *Client) QueryLabels() ([]db.Label, error) {
+func (c *Client) QueryLabels(filters ...client.Filter) ([]db.Label, error) {
+	c.Filters = filters
 	return nil, nil
 }
 
 // QueryClusters retrieves cluster information tracked by the Quilt daemon.
-func (c *Client) QueryClusters() ([]db.Cluster, error) {
+func (c *Client) QueryClusters(filters ...client.Filter) ([]db.Cluster, error) {
+	c.Filters = filters
 	if c.ClusterErr != nil {
 		return nil, c.ClusterErr
 	}
 	return c.ClusterReturn, nil
 }
 
+// QueryPlacements retrieves the placement constraints tracked by the Quilt daemon.
+func (c *Client) QueryPlacements(filters ...client.Filter) ([]db.Placement, error) {
+	c.Filters = filters
+	if c.PlacementErr != nil {
+		return nil, c.PlacementErr
+	}
+	return c.PlacementReturn, nil
+}
+
+// QueryACLs retrieves the ACL information tracked by the Quilt daemon.
+func (c *Client) QueryACLs(filters ...client.Filter) ([]db.ACL, error) {
+	c.Filters = filters
+	if c.ACLErr != nil {
+		return nil, c.ACLErr
+	}
+	return c.ACLReturn, nil
+}
+
+// QueryMinions retrieves the minion information tracked by the Quilt daemon.
+func (c *Client) QueryMinions(filters ...client.Filter) ([]db.Minion, error) {
+	c.Filters = filters
+	if c.MinionErr != nil {
+		return nil, c.MinionErr
+	}
+	return c.MinionReturn, nil
+}
+
+// QueryDeployments retrieves the deployment history of the Quilt daemon.
+func (c *Client) QueryDeployments(filters ...client.Filter) ([]db.Deployment,
+	error) {
+	c.Filters = filters
+	if c.DeploymentErr != nil {
+		return nil, c.DeploymentErr
+	}
+	return c.DeploymentReturn, nil
+}
+
+// QueryHistory retrieves the recent transactions committed to the database of the
+// Quilt daemon.
+func (c *Client) QueryHistory(table db.TableType) ([]db.TxnRecord, error) {
+	c.HistoryArg = table
+	if c.HistoryErr != nil {
+		return nil, c.HistoryErr
+	}
+	return c.HistoryReturn, nil
+}
+
+// Watch streams the changes committed to 'tables' on the Quilt daemon.
+func (c *Client) Watch(stop <-chan struct{}, tables ...db.TableType) (
+	<-chan db.ChangeSet, error) {
+
+	c.WatchArg = tables
+	if c.WatchErr != nil {
+		return nil, c.WatchErr
+	}
+
+	changes := make(chan db.ChangeSet)
+	go func() {
+		defer close(changes)
+		for _, cs := range c.WatchReturn {
+			select {
+			case changes <- cs:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
 // Close the grpc connection.
 func (c *Client) Close() error {
 	return nil
 }
 
 // Deploy makes a request to the Quilt daemon to deploy the given deployment.
-func (c *Client) Deploy(depl string) error {
+func (c *Client) Deploy(depl, message string) (int, error) {
 	if c.DeployErr != nil {
-		return c.DeployErr
+		return 0, c.DeployErr
 	}
 	c.DeployArg = depl
+	c.MessageArg = message
+	return c.RevisionReturn, nil
+}
+
+// PlanDeployment asks the Quilt daemon for the changes that deploying the given
+// deployment would make, without deploying it.
+func (c *Client) PlanDeployment(depl string) (engine.Plan, error) {
+	c.PlanArg = depl
+	if c.PlanErr != nil {
+		return engine.Plan{}, c.PlanErr
+	}
+	return c.PlanReturn, nil
+}
+
+// Drain marks the minion the Client is connected to as draining.
+func (c *Client) Drain(draining bool) error {
+	if c.DrainErr != nil {
+		return c.DrainErr
+	}
+	c.DrainArg = &draining
 	return nil
 }
 
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/db"
+
+	"golang.org/x/net/context"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Watch streams the changes committed to 'tables' on the Quilt daemon, or to every
+// table if none are given.
+func (c clientImpl) Watch(stop <-chan struct{}, tables ...db.TableType) (
+	<-chan db.ChangeSet, error) {
+
+	req := &pb.WatchRequest{}
+	for _, t := range tables {
+		req.Tables = append(req.Tables, string(t))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.pbClient.Watch(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	changes := make(chan db.ChangeSet)
+	go func() {
+		defer close(changes)
+		defer cancel()
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				select {
+				case <-stop:
+				default:
+					log.WithError(err).Warn("Watch stream failed.")
+				}
+				return
+			}
+
+			cs, err := toChangeSet(reply)
+			if err != nil {
+				log.WithError(err).Warn("Failed to decode watch reply.")
+				return
+			}
+
+			select {
+			case changes <- cs:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func toChangeSet(reply *pb.WatchReply) (db.ChangeSet, error) {
+	cs := db.ChangeSet{
+		Revision: int(reply.Revision),
+		Reason:   reply.Reason,
+	}
+
+	if reply.Time != 0 {
+		cs.Time = time.Unix(0, reply.Time)
+	}
+
+	for _, tc := range reply.Tables {
+		table := db.TableType(tc.Table)
+
+		var changes []struct {
+			ID  int
+			Old json.RawMessage
+			New json.RawMessage
+		}
+		if err := json.Unmarshal([]byte(tc.Changes), &changes); err != nil {
+			return db.ChangeSet{}, err
+		}
+
+		for _, change := range changes {
+			old, err := decodeRow(table, change.ID, change.Old)
+			if err != nil {
+				return db.ChangeSet{}, err
+			}
+
+			new, err := decodeRow(table, change.ID, change.New)
+			if err != nil {
+				return db.ChangeSet{}, err
+			}
+
+			rc := db.RowChange{Table: table, Old: old, New: new}
+			switch {
+			case old == nil:
+				cs.Inserted = append(cs.Inserted, rc)
+			case new == nil:
+				cs.Removed = append(cs.Removed, rc)
+			default:
+				cs.Modified = append(cs.Modified, rc)
+			}
+		}
+	}
+	return cs, nil
+}
+
+// decodeRow decodes the JSON encoding of the row of 'table' with the given 'id'.  It
+// returns nil if 'data' is null.
+func decodeRow(table db.TableType, id int, data json.RawMessage) (interface{},
+	error) {
+
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var rowType reflect.Type
+	switch table {
+	case db.MachineTable:
+		rowType = reflect.TypeOf(db.Machine{})
+	case db.ContainerTable:
+		rowType = reflect.TypeOf(db.Container{})
+	case db.EtcdTable:
+		rowType = reflect.TypeOf(db.Etcd{})
+	case db.LabelTable:
+		rowType = reflect.TypeOf(db.Label{})
+	case db.ConnectionTable:
+		rowType = reflect.TypeOf(db.Connection{})
+	case db.ClusterTable:
+		rowType = reflect.TypeOf(db.Cluster{})
+	case db.PlacementTable:
+		rowType = reflect.TypeOf(db.Placement{})
+	case db.ACLTable:
+		rowType = reflect.TypeOf(db.ACL{})
+	case db.MinionTable:
+		rowType = reflect.TypeOf(db.Minion{})
+	case db.DeploymentTable:
+		rowType = reflect.TypeOf(db.Deployment{})
+	default:
+		return nil, fmt.Errorf("unsupported table type: %s", table)
+	}
+
+	row := reflect.New(rowType)
+	if err := json.Unmarshal(data, row.Interface()); err != nil {
+		return nil, err
+	}
+
+	// Not every table includes the ID in the JSON encoding of its rows.
+	row.Elem().FieldByName("ID").SetInt(int64(id))
+	return row.Elem().Interface(), nil
+}
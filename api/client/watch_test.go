@@ -0,0 +1,57 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestToChangeSet(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	reply := &pb.WatchReply{
+		Revision: 4,
+		Time:     now.UnixNano(),
+		Reason:   "api: deploy",
+		Tables: []*pb.TableChanges{{
+			Table: string(db.MachineTable),
+			Changes: `[{"ID":1,"Old":null,` +
+				`"New":{"ID":1,"PublicIP":"1.2.3.4"}},` +
+				`{"ID":2,"Old":{"ID":2},"New":{"ID":2,"Role":"Master"}}]`,
+		}, {
+			Table:   string(db.LabelTable),
+			Changes: `[{"ID":3,"Old":{"Label":"red"},"New":null}]`,
+		}},
+	}
+
+	cs, err := toChangeSet(reply)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, cs.Revision)
+	assert.Equal(t, "api: deploy", cs.Reason)
+	assert.True(t, now.Equal(cs.Time))
+
+	assert.Equal(t, []db.RowChange{{
+		Table: db.MachineTable,
+		New:   db.Machine{ID: 1, PublicIP: "1.2.3.4"},
+	}}, cs.Inserted)
+	assert.Equal(t, []db.RowChange{{
+		Table: db.MachineTable,
+		Old:   db.Machine{ID: 2},
+		New:   db.Machine{ID: 2, Role: db.Master},
+	}}, cs.Modified)
+	assert.Equal(t, []db.RowChange{{
+		Table: db.LabelTable,
+		Old:   db.Label{ID: 3, Label: "red"},
+	}}, cs.Removed)
+	assert.Equal(t, 3, cs.Removed[0].ID())
+
+	_, err = toChangeSet(&pb.WatchReply{Tables: []*pb.TableChanges{
+		{Table: "foo", Changes: `[{"New":{}}]`},
+	}})
+	assert.EqualError(t, err, "unsupported table type: foo")
+}
@@ -6,13 +6,23 @@
 Package pb is a generated protocol buffer package.
 
 It is generated from these files:
+
 	pb/pb.proto
 
 It has these top-level messages:
+
 	DBQuery
+	Filter
 	QueryReply
 	DeployRequest
 	DeployReply
+	HistoryQuery
+	HistoryReply
+	WatchRequest
+	WatchReply
+	TableChanges
+	DrainRequest
+	DrainReply
 */
 package pb
 
@@ -37,7 +47,10 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type DBQuery struct {
-	Table string `protobuf:"bytes,1,opt,name=Table,json=table" json:"Table,omitempty"`
+	Table   string    `protobuf:"bytes,1,opt,name=Table,json=table" json:"Table,omitempty"`
+	Filters []*Filter `protobuf:"bytes,2,rep,name=Filters,json=filters" json:"Filters,omitempty"`
+	Limit   int32     `protobuf:"varint,3,opt,name=Limit,json=limit" json:"Limit,omitempty"`
+	Offset  int32     `protobuf:"varint,4,opt,name=Offset,json=offset" json:"Offset,omitempty"`
 }
 
 func (m *DBQuery) Reset()                    { *m = DBQuery{} }
@@ -52,6 +65,51 @@ func (m *DBQuery) GetTable() string {
 	return ""
 }
 
+func (m *DBQuery) GetFilters() []*Filter {
+	if m != nil {
+		return m.Filters
+	}
+	return nil
+}
+
+func (m *DBQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *DBQuery) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+type Filter struct {
+	Field  string   `protobuf:"bytes,1,opt,name=Field,json=field" json:"Field,omitempty"`
+	Values []string `protobuf:"bytes,2,rep,name=Values,json=values" json:"Values,omitempty"`
+}
+
+func (m *Filter) Reset()                    { *m = Filter{} }
+func (m *Filter) String() string            { return proto.CompactTextString(m) }
+func (*Filter) ProtoMessage()               {}
+func (*Filter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Filter) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *Filter) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
 type QueryReply struct {
 	TableContents string `protobuf:"bytes,1,opt,name=TableContents,json=tableContents" json:"TableContents,omitempty"`
 }
@@ -59,7 +117,7 @@ type QueryReply struct {
 func (m *QueryReply) Reset()                    { *m = QueryReply{} }
 func (m *QueryReply) String() string            { return proto.CompactTextString(m) }
 func (*QueryReply) ProtoMessage()               {}
-func (*QueryReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*QueryReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 func (m *QueryReply) GetTableContents() string {
 	if m != nil {
@@ -70,12 +128,14 @@ func (m *QueryReply) GetTableContents() string {
 
 type DeployRequest struct {
 	Deployment string `protobuf:"bytes,1,opt,name=Deployment,json=deployment" json:"Deployment,omitempty"`
+	DryRun     bool   `protobuf:"varint,2,opt,name=DryRun,json=dryRun" json:"DryRun,omitempty"`
+	Message    string `protobuf:"bytes,3,opt,name=Message,json=message" json:"Message,omitempty"`
 }
 
 func (m *DeployRequest) Reset()                    { *m = DeployRequest{} }
 func (m *DeployRequest) String() string            { return proto.CompactTextString(m) }
 func (*DeployRequest) ProtoMessage()               {}
-func (*DeployRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*DeployRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *DeployRequest) GetDeployment() string {
 	if m != nil {
@@ -84,19 +144,193 @@ func (m *DeployRequest) GetDeployment() string {
 	return ""
 }
 
+func (m *DeployRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *DeployRequest) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 type DeployReply struct {
+	Plan     string `protobuf:"bytes,1,opt,name=Plan,json=plan" json:"Plan,omitempty"`
+	Revision int32  `protobuf:"varint,2,opt,name=Revision,json=revision" json:"Revision,omitempty"`
 }
 
 func (m *DeployReply) Reset()                    { *m = DeployReply{} }
 func (m *DeployReply) String() string            { return proto.CompactTextString(m) }
 func (*DeployReply) ProtoMessage()               {}
-func (*DeployReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*DeployReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *DeployReply) GetPlan() string {
+	if m != nil {
+		return m.Plan
+	}
+	return ""
+}
+
+func (m *DeployReply) GetRevision() int32 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+type HistoryQuery struct {
+	Table string `protobuf:"bytes,1,opt,name=Table,json=table" json:"Table,omitempty"`
+}
+
+func (m *HistoryQuery) Reset()                    { *m = HistoryQuery{} }
+func (m *HistoryQuery) String() string            { return proto.CompactTextString(m) }
+func (*HistoryQuery) ProtoMessage()               {}
+func (*HistoryQuery) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *HistoryQuery) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+type HistoryReply struct {
+	Records string `protobuf:"bytes,1,opt,name=Records,json=records" json:"Records,omitempty"`
+}
+
+func (m *HistoryReply) Reset()                    { *m = HistoryReply{} }
+func (m *HistoryReply) String() string            { return proto.CompactTextString(m) }
+func (*HistoryReply) ProtoMessage()               {}
+func (*HistoryReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *HistoryReply) GetRecords() string {
+	if m != nil {
+		return m.Records
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	Tables []string `protobuf:"bytes,1,rep,name=Tables,json=tables" json:"Tables,omitempty"`
+}
+
+func (m *WatchRequest) Reset()                    { *m = WatchRequest{} }
+func (m *WatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()               {}
+func (*WatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *WatchRequest) GetTables() []string {
+	if m != nil {
+		return m.Tables
+	}
+	return nil
+}
+
+type WatchReply struct {
+	Revision int64           `protobuf:"varint,1,opt,name=Revision,json=revision" json:"Revision,omitempty"`
+	Time     int64           `protobuf:"varint,2,opt,name=Time,json=time" json:"Time,omitempty"`
+	Reason   string          `protobuf:"bytes,3,opt,name=Reason,json=reason" json:"Reason,omitempty"`
+	Tables   []*TableChanges `protobuf:"bytes,4,rep,name=Tables,json=tables" json:"Tables,omitempty"`
+}
+
+func (m *WatchReply) Reset()                    { *m = WatchReply{} }
+func (m *WatchReply) String() string            { return proto.CompactTextString(m) }
+func (*WatchReply) ProtoMessage()               {}
+func (*WatchReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *WatchReply) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *WatchReply) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *WatchReply) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *WatchReply) GetTables() []*TableChanges {
+	if m != nil {
+		return m.Tables
+	}
+	return nil
+}
+
+type TableChanges struct {
+	Table   string `protobuf:"bytes,1,opt,name=Table,json=table" json:"Table,omitempty"`
+	Changes string `protobuf:"bytes,2,opt,name=Changes,json=changes" json:"Changes,omitempty"`
+}
+
+func (m *TableChanges) Reset()                    { *m = TableChanges{} }
+func (m *TableChanges) String() string            { return proto.CompactTextString(m) }
+func (*TableChanges) ProtoMessage()               {}
+func (*TableChanges) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *TableChanges) GetTable() string {
+	if m != nil {
+		return m.Table
+	}
+	return ""
+}
+
+func (m *TableChanges) GetChanges() string {
+	if m != nil {
+		return m.Changes
+	}
+	return ""
+}
+
+type DrainRequest struct {
+	Draining bool `protobuf:"varint,1,opt,name=Draining,json=draining" json:"Draining,omitempty"`
+}
+
+func (m *DrainRequest) Reset()                    { *m = DrainRequest{} }
+func (m *DrainRequest) String() string            { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()               {}
+func (*DrainRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *DrainRequest) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+type DrainReply struct {
+}
+
+func (m *DrainReply) Reset()                    { *m = DrainReply{} }
+func (m *DrainReply) String() string            { return proto.CompactTextString(m) }
+func (*DrainReply) ProtoMessage()               {}
+func (*DrainReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func init() {
 	proto.RegisterType((*DBQuery)(nil), "DBQuery")
+	proto.RegisterType((*Filter)(nil), "Filter")
 	proto.RegisterType((*QueryReply)(nil), "QueryReply")
 	proto.RegisterType((*DeployRequest)(nil), "DeployRequest")
 	proto.RegisterType((*DeployReply)(nil), "DeployReply")
+	proto.RegisterType((*HistoryQuery)(nil), "HistoryQuery")
+	proto.RegisterType((*HistoryReply)(nil), "HistoryReply")
+	proto.RegisterType((*WatchRequest)(nil), "WatchRequest")
+	proto.RegisterType((*WatchReply)(nil), "WatchReply")
+	proto.RegisterType((*TableChanges)(nil), "TableChanges")
+	proto.RegisterType((*DrainRequest)(nil), "DrainRequest")
+	proto.RegisterType((*DrainReply)(nil), "DrainReply")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -112,6 +346,9 @@ const _ = grpc.SupportPackageIsVersion4
 type APIClient interface {
 	Query(ctx context.Context, in *DBQuery, opts ...grpc.CallOption) (*QueryReply, error)
 	Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
+	QueryHistory(ctx context.Context, in *HistoryQuery, opts ...grpc.CallOption) (*HistoryReply, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (API_WatchClient, error)
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error)
 }
 
 type aPIClient struct {
@@ -140,11 +377,64 @@ func (c *aPIClient) Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *aPIClient) QueryHistory(ctx context.Context, in *HistoryQuery, opts ...grpc.CallOption) (*HistoryReply, error) {
+	out := new(HistoryReply)
+	err := grpc.Invoke(ctx, "/API/QueryHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (API_WatchClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_API_serviceDesc.Streams[0], c.cc, "/API/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_WatchClient interface {
+	Recv() (*WatchReply, error)
+	grpc.ClientStream
+}
+
+type aPIWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIWatchClient) Recv() (*WatchReply, error) {
+	m := new(WatchReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error) {
+	out := new(DrainReply)
+	err := grpc.Invoke(ctx, "/API/Drain", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
 	Query(context.Context, *DBQuery) (*QueryReply, error)
 	Deploy(context.Context, *DeployRequest) (*DeployReply, error)
+	QueryHistory(context.Context, *HistoryQuery) (*HistoryReply, error)
+	Watch(*WatchRequest, API_WatchServer) error
+	Drain(context.Context, *DrainRequest) (*DrainReply, error)
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -187,6 +477,63 @@ func _API_Deploy_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_QueryHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).QueryHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/QueryHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).QueryHistory(ctx, req.(*HistoryQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Watch(m, &aPIWatchServer{stream})
+}
+
+type API_WatchServer interface {
+	Send(*WatchReply) error
+	grpc.ServerStream
+}
+
+type aPIWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIWatchServer) Send(m *WatchReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _API_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Drain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "API",
 	HandlerType: (*APIServer)(nil),
@@ -199,25 +546,60 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Deploy",
 			Handler:    _API_Deploy_Handler,
 		},
+		{
+			MethodName: "QueryHistory",
+			Handler:    _API_QueryHistory_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _API_Drain_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _API_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/pb.proto",
 }
 
 func init() { proto.RegisterFile("pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 189 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0x48, 0xd2, 0x2f,
-	0x48, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x92, 0xe7, 0x62, 0x77, 0x71, 0x0a, 0x2c, 0x4d,
-	0x2d, 0xaa, 0x14, 0x12, 0xe1, 0x62, 0x0d, 0x49, 0x4c, 0xca, 0x49, 0x95, 0x60, 0x54, 0x60, 0xd4,
-	0xe0, 0x0c, 0x62, 0x2d, 0x01, 0x71, 0x94, 0x8c, 0xb8, 0xb8, 0xc0, 0xd2, 0x41, 0xa9, 0x05, 0x39,
-	0x95, 0x42, 0x2a, 0x5c, 0xbc, 0x60, 0x35, 0xce, 0xf9, 0x79, 0x25, 0xa9, 0x79, 0x25, 0xc5, 0x50,
-	0xb5, 0xbc, 0x25, 0xc8, 0x82, 0x4a, 0xfa, 0x5c, 0xbc, 0x2e, 0xa9, 0x05, 0x39, 0xf9, 0x95, 0x41,
-	0xa9, 0x85, 0xa5, 0xa9, 0xc5, 0x25, 0x42, 0x72, 0x5c, 0x5c, 0x10, 0x81, 0xdc, 0xd4, 0xbc, 0x12,
-	0xa8, 0x1e, 0xae, 0x14, 0xb8, 0x88, 0x12, 0x2f, 0x17, 0x37, 0x4c, 0x43, 0x41, 0x4e, 0xa5, 0x51,
-	0x20, 0x17, 0xb3, 0x63, 0x80, 0xa7, 0x90, 0x02, 0x17, 0x2b, 0xc4, 0x65, 0x1c, 0x7a, 0x50, 0x37,
-	0x4a, 0x71, 0xeb, 0x21, 0x1c, 0xa3, 0xc4, 0x20, 0xa4, 0xc1, 0xc5, 0x06, 0xd1, 0x27, 0xc4, 0xa7,
-	0x87, 0x62, 0xa3, 0x14, 0x8f, 0x1e, 0x92, 0x81, 0x4a, 0x0c, 0x49, 0x6c, 0x60, 0xef, 0x1a, 0x03,
-	0x02, 0x00, 0x00, 0xff, 0xff, 0x52, 0x06, 0x87, 0x98, 0xfd, 0x00, 0x00, 0x00,
+	// 525 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x93, 0x4f, 0x8f, 0xd3, 0x3c,
+	0x10, 0xc6, 0x9b, 0x6d, 0xf3, 0xa7, 0x93, 0xe4, 0x3d, 0x58, 0xaf, 0x56, 0x51, 0x0e, 0xa8, 0x58,
+	0xbb, 0x10, 0x71, 0x30, 0xa8, 0x48, 0xdc, 0x40, 0x02, 0xaa, 0x15, 0x48, 0x20, 0x16, 0x6b, 0x05,
+	0xe7, 0xb4, 0x75, 0xbb, 0x96, 0xd2, 0x24, 0xc4, 0xee, 0x4a, 0x11, 0x9f, 0x92, 0x6f, 0x84, 0x3c,
+	0x76, 0xda, 0x70, 0x80, 0xe3, 0x6f, 0x34, 0xf3, 0xcc, 0x8c, 0x9f, 0x31, 0xc4, 0xed, 0xfa, 0x79,
+	0xbb, 0x66, 0x6d, 0xd7, 0xe8, 0x86, 0xb6, 0x10, 0xae, 0xde, 0x7d, 0x3d, 0x8a, 0xae, 0x27, 0xff,
+	0x83, 0x7f, 0x57, 0xae, 0x2b, 0x91, 0x79, 0x0b, 0xaf, 0x98, 0x73, 0x5f, 0x1b, 0x20, 0x8f, 0x21,
+	0xbc, 0x91, 0x95, 0x16, 0x9d, 0xca, 0x2e, 0x16, 0xd3, 0x22, 0x5e, 0x86, 0xcc, 0x32, 0x0f, 0x77,
+	0x36, 0x6e, 0x0a, 0x3f, 0xc9, 0x83, 0xd4, 0xd9, 0x74, 0xe1, 0x15, 0x3e, 0xf7, 0x2b, 0x03, 0xe4,
+	0x12, 0x82, 0x2f, 0xbb, 0x9d, 0x12, 0x3a, 0x9b, 0x61, 0x38, 0x68, 0x90, 0xe8, 0x2b, 0x08, 0xac,
+	0x80, 0xa9, 0xbb, 0x91, 0xa2, 0xda, 0x0e, 0x0d, 0x77, 0x06, 0x4c, 0xdd, 0xb7, 0xb2, 0x3a, 0x0a,
+	0xdb, 0x6f, 0xce, 0x83, 0x07, 0x24, 0xba, 0x04, 0xc0, 0x39, 0xb9, 0x68, 0xab, 0x9e, 0x5c, 0x41,
+	0x8a, 0xc3, 0xbe, 0x6f, 0x6a, 0x2d, 0x6a, 0xad, 0x9c, 0x46, 0xaa, 0xc7, 0x41, 0x5a, 0x42, 0xba,
+	0x12, 0x6d, 0xd5, 0xf4, 0x5c, 0xfc, 0x38, 0x0a, 0xa5, 0xc9, 0x23, 0x00, 0x1b, 0x38, 0x88, 0x5a,
+	0xbb, 0x1a, 0xd8, 0x9e, 0x22, 0xa6, 0xf9, 0xaa, 0xeb, 0xf9, 0xb1, 0xce, 0x2e, 0x16, 0x5e, 0x11,
+	0xf1, 0x60, 0x8b, 0x44, 0x32, 0x08, 0x3f, 0x0b, 0xa5, 0xca, 0xbd, 0xc0, 0x25, 0xe7, 0x3c, 0x3c,
+	0x58, 0xa4, 0xaf, 0x21, 0x1e, 0x5a, 0x98, 0xb9, 0x08, 0xcc, 0x6e, 0xab, 0xb2, 0x76, 0xd2, 0xb3,
+	0xb6, 0x2a, 0x6b, 0x92, 0x43, 0xc4, 0xc5, 0x83, 0x54, 0xb2, 0xb1, 0xb2, 0x3e, 0x8f, 0x3a, 0xc7,
+	0xf4, 0x0a, 0x92, 0x0f, 0x52, 0xe9, 0xa6, 0xeb, 0xff, 0x61, 0x02, 0x2d, 0x4e, 0x59, 0xb6, 0x4b,
+	0x06, 0x21, 0x17, 0x9b, 0xa6, 0xdb, 0x0e, 0x7b, 0x87, 0x9d, 0x45, 0xfa, 0x04, 0x92, 0xef, 0xa5,
+	0xde, 0xdc, 0x0f, 0x0b, 0x5f, 0x42, 0x80, 0x7a, 0x26, 0x11, 0x5f, 0x13, 0x05, 0x15, 0xfd, 0x09,
+	0xe0, 0xf2, 0x8c, 0xde, 0x78, 0x42, 0x23, 0x38, 0x3d, 0x4f, 0x68, 0x36, 0xba, 0x93, 0x07, 0x81,
+	0x93, 0x4f, 0xf9, 0x4c, 0xcb, 0x83, 0x30, 0xaa, 0x5c, 0x94, 0xaa, 0xa9, 0xdd, 0x6b, 0x04, 0x1d,
+	0x12, 0xb9, 0x3e, 0x75, 0x9b, 0xe1, 0xad, 0xa4, 0xcc, 0x9a, 0x74, 0x5f, 0xd6, 0x7b, 0xa1, 0x4e,
+	0xcd, 0xdf, 0x40, 0x32, 0x8e, 0xff, 0xe5, 0xf2, 0x32, 0x08, 0x5d, 0x02, 0xf6, 0x9e, 0xf3, 0x70,
+	0x63, 0x91, 0x3e, 0x83, 0x64, 0xd5, 0x95, 0xb2, 0x1e, 0x96, 0xcc, 0x21, 0x42, 0x96, 0xf5, 0x1e,
+	0x25, 0x22, 0x1e, 0x6d, 0x1d, 0xd3, 0x04, 0xc0, 0xe5, 0xb6, 0x55, 0xbf, 0xfc, 0xe5, 0xc1, 0xf4,
+	0xed, 0xed, 0x47, 0xb2, 0x00, 0xdf, 0xbe, 0x77, 0xc4, 0xdc, 0xf9, 0xe7, 0x31, 0x3b, 0x9f, 0x17,
+	0x9d, 0x90, 0x02, 0x02, 0xeb, 0x2b, 0xf9, 0x8f, 0xfd, 0x71, 0x43, 0x79, 0xc2, 0x46, 0x86, 0xd3,
+	0x09, 0x61, 0x90, 0x60, 0xa5, 0x73, 0x88, 0xa4, 0x6c, 0xec, 0x68, 0x7e, 0xc2, 0x21, 0xff, 0x29,
+	0xf8, 0xf8, 0xf4, 0x24, 0x65, 0x63, 0xab, 0xf2, 0x98, 0x9d, 0x1d, 0xa1, 0x93, 0x17, 0x1e, 0xb9,
+	0x06, 0x1f, 0x47, 0x27, 0x29, 0x1b, 0xaf, 0x9b, 0xc7, 0xec, 0xbc, 0x11, 0x9d, 0xac, 0x03, 0xfc,
+	0xc9, 0x2f, 0x7f, 0x0f, 0x00, 0x38, 0x81, 0x70, 0xed, 0xd8, 0x03, 0x00, 0x00,
 }
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/NetSys/quilt/api/pb"
+)
+
+// filterRows returns the rows in the slice 'rows' that satisfy every filter in
+// 'query', ordered by ID, and restricted to the window described by the query's
+// offset and limit.  A row satisfies a filter if the string representation of the
+// named field is one of the filter's values.  Slice fields satisfy a filter if any
+// of their elements do.
+func filterRows(rows interface{}, query *pb.DBQuery) (interface{}, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, errors.New("limit and offset must be non-negative")
+	}
+
+	rowsVal := reflect.ValueOf(rows)
+	rowType := rowsVal.Type().Elem()
+	for _, f := range query.Filters {
+		if _, ok := rowType.FieldByName(f.Field); !ok {
+			return nil, fmt.Errorf("unrecognized field: %s", f.Field)
+		}
+	}
+
+	var matches []reflect.Value
+	for i := 0; i < rowsVal.Len(); i++ {
+		if rowMatches(rowsVal.Index(i), query.Filters) {
+			matches = append(matches, rowsVal.Index(i))
+		}
+	}
+	sort.Sort(rowsByID(matches))
+
+	start := minInt(int(query.Offset), len(matches))
+	end := len(matches)
+	if query.Limit > 0 {
+		end = minInt(start+int(query.Limit), end)
+	}
+
+	// Start from the nil slice so that an empty result is encoded in the same way
+	// as an empty table.
+	result := reflect.Zero(rowsVal.Type())
+	for _, row := range matches[start:end] {
+		result = reflect.Append(result, row)
+	}
+	return result.Interface(), nil
+}
+
+func rowMatches(row reflect.Value, filters []*pb.Filter) bool {
+	for _, f := range filters {
+		if !fieldMatches(row.FieldByName(f.Field), f.Values) {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldMatches(field reflect.Value, values []string) bool {
+	if field.Kind() == reflect.Slice {
+		for i := 0; i < field.Len(); i++ {
+			if fieldMatches(field.Index(i), values) {
+				return true
+			}
+		}
+		return false
+	}
+
+	str := fmt.Sprint(field.Interface())
+	for _, val := range values {
+		if str == val {
+			return true
+		}
+	}
+	return false
+}
+
+type rowsByID []reflect.Value
+
+func (rows rowsByID) Len() int {
+	return len(rows)
+}
+
+func (rows rowsByID) Swap(i, j int) {
+	rows[i], rows[j] = rows[j], rows[i]
+}
+
+func (rows rowsByID) Less(i, j int) bool {
+	return rows[i].FieldByName("ID").Int() < rows[j].FieldByName("ID").Int()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestFilterRows(t *testing.T) {
+	t.Parallel()
+
+	containers := []db.Container{
+		{ID: 3, Image: "a", Labels: []string{"red", "blue"}},
+		{ID: 1, Image: "b", Labels: []string{"red"}},
+		{ID: 2, Image: "a"},
+	}
+
+	checkFilter := func(query pb.DBQuery, exp []db.Container) {
+		res, err := filterRows(containers, &query)
+		assert.NoError(t, err)
+		assert.Equal(t, exp, res)
+	}
+
+	checkFilter(pb.DBQuery{}, []db.Container{
+		containers[1], containers[2], containers[0]})
+
+	checkFilter(pb.DBQuery{Filters: []*pb.Filter{
+		{Field: "Image", Values: []string{"a"}},
+	}}, []db.Container{containers[2], containers[0]})
+
+	checkFilter(pb.DBQuery{Filters: []*pb.Filter{
+		{Field: "Image", Values: []string{"a", "b"}},
+		{Field: "Labels", Values: []string{"red"}},
+	}}, []db.Container{containers[1], containers[0]})
+
+	checkFilter(pb.DBQuery{Filters: []*pb.Filter{
+		{Field: "ID", Values: []string{"2"}},
+	}}, []db.Container{containers[2]})
+
+	checkFilter(pb.DBQuery{Filters: []*pb.Filter{
+		{Field: "Image", Values: []string{"c"}},
+	}}, []db.Container(nil))
+
+	checkFilter(pb.DBQuery{Offset: 1, Limit: 1},
+		[]db.Container{containers[2]})
+	checkFilter(pb.DBQuery{Offset: 1}, []db.Container{containers[2], containers[0]})
+	checkFilter(pb.DBQuery{Offset: 5}, []db.Container(nil))
+
+	_, err := filterRows(containers, &pb.DBQuery{Filters: []*pb.Filter{
+		{Field: "Foo", Values: []string{"a"}},
+	}})
+	assert.EqualError(t, err, "unrecognized field: Foo")
+
+	_, err = filterRows(containers, &pb.DBQuery{Limit: -1})
+	assert.EqualError(t, err, "limit and offset must be non-negative")
+}
@@ -0,0 +1,460 @@
+package server
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NetSys/quilt/api"
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/auth"
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The prefix of every path served by the HTTP API.  It changes only if the API does
+// so incompatibly.
+const httpPrefix = "/v1"
+
+// A route describes an HTTP endpoint.  Routes are both served by the HTTP API and
+// used to generate its OpenAPI description, so the two cannot diverge.
+type route struct {
+	method  string
+	path    string
+	summary string
+	params  []param
+
+	// The Go values whose types describe the request and response bodies.  A nil
+	// request means the endpoint takes no body.
+	request  interface{}
+	response interface{}
+
+	// Whether the response is a stream of newline-delimited JSON values.
+	stream bool
+
+	handle func(s server, w http.ResponseWriter, r *http.Request) error
+}
+
+// A param describes a query parameter.
+type param struct {
+	name        string
+	description string
+	kind        string // An OpenAPI type.
+	repeated    bool
+}
+
+// A httpError is returned by a handler to respond with a particular status code.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+// A WatchEvent is the HTTP encoding of a single reply to the Watch RPC.  Changes maps
+// the name of each modified table to a list of the rows that changed, each of
+// which has an "Old" and "New" value.
+type WatchEvent struct {
+	Revision int
+	Time     time.Time
+	Reason   string `json:",omitempty"`
+	Changes  map[string]json.RawMessage
+}
+
+var tableRows = map[db.TableType]interface{}{
+	db.ClusterTable:    []db.Cluster{},
+	db.MachineTable:    []db.Machine{},
+	db.ContainerTable:  []db.Container{},
+	db.MinionTable:     []db.Minion{},
+	db.ConnectionTable: []db.Connection{},
+	db.LabelTable:      []db.Label{},
+	db.EtcdTable:       []db.Etcd{},
+	db.PlacementTable:  []db.Placement{},
+	db.ACLTable:        []db.ACL{},
+	db.DeploymentTable: []db.Deployment{},
+}
+
+// RunHTTP serves an HTTP/JSON interface to the API at 'listenAddr'.  Each RPC is
+// mirrored by an endpoint, and an OpenAPI description of them is served at
+// /v1/openapi.json.  As with Run, clients connecting over TCP must present a
+// certificate signed by the same CA as 'creds', unless 'creds' is empty.
+func RunHTTP(conn db.Conn, listenAddr string, creds auth.Credentials) error {
+	proto, addr, err := api.ParseListenAddress(listenAddr)
+	if err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if proto == "tcp" {
+		if tlsConfig, err = creds.ServerTLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	var sock net.Listener
+	for {
+		sock, err = net.Listen(proto, addr)
+		if err == nil {
+			break
+		}
+		log.WithError(err).Error("Failed to open HTTP socket.")
+
+		time.Sleep(30 * time.Second)
+	}
+
+	if tlsConfig != nil {
+		sock = tls.NewListener(sock, tlsConfig)
+	}
+
+	return http.Serve(sock, newHTTPHandler(server{conn}))
+}
+
+func newHTTPHandler(s server) http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range httpRoutes() {
+		rt := rt
+		mux.HandleFunc(rt.path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != rt.method {
+				writeHTTPError(w, httpError{http.StatusMethodNotAllowed,
+					fmt.Errorf("method %s not allowed", r.Method)})
+				return
+			}
+
+			// Only the daemon's credentials may change the deployment.
+			if r.Method != "GET" {
+				if err := auth.CheckDaemon(r.TLS); err != nil {
+					writeHTTPError(w, httpError{http.StatusForbidden,
+						err})
+					return
+				}
+			}
+
+			if err := rt.handle(s, w, r); err != nil {
+				writeHTTPError(w, err)
+			}
+		})
+	}
+
+	mux.HandleFunc(httpPrefix+"/openapi.json",
+		func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, openAPISpec(httpRoutes()))
+		})
+	return mux
+}
+
+func httpRoutes() []route {
+	routes := []route{{
+		method: "POST",
+		path:   httpPrefix + "/deploy",
+		summary: "Deploy a stitch, replacing the current deployment, and " +
+			"return the revision it was recorded as.  In a dry run, the " +
+			"planned changes are returned instead.",
+		params: []param{{
+			name: "dryRun",
+			description: "Return the changes the deployment would make " +
+				"without deploying it.",
+			kind: "boolean",
+		}, {
+			name:        "message",
+			description: "A description of the deployment to record with it.",
+			kind:        "string",
+		}},
+		request:  map[string]interface{}{},
+		response: engine.Plan{},
+		handle:   handleDeploy,
+	}, {
+		method:  "GET",
+		path:    httpPrefix + "/history",
+		summary: "List the transactions recently committed to the database.",
+		params: []param{{
+			name:        "table",
+			description: "Only include the changes made to this table.",
+			kind:        "string",
+		}},
+		response: []db.TxnRecord{},
+		handle:   handleHistory,
+	}, {
+		method: "GET",
+		path:   httpPrefix + "/watch",
+		summary: "Stream the changes committed to the database.  The first " +
+			"event lists every row as inserted.",
+		params: []param{{
+			name:        "table",
+			description: "Only stream changes to these tables.",
+			kind:        "string",
+			repeated:    true,
+		}},
+		response: WatchEvent{},
+		stream:   true,
+		handle:   handleWatch,
+	}, {
+		method: "POST",
+		path:   httpPrefix + "/drain",
+		summary: "Mark the minion serving the request as draining, so that " +
+			"its containers are migrated to other minions.",
+		params: []param{{
+			name:        "undo",
+			description: "Clear the draining mark instead of setting it.",
+			kind:        "boolean",
+		}},
+		response: map[string]interface{}{},
+		handle:   handleDrain,
+	}}
+
+	for _, t := range db.AllTables {
+		t := t
+		handle := func(s server, w http.ResponseWriter, r *http.Request) error {
+			return handleQuery(s, t, w, r)
+		}
+
+		name := tableName(t)
+		routes = append(routes, route{
+			method:   "GET",
+			path:     tablePath(t),
+			summary:  fmt.Sprintf("List the rows of the %s table.", name),
+			params:   tableParams(t),
+			response: tableRows[t],
+			handle:   handle,
+		})
+	}
+	return routes
+}
+
+// tableParams describes the query parameters accepted by the endpoint of table
+// 't'.  Any field of a row may be used to filter the results.
+func tableParams(t db.TableType) []param {
+	params := []param{{
+		name:        "limit",
+		description: "The maximum number of rows to return.",
+		kind:        "integer",
+	}, {
+		name:        "offset",
+		description: "The number of matching rows to skip, ordered by ID.",
+		kind:        "integer",
+	}}
+
+	rowSchema := schemaOf(tableRows[t])["items"].(map[string]interface{})
+	for _, field := range sortedKeys(rowSchema["properties"]) {
+		params = append(params, param{
+			name: field,
+			description: fmt.Sprintf("Only return rows whose %s is one "+
+				"of the given values.", field),
+			kind:     "string",
+			repeated: true,
+		})
+	}
+	return params
+}
+
+func handleQuery(s server, t db.TableType, w http.ResponseWriter,
+	r *http.Request) error {
+
+	query := &pb.DBQuery{Table: string(t)}
+	values := r.URL.Query()
+	for _, key := range sortedKeys(values) {
+		var err error
+		switch key {
+		case "limit":
+			query.Limit, err = parseInt32(values.Get(key))
+		case "offset":
+			query.Offset, err = parseInt32(values.Get(key))
+		default:
+			query.Filters = append(query.Filters,
+				&pb.Filter{Field: key, Values: values[key]})
+		}
+
+		if err != nil {
+			return httpError{http.StatusBadRequest,
+				fmt.Errorf("malformed %s: %s", key, err)}
+		}
+	}
+
+	reply, err := s.Query(context.Background(), query)
+	if err != nil {
+		return httpError{http.StatusBadRequest, err}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = fmt.Fprint(w, reply.TableContents)
+	return err
+}
+
+func handleDeploy(s server, w http.ResponseWriter, r *http.Request) error {
+	deployment, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var dryRun bool
+	if str := r.URL.Query().Get("dryRun"); str != "" {
+		if dryRun, err = strconv.ParseBool(str); err != nil {
+			return httpError{http.StatusBadRequest,
+				fmt.Errorf("malformed dryRun: %s", err)}
+		}
+	}
+
+	reply, err := s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: string(deployment),
+		DryRun:     dryRun,
+		Message:    r.URL.Query().Get("message"),
+	})
+	if err != nil {
+		return httpError{http.StatusBadRequest, err}
+	}
+
+	if !dryRun {
+		writeJSON(w, map[string]int32{"revision": reply.Revision})
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = fmt.Fprint(w, reply.Plan)
+	return err
+}
+
+func handleDrain(s server, w http.ResponseWriter, r *http.Request) error {
+	var undo bool
+	if str := r.URL.Query().Get("undo"); str != "" {
+		var err error
+		if undo, err = strconv.ParseBool(str); err != nil {
+			return httpError{http.StatusBadRequest,
+				fmt.Errorf("malformed undo: %s", err)}
+		}
+	}
+
+	_, err := s.Drain(context.Background(), &pb.DrainRequest{Draining: !undo})
+	if err != nil {
+		return httpError{http.StatusBadRequest, err}
+	}
+
+	writeJSON(w, struct{}{})
+	return nil
+}
+
+func handleHistory(s server, w http.ResponseWriter, r *http.Request) error {
+	table := r.URL.Query().Get("table")
+	if table != "" {
+		table = string(tableByName(table))
+	}
+
+	reply, err := s.QueryHistory(context.Background(),
+		&pb.HistoryQuery{Table: table})
+	if err != nil {
+		return httpError{http.StatusBadRequest, err}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = fmt.Fprint(w, reply.Records)
+	return err
+}
+
+func handleWatch(s server, w http.ResponseWriter, r *http.Request) error {
+	req := &pb.WatchRequest{}
+	for _, table := range r.URL.Query()["table"] {
+		req.Tables = append(req.Tables, string(tableByName(table)))
+	}
+
+	for _, t := range req.Tables {
+		if !validTable(db.TableType(t)) {
+			return httpError{http.StatusBadRequest,
+				fmt.Errorf("unrecognized table: %s", t)}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	return s.Watch(req, httpWatchStream{w: w, ctx: r.Context()})
+}
+
+// httpWatchStream adapts an HTTP response to the stream expected by the Watch RPC,
+// encoding each reply as a line of JSON.
+type httpWatchStream struct {
+	grpc.ServerStream
+
+	w   http.ResponseWriter
+	ctx context.Context
+}
+
+func (stream httpWatchStream) Send(reply *pb.WatchReply) error {
+	event := WatchEvent{
+		Revision: int(reply.Revision),
+		Reason:   reply.Reason,
+		Changes:  map[string]json.RawMessage{},
+	}
+
+	if reply.Time != 0 {
+		event.Time = time.Unix(0, reply.Time)
+	}
+
+	for _, tc := range reply.Tables {
+		name := tableName(db.TableType(tc.Table))
+		event.Changes[name] = json.RawMessage(tc.Changes)
+	}
+
+	if err := json.NewEncoder(stream.w).Encode(event); err != nil {
+		return err
+	}
+
+	if flusher, ok := stream.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (stream httpWatchStream) Context() context.Context {
+	return stream.ctx
+}
+
+// tableName returns the name of 't' as it appears in URLs, e.g. "machine".
+func tableName(t db.TableType) string {
+	return strings.ToLower(strings.TrimPrefix(string(t), "db."))
+}
+
+// tableByName is the inverse of tableName.  Unrecognized names are returned as is.
+func tableByName(name string) db.TableType {
+	for _, t := range db.AllTables {
+		if tableName(t) == name {
+			return t
+		}
+	}
+	return db.TableType(name)
+}
+
+func tablePath(t db.TableType) string {
+	return httpPrefix + "/tables/" + tableName(t)
+}
+
+func parseInt32(str string) (int32, error) {
+	i, err := strconv.ParseInt(str, 10, 32)
+	return int32(i), err
+}
+
+func writeJSON(w http.ResponseWriter, val interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(val); err != nil {
+		log.WithError(err).Warn("Failed to write HTTP response.")
+	}
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if httpErr, ok := err.(httpError); ok {
+		status = httpErr.status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
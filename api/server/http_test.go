@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/auth"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestHTTPQuery(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, role := range []db.Role{db.Master, db.Worker, db.Worker} {
+			m := view.InsertMachine()
+			m.Role = role
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	ts := httptest.NewServer(newHTTPHandler(server{conn}))
+	defer ts.Close()
+
+	var machines []db.Machine
+	status := httpGet(t, ts.URL+"/v1/tables/machine?Role=Worker&limit=1", &machines)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []db.Machine{{ID: 2, Role: db.Worker}}, machines)
+
+	var errReply map[string]string
+	status = httpGet(t, ts.URL+"/v1/tables/machine?Foo=bar", &errReply)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "unrecognized field: Foo", errReply["error"])
+
+	status = httpGet(t, ts.URL+"/v1/tables/machine?limit=a", &errReply)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	resp, err := http.Post(ts.URL+"/v1/tables/machine", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHTTPDeploy(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	ts := httptest.NewServer(newHTTPHandler(server{conn}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/deploy?message=first", "application/json",
+		strings.NewReader(`{"Machines":[{"Provider":"Amazon","Role":"Master"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var reply map[string]int
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&reply))
+	resp.Body.Close()
+	assert.Equal(t, map[string]int{"revision": 1}, reply)
+
+	clusters := conn.SelectFromCluster(nil)
+	assert.Len(t, clusters, 1)
+
+	deployments := conn.SelectFromDeployment(nil)
+	assert.Len(t, deployments, 1)
+	assert.Equal(t, "first", deployments[0].Message)
+
+	var records []db.TxnRecord
+	status := httpGet(t, ts.URL+"/v1/history?table=cluster", &records)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "api: deploy", records[0].Reason)
+
+	resp, err = http.Post(ts.URL+"/v1/deploy", "application/json",
+		strings.NewReader(`{`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHTTPDrain(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	ts := httptest.NewServer(newHTTPHandler(server{conn}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/drain", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		view.Commit(m)
+		return nil
+	})
+
+	resp, err = http.Post(ts.URL+"/v1/drain", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	self, _ := conn.MinionSelf()
+	assert.True(t, self.Draining)
+
+	resp, err = http.Post(ts.URL+"/v1/drain?undo=true", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	self, _ = conn.MinionSelf()
+	assert.False(t, self.Draining)
+}
+
+func TestHTTPAuthorization(t *testing.T) {
+	t.Parallel()
+
+	ca, err := auth.NewCA()
+	assert.NoError(t, err)
+
+	minion, err := ca.IssueMinion()
+	assert.NoError(t, err)
+
+	handler := newHTTPHandler(server{db.New()})
+
+	// Peers other than the daemon may read the database, but not change it.
+	req := httptest.NewRequest("GET", "/v1/tables/machine", nil)
+	req.TLS = auth.PeerState(minion)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("POST", "/v1/drain", nil)
+	req.TLS = auth.PeerState(minion)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHTTPWatch(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.InsertLabel()
+		return nil
+	})
+
+	ts := httptest.NewServer(newHTTPHandler(server{conn}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/v1/watch?table=label")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadBytes('\n')
+	assert.NoError(t, err)
+
+	var event WatchEvent
+	assert.NoError(t, json.Unmarshal(line, &event))
+	assert.JSONEq(t, `[{"ID":1,"Old":null,"New":{"Label":"","IP":"",`+
+		`"ContainerIPs":null}}]`,
+		string(event.Changes["label"]))
+
+	resp, err = http.Get(ts.URL + "/v1/watch?table=foo")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(newHTTPHandler(server{db.New()}))
+	defer ts.Close()
+
+	var spec map[string]interface{}
+	assert.Equal(t, http.StatusOK, httpGet(t, ts.URL+"/v1/openapi.json", &spec))
+
+	paths := spec["paths"].(map[string]interface{})
+	for _, rt := range httpRoutes() {
+		assert.Contains(t, paths, rt.path)
+	}
+
+	get := paths["/v1/tables/machine"].(map[string]interface{})["get"]
+	schema := lookupJSON(get, "responses", "200", "content", "application/json",
+		"schema")
+	props := lookupJSON(schema, "items", "properties")
+	assert.Equal(t, map[string]interface{}{"type": "string"},
+		props["PublicIP"])
+	assert.Equal(t, map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string"},
+	}, props["SSHKeys"])
+
+	// Fields hidden from the JSON encoding aren't described.
+	containers := schemaOf([]db.Container{})["items"].(map[string]interface{})
+	assert.NotContains(t, containers["properties"], "ID")
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"},
+		containers["properties"].(map[string]interface{})["Created"])
+}
+
+func httpGet(t *testing.T, url string, val interface{}) int {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, val))
+	return resp.StatusCode
+}
+
+func lookupJSON(val interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		val = val.(map[string]interface{})[key]
+	}
+	return val.(map[string]interface{})
+}
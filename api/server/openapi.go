@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openAPISpec generates an OpenAPI 3.0 description of 'routes'.  The schemas of
+// request and response bodies are derived from the Go types that the HTTP API
+// encodes, so the description stays in sync with the implementation.
+func openAPISpec(routes []route) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, rt := range routes {
+		op := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+		}
+
+		var params []interface{}
+		for _, p := range rt.params {
+			schema := map[string]interface{}{"type": p.kind}
+			if p.repeated {
+				schema = map[string]interface{}{
+					"type":  "array",
+					"items": schema,
+				}
+			}
+
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"schema":      schema,
+				"explode":     true,
+			})
+		}
+
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent("application/json", rt.request),
+			}
+		}
+
+		contentType := "application/json"
+		if rt.stream {
+			contentType = "application/x-ndjson"
+		}
+
+		op["responses"] = map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Success.",
+				"content":     jsonContent(contentType, rt.response),
+			},
+			"default": map[string]interface{}{
+				"description": "An error.",
+				"content": jsonContent("application/json",
+					map[string]string{"error": ""}),
+			},
+		}
+
+		pathItem, ok := paths[rt.path].(map[string]interface{})
+		if !ok {
+			pathItem = map[string]interface{}{}
+			paths[rt.path] = pathItem
+		}
+		pathItem[strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "Quilt API",
+			"version": strings.TrimPrefix(httpPrefix, "/"),
+		},
+		"paths": paths,
+	}
+}
+
+func operationID(rt route) string {
+	parts := strings.Split(strings.TrimPrefix(rt.path, httpPrefix+"/"), "/")
+	for i := range parts {
+		parts[i] = strings.Title(parts[i])
+	}
+	return strings.ToLower(rt.method) + strings.Join(parts, "")
+}
+
+func jsonContent(contentType string, val interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		contentType: map[string]interface{}{"schema": schemaOf(val)},
+	}
+}
+
+// schemaOf returns the JSON schema of the encoding of 'val' by encoding/json.
+func schemaOf(val interface{}) map[string]interface{} {
+	return typeSchema(reflect.TypeOf(val))
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			} else if name == "" {
+				name = field.Name
+			}
+			props[name] = typeSchema(field.Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	default:
+		// Interfaces may hold any value.
+		return map[string]interface{}{}
+	}
+}
+
+// sortedKeys returns the keys of the map 'm', which must be keyed by strings, in
+// sorted order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -13,7 +13,9 @@ import (
 
 	"github.com/NetSys/quilt/api"
 	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
 	"github.com/NetSys/quilt/stitch"
 
 	"golang.org/x/net/context"
@@ -26,13 +28,22 @@ type server struct {
 	conn db.Conn
 }
 
-// Run accepts incoming `quiltctl` connections and responds to them.
-func Run(conn db.Conn, listenAddr string) error {
+// Run accepts incoming `quiltctl` connections and responds to them.  Over TCP, only
+// clients with credentials signed by the same CA as 'creds' are accepted, unless
+// 'creds' is empty.  Unix sockets are protected by their file permissions instead.
+func Run(conn db.Conn, listenAddr string, creds auth.Credentials) error {
 	proto, addr, err := api.ParseListenAddress(listenAddr)
 	if err != nil {
 		return err
 	}
 
+	var opts []grpc.ServerOption
+	if proto == "tcp" {
+		if opts, err = creds.ServerOptions(); err != nil {
+			return err
+		}
+	}
+
 	var sock net.Listener
 	apiServer := server{conn}
 	for {
@@ -56,7 +67,7 @@ func Run(conn db.Conn, listenAddr string) error {
 		os.Exit(0)
 	}(sigc)
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(opts...)
 	pb.RegisterAPIServer(s, apiServer)
 	s.Serve(sock)
 
@@ -78,10 +89,23 @@ func (s server) Query(cts context.Context, query *pb.DBQuery) (*pb.QueryReply, e
 		rows = s.conn.SelectFromLabel(nil)
 	case db.ClusterTable:
 		rows = s.conn.SelectFromCluster(nil)
+	case db.PlacementTable:
+		rows = s.conn.SelectFromPlacement(nil)
+	case db.ACLTable:
+		rows = s.conn.SelectFromACL(nil)
+	case db.MinionTable:
+		rows = s.conn.SelectFromMinion(nil)
+	case db.DeploymentTable:
+		rows = s.conn.SelectFromDeployment(nil)
 	default:
 		return nil, fmt.Errorf("unrecognized table: %s", query.Table)
 	}
 
+	rows, err := filterRows(rows, query)
+	if err != nil {
+		return nil, err
+	}
+
 	json, err := json.Marshal(rows)
 	if err != nil {
 		return nil, err
@@ -93,6 +117,10 @@ func (s server) Query(cts context.Context, query *pb.DBQuery) (*pb.QueryReply, e
 func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	*pb.DeployReply, error) {
 
+	if err := auth.CheckDaemonRPC(cts); err != nil {
+		return &pb.DeployReply{}, err
+	}
+
 	stitch, err := stitch.FromJSON(deployReq.Deployment)
 	if err != nil {
 		return &pb.DeployReply{}, err
@@ -106,7 +134,18 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 		}
 	}
 
-	err = s.conn.Txn(db.ClusterTable).Run(func(view db.Database) error {
+	if deployReq.DryRun {
+		plan, err := json.Marshal(engine.PlanDeployment(s.conn, stitch))
+		if err != nil {
+			return &pb.DeployReply{}, err
+		}
+		return &pb.DeployReply{Plan: string(plan)}, nil
+	}
+
+	var revision int
+	txn := s.conn.Txn(db.ClusterTable, db.DeploymentTable).WithReason(
+		"api: deploy")
+	err = txn.Run(func(view db.Database) error {
 		cluster, err := view.GetCluster()
 		if err != nil {
 			cluster = view.InsertCluster()
@@ -114,6 +153,13 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 
 		cluster.Spec = stitch.String()
 		view.Commit(cluster)
+
+		deployment := view.InsertDeployment()
+		deployment.Time = time.Now()
+		deployment.Message = deployReq.Message
+		deployment.Spec = cluster.Spec
+		view.Commit(deployment)
+		revision = deployment.Revision
 		return nil
 	})
 	if err != nil {
@@ -126,9 +172,73 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 			err = errors.New("The Vagrant provider is in development." +
 				" The stitch will continue to run, but" +
 				" probably won't work correctly.")
-			return &pb.DeployReply{}, err
+			return &pb.DeployReply{Revision: int32(revision)}, err
+		}
+	}
+
+	return &pb.DeployReply{Revision: int32(revision)}, nil
+}
+
+func (s server) QueryHistory(cts context.Context, query *pb.HistoryQuery) (
+	*pb.HistoryReply, error) {
+
+	table := db.TableType(query.Table)
+	if table != "" && !validTable(table) {
+		return nil, fmt.Errorf("unrecognized table: %s", query.Table)
+	}
+
+	records := []db.TxnRecord{}
+	for _, rec := range s.conn.SelectFromHistory(nil) {
+		if table != "" {
+			rec = rec.Filter(func(rd db.RowDiff) bool {
+				return rd.Table == table
+			})
+		}
+
+		if len(rec.Rows) > 0 {
+			records = append(records, rec)
 		}
 	}
 
-	return &pb.DeployReply{}, nil
+	json, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.HistoryReply{Records: string(json)}, nil
+}
+
+// Drain sets whether this minion is draining.  The leader migrates the containers of
+// draining minions elsewhere, and places no new ones on them.
+func (s server) Drain(cts context.Context, req *pb.DrainRequest) (*pb.DrainReply,
+	error) {
+
+	if err := auth.CheckDaemonRPC(cts); err != nil {
+		return nil, err
+	}
+
+	txn := s.conn.Txn(db.MinionTable).WithReason("api: drain minion")
+	err := txn.Run(func(view db.Database) error {
+		self, err := view.MinionSelf()
+		if err != nil {
+			return errors.New("only minions can be drained")
+		}
+
+		self.Draining = req.Draining
+		view.Commit(self)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DrainReply{}, nil
+}
+
+func validTable(table db.TableType) bool {
+	for _, t := range db.AllTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
 }
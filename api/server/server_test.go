@@ -1,13 +1,18 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 
 	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
 	"github.com/NetSys/quilt/stitch"
 	"github.com/stretchr/testify/assert"
 )
@@ -101,6 +106,34 @@ func testInvalidImage(t *testing.T, s server, img string) {
 	assert.EqualError(t, err, "could not parse container image: "+img)
 }
 
+func TestDeployAuthorization(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn}
+
+	ca, err := auth.NewCA()
+	assert.NoError(t, err)
+
+	peerContext := func(issue func() (auth.Credentials, error)) context.Context {
+		creds, err := issue()
+		assert.NoError(t, err)
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: *auth.PeerState(creds)},
+		})
+	}
+
+	// Only the daemon's credentials may deploy.
+	deployment := `{"Machines":[{"Provider":"Amazon","Role":"Master"}]}`
+	_, err = s.Deploy(peerContext(ca.IssueMinion),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, "only the daemon is authorized")
+	assert.Empty(t, conn.SelectFromCluster(nil))
+
+	_, err = s.Deploy(peerContext(ca.IssueDaemon),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.NoError(t, err)
+	assert.Len(t, conn.SelectFromCluster(nil), 1)
+}
+
 func TestDeploy(t *testing.T) {
 	conn := db.New()
 	s := server{conn: conn}
@@ -115,10 +148,13 @@ func TestDeploy(t *testing.T) {
 		"Size":"m4.large"
 	}]}`
 
-	_, err := s.Deploy(context.Background(),
-		&pb.DeployRequest{Deployment: createMachineDeployment})
+	reply, err := s.Deploy(context.Background(), &pb.DeployRequest{
+		Deployment: createMachineDeployment,
+		Message:    "initial",
+	})
 
 	assert.NoError(t, err)
+	assert.Equal(t, int32(1), reply.Revision)
 
 	var spec string
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
@@ -135,6 +171,17 @@ func TestDeploy(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, exp, actual)
+
+	deployments := conn.SelectFromDeployment(nil)
+	assert.Len(t, deployments, 1)
+	assert.Equal(t, 1, deployments[0].Revision)
+	assert.Equal(t, "initial", deployments[0].Message)
+	assert.Equal(t, spec, deployments[0].Spec)
+
+	reply, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: createMachineDeployment})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), reply.Revision)
 }
 
 func TestVagrantDeployment(t *testing.T) {
@@ -175,3 +222,153 @@ func TestVagrantDeployment(t *testing.T) {
 
 	assert.Equal(t, exp, actual)
 }
+
+func TestQueryHistory(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn}
+
+	conn.Txn(db.AllTables...).WithReason("test").Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.PublicIP = "8.8.8.8"
+		view.Commit(m)
+
+		view.Commit(view.InsertLabel())
+		return nil
+	})
+
+	reply, err := s.QueryHistory(context.Background(),
+		&pb.HistoryQuery{Table: string(db.MachineTable)})
+	assert.NoError(t, err)
+
+	var records []db.TxnRecord
+	assert.NoError(t, json.Unmarshal([]byte(reply.Records), &records))
+	assert.Len(t, records, 1)
+	assert.Equal(t, "test", records[0].Reason)
+	assert.Equal(t, []db.RowDiff{{
+		Table:  db.MachineTable,
+		ID:     1,
+		Change: db.RowInserted,
+		Row:    db.Machine{ID: 1, PublicIP: "8.8.8.8"}.String(),
+		Fields: []db.FieldDiff{{Field: "PublicIP", New: "8.8.8.8"}},
+	}}, records[0].Rows)
+
+	reply, err = s.QueryHistory(context.Background(),
+		&pb.HistoryQuery{Table: string(db.EtcdTable)})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", reply.Records)
+
+	_, err = s.QueryHistory(context.Background(), &pb.HistoryQuery{Table: "foo"})
+	assert.EqualError(t, err, "unrecognized table: foo")
+}
+
+func TestQueryAllTables(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		p := view.InsertPlacement()
+		p.TargetLabel = "red"
+		view.Commit(p)
+
+		p = view.InsertPlacement()
+		p.TargetLabel = "blue"
+		view.Commit(p)
+
+		acl := view.InsertACL()
+		acl.Admin = []string{"1.2.3.4/32"}
+		view.Commit(acl)
+
+		m := view.InsertMinion()
+		m.PrivateIP = "10.0.0.1"
+		view.Commit(m)
+		return nil
+	})
+
+	s := server{conn}
+	for _, table := range db.AllTables {
+		_, err := s.Query(context.Background(),
+			&pb.DBQuery{Table: string(table)})
+		assert.NoError(t, err)
+	}
+
+	reply, err := s.Query(context.Background(), &pb.DBQuery{
+		Table:   string(db.PlacementTable),
+		Filters: []*pb.Filter{{Field: "TargetLabel", Values: []string{"blue"}}},
+	})
+	assert.NoError(t, err)
+
+	var placements []db.Placement
+	assert.NoError(t, json.Unmarshal([]byte(reply.TableContents), &placements))
+	assert.Len(t, placements, 1)
+	assert.Equal(t, "blue", placements[0].TargetLabel)
+
+	checkQuery(t, s, db.ACLTable,
+		`[{"ID":3,"Admin":["1.2.3.4/32"],"ApplicationPorts":null}]`)
+	checkQuery(t, s, db.MinionTable, `[{"Role":"","PrivateIP":"10.0.0.1",`+
+		`"Provider":"","Size":"","Region":"","FloatingIP":"",`+
+		`"Draining":false}]`)
+
+	_, err = s.Query(context.Background(), &pb.DBQuery{
+		Table:   string(db.MinionTable),
+		Filters: []*pb.Filter{{Field: "Foo"}},
+	})
+	assert.EqualError(t, err, "unrecognized field: Foo")
+}
+
+func TestDryRunDeploy(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn}
+
+	deployment := `
+	{"Machines":[
+		{"Provider":"Amazon",
+		"Role":"Master",
+		"Size":"m4.large"
+	}, {"Provider":"Amazon",
+		"Role":"Worker",
+		"Size":"m4.large"
+	}]}`
+
+	reply, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment, DryRun: true})
+	assert.NoError(t, err)
+
+	var plan engine.Plan
+	assert.NoError(t, json.Unmarshal([]byte(reply.Plan), &plan))
+	assert.Len(t, plan.Boot, 2)
+	assert.Empty(t, plan.Terminate)
+
+	// Nothing is deployed in a dry run.
+	assert.Empty(t, conn.SelectFromCluster(nil))
+	assert.Empty(t, conn.SelectFromMachine(nil))
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	s := server{conn: conn}
+
+	_, err := s.Drain(context.Background(), &pb.DrainRequest{Draining: true})
+	assert.EqualError(t, err, "only minions can be drained")
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		self := view.InsertMinion()
+		self.Self = true
+		view.Commit(self)
+		return nil
+	})
+
+	_, err = s.Drain(context.Background(), &pb.DrainRequest{Draining: true})
+	assert.NoError(t, err)
+	self, err := conn.MinionSelf()
+	assert.NoError(t, err)
+	assert.True(t, self.Draining)
+
+	_, err = s.Drain(context.Background(), &pb.DrainRequest{Draining: false})
+	assert.NoError(t, err)
+	self, _ = conn.MinionSelf()
+	assert.False(t, self.Draining)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/db"
+)
+
+// A rowChange is the JSON encoding of a db.RowChange.  Old is null for inserted rows,
+// and New is null for removed rows.  The ID is included explicitly because some
+// tables omit it from the JSON encoding of their rows.
+type rowChange struct {
+	ID  int
+	Old interface{}
+	New interface{}
+}
+
+// Watch streams the changes committed to the requested tables, or all tables if none
+// are requested.  The first reply lists every row currently in those tables as
+// inserted, and each subsequent reply describes a single committed transaction.
+func (s server) Watch(req *pb.WatchRequest, stream pb.API_WatchServer) error {
+	tables := db.AllTables
+	if len(req.Tables) > 0 {
+		tables = nil
+		for _, t := range req.Tables {
+			table := db.TableType(t)
+			if !validTable(table) {
+				return fmt.Errorf("unrecognized table: %s", t)
+			}
+			tables = append(tables, table)
+		}
+	}
+
+	sub := s.conn.Subscribe(tables...)
+	defer sub.Stop()
+
+	for {
+		select {
+		case cs := <-sub.C:
+			reply, err := watchReply(cs)
+			if err != nil {
+				return err
+			}
+
+			if err := stream.Send(reply); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func watchReply(cs db.ChangeSet) (*pb.WatchReply, error) {
+	reply := &pb.WatchReply{
+		Revision: int64(cs.Revision),
+		Reason:   cs.Reason,
+	}
+
+	if !cs.Time.IsZero() {
+		reply.Time = cs.Time.UnixNano()
+	}
+
+	for _, t := range db.AllTables {
+		inserted, modified, removed := cs.Changes(t)
+
+		var changes []rowChange
+		for _, c := range append(append(inserted, modified...), removed...) {
+			changes = append(changes,
+				rowChange{ID: c.ID(), Old: c.Old, New: c.New})
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return nil, err
+		}
+
+		reply.Tables = append(reply.Tables, &pb.TableChanges{
+			Table:   string(t),
+			Changes: string(changesJSON),
+		})
+	}
+	return reply, nil
+}
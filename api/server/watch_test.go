@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/NetSys/quilt/api/pb"
+	"github.com/NetSys/quilt/db"
+)
+
+type mockWatchServer struct {
+	grpc.ServerStream
+
+	ctx     context.Context
+	replies chan *pb.WatchReply
+}
+
+func (s mockWatchServer) Send(reply *pb.WatchReply) error {
+	s.replies <- reply
+	return nil
+}
+
+func (s mockWatchServer) Context() context.Context {
+	return s.ctx
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.PublicIP = "1.2.3.4"
+		view.Commit(m)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := mockWatchServer{ctx: ctx, replies: make(chan *pb.WatchReply)}
+
+	done := make(chan error)
+	go func() {
+		done <- server{conn}.Watch(&pb.WatchRequest{
+			Tables: []string{string(db.MachineTable)},
+		}, stream)
+	}()
+
+	reply := recvWatchReply(t, stream)
+	assert.Len(t, reply.Tables, 1)
+	assert.Equal(t, string(db.MachineTable), reply.Tables[0].Table)
+	checkChanges(t, reply.Tables[0].Changes,
+		`[{"ID":1,"Old":null,"New":{"ID":1,"StitchID":"","Role":"",`+
+			`"Provider":"",`+
+			`"Region":"","Size":"","DiskSize":0,"SSHKeys":null,`+
+			`"FloatingIP":"","CloudID":"","PublicIP":"1.2.3.4",`+
+			`"PrivateIP":"","Connected":false}}]`)
+
+	// Changes to other tables aren't streamed.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.InsertLabel()
+		return nil
+	})
+
+	txn := conn.Txn(db.AllTables...).WithReason("test")
+	txn.Run(func(view db.Database) error {
+		view.Remove(view.SelectFromMachine(nil)[0])
+		return nil
+	})
+
+	reply = recvWatchReply(t, stream)
+	assert.Equal(t, "test", reply.Reason)
+	assert.Equal(t, int64(conn.Revision()), reply.Revision)
+	assert.NotZero(t, reply.Time)
+	assert.Len(t, reply.Tables, 1)
+
+	var changes []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(reply.Tables[0].Changes), &changes))
+	assert.Len(t, changes, 1)
+	assert.Nil(t, changes[0]["New"])
+	assert.NotNil(t, changes[0]["Old"])
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// Container rows omit their ID from JSON, so it's sent alongside them.
+	var id int
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		c := view.InsertContainer()
+		c.StitchID = "abc"
+		view.Commit(c)
+		id = c.ID
+		return nil
+	})
+
+	ctx, cancel = context.WithCancel(context.Background())
+	stream = mockWatchServer{ctx: ctx, replies: make(chan *pb.WatchReply)}
+	go func() {
+		done <- server{conn}.Watch(&pb.WatchRequest{
+			Tables: []string{string(db.ContainerTable)},
+		}, stream)
+	}()
+
+	reply = recvWatchReply(t, stream)
+	changes = nil
+	assert.NoError(t, json.Unmarshal([]byte(reply.Tables[0].Changes), &changes))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, float64(id), changes[0]["ID"])
+	assert.NotContains(t, changes[0]["New"], "ID")
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	err := server{conn}.Watch(&pb.WatchRequest{Tables: []string{"foo"}}, stream)
+	assert.EqualError(t, err, "unrecognized table: foo")
+}
+
+func recvWatchReply(t *testing.T, stream mockWatchServer) *pb.WatchReply {
+	select {
+	case reply := <-stream.replies:
+		return reply
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for watch reply")
+	}
+	return nil
+}
+
+func checkChanges(t *testing.T, actual, exp string) {
+	var actualVal, expVal interface{}
+	assert.NoError(t, json.Unmarshal([]byte(actual), &actualVal))
+	assert.NoError(t, json.Unmarshal([]byte(exp), &expVal))
+	assert.Equal(t, expVal, actualVal)
+}
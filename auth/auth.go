@@ -0,0 +1,398 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NetSys/quilt/util"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// The files in which credentials are stored within a directory.
+const (
+	CACertFile = "ca.crt"
+	CAKeyFile  = "ca.key"
+	CertFile   = "quilt.crt"
+	KeyFile    = "quilt.key"
+)
+
+// ServerName is the name that every certificate issued by a CA is valid for.  Peers
+// are authenticated by the CA that signed their certificate rather than by their
+// address, as machines are issued certificates before their IPs are known.
+const ServerName = "quilt"
+
+// The common names of issued certificates, which identify the role of their holder.
+const (
+	DaemonName = "quilt-daemon"
+	MinionName = "quilt-minion"
+)
+
+// How long issued certificates remain valid.
+const validity = 10 * 365 * 24 * time.Hour
+
+// A CA is a certificate authority that issues the credentials used to authenticate
+// the Quilt daemon, its minions, and quiltctl to one another.
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// Credentials are a certificate and private key signed by a CA, along with the
+// certificate of the CA itself.  Each field is PEM encoded.  The zero value
+// represents the absence of credentials, in which case connections are neither
+// encrypted nor authenticated.
+type Credentials struct {
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+}
+
+// DefaultDir returns the directory in which the Quilt daemon stores its CA and
+// credentials by default, and from which quiltctl loads them.
+func DefaultDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".quilt", "tls")
+}
+
+// NewCA creates a new certificate authority with a freshly generated key.
+func NewCA() (CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return CA{}, err
+	}
+
+	template, err := certTemplate()
+	if err != nil {
+		return CA{}, err
+	}
+	template.Subject = pkix.Name{CommonName: "Quilt CA"}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		return CA{}, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return CA{}, err
+	}
+
+	return parseCA(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM)
+}
+
+// LoadOrCreateCA loads the certificate authority stored in 'dir'.  If there is
+// none, a new one is created and stored there.
+func LoadOrCreateCA(dir string) (CA, error) {
+	certPEM, certErr := util.ReadFile(filepath.Join(dir, CACertFile))
+	keyPEM, keyErr := util.ReadFile(filepath.Join(dir, CAKeyFile))
+	if certErr == nil && keyErr == nil {
+		return parseCA([]byte(certPEM), []byte(keyPEM))
+	} else if !os.IsNotExist(certErr) && certErr != nil {
+		return CA{}, certErr
+	} else if !os.IsNotExist(keyErr) && keyErr != nil {
+		return CA{}, keyErr
+	}
+
+	ca, err := NewCA()
+	if err != nil {
+		return CA{}, err
+	}
+
+	if err := util.AppFs.MkdirAll(dir, 0700); err != nil {
+		return CA{}, err
+	}
+
+	err = util.WriteFile(filepath.Join(dir, CACertFile), ca.certPEM, 0644)
+	if err != nil {
+		return CA{}, err
+	}
+
+	err = util.WriteFile(filepath.Join(dir, CAKeyFile), ca.keyPEM, 0600)
+	return ca, err
+}
+
+// IssueDaemon creates the daemon's credentials, signed by the CA.  The certificate
+// may be used to authenticate both servers and clients, so it's also what quiltctl
+// uses to connect to the daemon and minions.
+func (ca CA) IssueDaemon() (Credentials, error) {
+	return ca.issue(DaemonName, x509.ExtKeyUsageServerAuth,
+		x509.ExtKeyUsageClientAuth)
+}
+
+// IssueMinion creates credentials for a minion, signed by the CA.  The certificate
+// may only be used to authenticate servers, so that a minion can't use it to
+// control other minions.
+func (ca CA) IssueMinion() (Credentials, error) {
+	return ca.issue(MinionName, x509.ExtKeyUsageServerAuth)
+}
+
+func (ca CA) issue(name string, usage ...x509.ExtKeyUsage) (Credentials, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	template, err := certTemplate()
+	if err != nil {
+		return Credentials{}, err
+	}
+	template.Subject = pkix.Name{CommonName: name}
+	template.DNSNames = []string{ServerName}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = usage
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert,
+		&key.PublicKey, ca.key)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{
+		CACert: ca.certPEM,
+		Cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:    keyPEM,
+	}, nil
+}
+
+// Save writes 'creds' to 'dir'.
+func Save(dir string, creds Credentials) error {
+	if err := util.AppFs.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{CACertFile, creds.CACert, 0644},
+		{CertFile, creds.Cert, 0644},
+		{KeyFile, creds.Key, 0600},
+	}
+
+	for _, f := range files {
+		err := util.WriteFile(filepath.Join(dir, f.name), f.data, f.perm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads the credentials stored in 'dir' by Save.  If 'dir' doesn't contain
+// credentials, the zero Credentials are returned.
+func Load(dir string) (Credentials, error) {
+	var strs [3]string
+	for i, name := range []string{CACertFile, CertFile, KeyFile} {
+		str, err := util.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return Credentials{}, nil
+		} else if err != nil {
+			return Credentials{}, err
+		}
+		strs[i] = str
+	}
+
+	creds := Credentials{
+		CACert: []byte(strs[0]),
+		Cert:   []byte(strs[1]),
+		Key:    []byte(strs[2]),
+	}
+
+	// Catch malformed credentials here, rather than on first use.
+	if _, err := creds.tlsConfig(); err != nil {
+		return Credentials{}, fmt.Errorf("malformed credentials in %s: %s",
+			dir, err)
+	}
+	return creds, nil
+}
+
+// Enabled returns true if 'creds' holds credentials.
+func (creds Credentials) Enabled() bool {
+	return len(creds.Cert) > 0
+}
+
+// ServerOptions returns the options with which a gRPC server should be created so
+// that it only accepts connections from clients with credentials signed by the
+// same CA.  If 'creds' isn't Enabled, no options are required.
+func (creds Credentials) ServerOptions() ([]grpc.ServerOption, error) {
+	config, err := creds.ServerTLSConfig()
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}, nil
+}
+
+// DialOption returns the option with which a gRPC client should dial a server so
+// that both authenticate each other.  If 'creds' isn't Enabled, the connection is
+// made insecurely.
+func (creds Credentials) DialOption() (grpc.DialOption, error) {
+	if !creds.Enabled() {
+		return grpc.WithInsecure(), nil
+	}
+
+	config, err := creds.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.ServerName = ServerName
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// ServerTLSConfig returns the TLS configuration of a server that requires its
+// clients to present certificates signed by the same CA.  It returns nil if 'creds'
+// isn't Enabled.
+func (creds Credentials) ServerTLSConfig() (*tls.Config, error) {
+	if !creds.Enabled() {
+		return nil, nil
+	}
+
+	config, err := creds.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = config.RootCAs
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
+
+// CheckDaemon returns an error unless the peer of a TLS connection in 'state'
+// authenticated with the daemon's certificate.  Connections without TLS, for which
+// 'state' is nil, are allowed, as servers only accept them when security is
+// disabled or over Unix sockets protected by their file permissions.
+func CheckDaemon(state *tls.ConnectionState) error {
+	if state == nil {
+		return nil
+	}
+
+	for _, chain := range state.VerifiedChains {
+		if len(chain) > 0 && chain[0].Subject.CommonName == DaemonName {
+			return nil
+		}
+	}
+	return errors.New("only the daemon is authorized")
+}
+
+// CheckDaemonRPC is CheckDaemon for the connection over which the gRPC request with
+// context 'ctx' was received.
+func CheckDaemonRPC(ctx context.Context) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return CheckDaemon(&info.State)
+}
+
+// PeerState returns the state of a TLS connection, as seen by the server, whose client
+// authenticated with 'creds'.  It's intended for testing authorization.
+func PeerState(creds Credentials) *tls.ConnectionState {
+	state := &tls.ConnectionState{}
+	block, _ := pem.Decode(creds.Cert)
+	if block == nil {
+		return state
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return state
+	}
+
+	state.PeerCertificates = []*x509.Certificate{cert}
+	state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	return state
+}
+
+func (creds Credentials) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(creds.Cert, creds.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(creds.CACert) {
+		return nil, errors.New("failed to parse CA certificate")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func parseCA(certPEM, keyPEM []byte) (CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return CA{}, errors.New("failed to decode CA certificate")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return CA{}, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return CA{}, errors.New("failed to decode CA key")
+	}
+
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return CA{}, err
+	}
+
+	return CA{cert: cert, key: key, certPEM: certPEM, keyPEM: keyPEM}, nil
+}
+
+func certTemplate() (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/NetSys/quilt/util"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	ca, err := LoadOrCreateCA("/tls")
+	assert.NoError(t, err)
+
+	loaded, err := LoadOrCreateCA("/tls")
+	assert.NoError(t, err)
+	assert.Equal(t, ca.certPEM, loaded.certPEM)
+	assert.Equal(t, ca.keyPEM, loaded.keyPEM)
+
+	_, err = util.AppFs.Stat("/tls/" + CAKeyFile)
+	assert.NoError(t, err)
+
+	util.WriteFile("/bad/"+CACertFile, []byte("foo"), 0644)
+	util.WriteFile("/bad/"+CAKeyFile, []byte("bar"), 0600)
+	_, err = LoadOrCreateCA("/bad")
+	assert.EqualError(t, err, "failed to decode CA certificate")
+}
+
+func TestSaveLoad(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	creds, err := Load("/tls")
+	assert.NoError(t, err)
+	assert.False(t, creds.Enabled())
+
+	ca, err := NewCA()
+	assert.NoError(t, err)
+
+	creds, err = ca.IssueDaemon()
+	assert.NoError(t, err)
+	assert.True(t, creds.Enabled())
+
+	assert.NoError(t, Save("/tls", creds))
+	loaded, err := Load("/tls")
+	assert.NoError(t, err)
+	assert.Equal(t, creds, loaded)
+
+	util.WriteFile("/tls/"+KeyFile, []byte("foo"), 0600)
+	_, err = Load("/tls")
+	assert.Error(t, err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, err := NewCA()
+	assert.NoError(t, err)
+
+	serverCreds, err := ca.IssueMinion()
+	assert.NoError(t, err)
+
+	clientCreds, err := ca.IssueDaemon()
+	assert.NoError(t, err)
+
+	otherCA, err := NewCA()
+	assert.NoError(t, err)
+
+	otherCreds, err := otherCA.IssueDaemon()
+	assert.NoError(t, err)
+
+	serverConfig, err := serverCreds.ServerTLSConfig()
+	assert.NoError(t, err)
+
+	sock, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	assert.NoError(t, err)
+	defer sock.Close()
+
+	go func() {
+		for {
+			conn, err := sock.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+
+	dial := func(config *tls.Config) error {
+		conn, err := tls.Dial("tcp", sock.Addr().String(), config)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		_, err = ioutil.ReadAll(conn)
+		return err
+	}
+
+	config, err := clientCreds.tlsConfig()
+	assert.NoError(t, err)
+	config.ServerName = ServerName
+	assert.NoError(t, dial(config))
+
+	// The server doesn't trust clients signed by another CA.
+	config, err = otherCreds.tlsConfig()
+	assert.NoError(t, err)
+	config.ServerName = ServerName
+	config.RootCAs = serverConfig.RootCAs
+	assert.Error(t, dial(config))
+
+	// Nor does it accept minions as clients.
+	config, err = serverCreds.tlsConfig()
+	assert.NoError(t, err)
+	config.ServerName = ServerName
+	assert.Error(t, dial(config))
+
+	// Nor clients without certificates.
+	assert.Error(t, dial(&tls.Config{
+		RootCAs:    serverConfig.RootCAs,
+		ServerName: ServerName,
+	}))
+
+	// And clients don't trust servers signed by another CA.
+	config, err = otherCreds.tlsConfig()
+	assert.NoError(t, err)
+	config.ServerName = ServerName
+	_, err = tls.Dial("tcp", sock.Addr().String(), config)
+	assert.Error(t, err)
+}
+
+func TestCheckDaemon(t *testing.T) {
+	ca, err := NewCA()
+	assert.NoError(t, err)
+
+	daemon, err := ca.IssueDaemon()
+	assert.NoError(t, err)
+
+	minion, err := ca.IssueMinion()
+	assert.NoError(t, err)
+
+	assert.NoError(t, CheckDaemon(nil))
+	assert.NoError(t, CheckDaemon(PeerState(daemon)))
+	assert.EqualError(t, CheckDaemon(PeerState(minion)),
+		"only the daemon is authorized")
+	assert.Error(t, CheckDaemon(&tls.ConnectionState{}))
+
+	ctx := context.Background()
+	assert.NoError(t, CheckDaemonRPC(ctx))
+
+	ctx = peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: *PeerState(minion)},
+	})
+	assert.Error(t, CheckDaemonRPC(ctx))
+}
+
+func TestDisabled(t *testing.T) {
+	var creds Credentials
+	assert.False(t, creds.Enabled())
+
+	opts, err := creds.ServerOptions()
+	assert.NoError(t, err)
+	assert.Empty(t, opts)
+
+	config, err := creds.ServerTLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	_, err = creds.DialOption()
+	assert.NoError(t, err)
+}
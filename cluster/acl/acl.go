@@ -1,10 +1,20 @@
 package acl
 
-// ACL represents allowed traffic to a machine.
+// ACL represents allowed traffic to a machine.  If Protocol is empty, TCP, UDP and ICMP
+// traffic is allowed, otherwise only traffic of that protocol is.  ICMP has no ports.
 type ACL struct {
-	CidrIP  string
-	MinPort int
-	MaxPort int
+	CidrIP   string
+	MinPort  int
+	MaxPort  int
+	Protocol string
+}
+
+// Protocols returns the protocols of the traffic allowed by 'acl'.
+func (acl ACL) Protocols() []string {
+	if acl.Protocol == "" {
+		return []string{"tcp", "udp", "icmp"}
+	}
+	return []string{acl.Protocol}
 }
 
 // Slice is an alias for []ACL to allow for joins
@@ -7,9 +7,14 @@ import (
 )
 
 func TestSlice(t *testing.T) {
-	acl := ACL{"1.2.3.4", 1, 2}
+	acl := ACL{CidrIP: "1.2.3.4", MinPort: 1, MaxPort: 2}
 	slice := Slice([]ACL{acl})
 
 	assert.Equal(t, slice.Len(), 1)
 	assert.Equal(t, slice.Get(0), acl)
 }
+
+func TestProtocols(t *testing.T) {
+	assert.Equal(t, []string{"tcp", "udp", "icmp"}, ACL{}.Protocols())
+	assert.Equal(t, []string{"udp"}, ACL{Protocol: "udp"}.Protocols())
+}
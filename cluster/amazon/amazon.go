@@ -672,34 +672,23 @@ func syncACLs(desiredACLs []acl.ACL, desiredGroupID string,
 
 	var desiredRangeRules []*ec2.IpPermission
 	for _, acl := range desiredACLs {
-		desiredRangeRules = append(desiredRangeRules, &ec2.IpPermission{
-			FromPort: aws.Int64(int64(acl.MinPort)),
-			ToPort:   aws.Int64(int64(acl.MaxPort)),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
-				},
-			},
-			IpProtocol: aws.String("tcp"),
-		}, &ec2.IpPermission{
-			FromPort: aws.Int64(int64(acl.MinPort)),
-			ToPort:   aws.Int64(int64(acl.MaxPort)),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
-				},
-			},
-			IpProtocol: aws.String("udp"),
-		}, &ec2.IpPermission{
-			FromPort: aws.Int64(-1),
-			ToPort:   aws.Int64(-1),
-			IpRanges: []*ec2.IpRange{
-				{
-					CidrIp: aws.String(acl.CidrIP),
+		for _, protocol := range acl.Protocols() {
+			minPort, maxPort := int64(acl.MinPort), int64(acl.MaxPort)
+			if protocol == "icmp" {
+				minPort, maxPort = -1, -1
+			}
+
+			desiredRangeRules = append(desiredRangeRules, &ec2.IpPermission{
+				FromPort: aws.Int64(minPort),
+				ToPort:   aws.Int64(maxPort),
+				IpRanges: []*ec2.IpRange{
+					{
+						CidrIp: aws.String(acl.CidrIP),
+					},
 				},
-			},
-			IpProtocol: aws.String("icmp"),
-		})
+				IpProtocol: aws.String(protocol),
+			})
+		}
 	}
 
 	_, toAdd, rangesToRemove := join.HashJoin(ipPermSlice(desiredRangeRules),
@@ -722,20 +711,17 @@ func logACLs(add bool, perms []*ec2.IpPermission) {
 
 	for _, perm := range perms {
 		if len(perm.IpRanges) != 0 {
-			// Each rule has three variants (TCP, UDP, and ICMP), but
-			// we only want to log once.
 			protocol := *perm.IpProtocol
-			if protocol != "tcp" {
-				continue
-			}
-
 			cidrIP := *perm.IpRanges[0].CidrIp
-			ports := fmt.Sprintf("%d", *perm.FromPort)
-			if *perm.FromPort != *perm.ToPort {
-				ports += fmt.Sprintf("-%d", *perm.ToPort)
+			ports := "*"
+			if perm.FromPort != nil && *perm.FromPort >= 0 {
+				ports = fmt.Sprintf("%d", *perm.FromPort)
+				if *perm.FromPort != *perm.ToPort {
+					ports += fmt.Sprintf("-%d", *perm.ToPort)
+				}
 			}
 			log.WithField("ACL",
-				fmt.Sprintf("%s:%s", cidrIP, ports)).
+				fmt.Sprintf("%s:%s/%s", cidrIP, ports, protocol)).
 				Debugf("Amazon: %s ACL", action)
 		} else {
 			log.WithField("Group",
@@ -881,3 +881,29 @@ func TestUpdateFloatingIPs(t *testing.T) {
 	err := amazonCluster.UpdateFloatingIPs(mockMachines)
 	assert.Nil(t, err)
 }
+
+func TestSyncACLsProtocol(t *testing.T) {
+	t.Parallel()
+
+	toAdd, _, toRemove := syncACLs([]acl.ACL{
+		{CidrIP: "0.0.0.0/0", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "0.0.0.0/0", Protocol: "icmp"},
+	}, "", nil)
+
+	sort.Sort(ipPermSlice(toAdd))
+	assert.Empty(t, toRemove)
+	assert.Equal(t, []*ec2.IpPermission{
+		{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			FromPort:   aws.Int64(-1),
+			ToPort:     aws.Int64(-1),
+			IpProtocol: aws.String("icmp"),
+		},
+		{
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			FromPort:   aws.Int64(53),
+			ToPort:     aws.Int64(53),
+			IpProtocol: aws.String("udp"),
+		},
+	}, toAdd)
+}
@@ -4,26 +4,72 @@ import (
 	"bytes"
 	"strings"
 	"text/template"
+
+	"github.com/NetSys/quilt/auth"
 )
 
 const (
 	quiltImage = "quilt/quilt:latest"
+
+	// The directory on booted machines in which the minion's credentials are
+	// stored.
+	tlsDir = "/etc/quilt/tls"
 )
 
+// The certificate authority that issues credentials to booted minions.  If nil,
+// minions are booted without credentials, and accept unauthenticated connections.
+// The credentials are embedded in the cloud config, so their private keys are as
+// exposed as the machine's user data.  They're therefore only valid for servers.
+var ca *auth.CA
+
+// The comma-separated resolvers that booted minions forward DNS queries outside of
+// Quilt to.  If empty, minions use the resolvers of their hosts.
+var dnsUpstream string
+
+// SetCA configures the cloud config to issue each booted minion credentials signed
+// by 'newCA'.
+func SetCA(newCA auth.CA) {
+	ca = &newCA
+}
+
+// SetDNSUpstreams configures the cloud config to have each booted minion forward DNS
+// queries outside of Quilt to 'upstreams'.
+func SetDNSUpstreams(upstreams []string) {
+	dnsUpstream = strings.Join(upstreams, ",")
+}
+
 // Ubuntu generates a cloud config file for the Ubuntu operating system with the
 // corresponding `version`.
 func Ubuntu(keys []string, version string) string {
 	t := template.Must(template.New("cloudConfig").Parse(cfgTemplate))
 
+	var creds auth.Credentials
+	if ca != nil {
+		var err error
+		if creds, err = ca.IssueMinion(); err != nil {
+			panic(err)
+		}
+	}
+
 	var cloudConfigBytes bytes.Buffer
 	err := t.Execute(&cloudConfigBytes, struct {
 		QuiltImage    string
 		UbuntuVersion string
 		SSHKeys       string
+		TLSDir        string
+		CACert        string
+		Cert          string
+		Key           string
+		DNSUpstream   string
 	}{
 		QuiltImage:    quiltImage,
 		UbuntuVersion: version,
 		SSHKeys:       strings.Join(keys, "\n"),
+		TLSDir:        tlsDirIfEnabled(creds),
+		CACert:        string(creds.CACert),
+		Cert:          string(creds.Cert),
+		Key:           string(creds.Key),
+		DNSUpstream:   dnsUpstream,
 	})
 	if err != nil {
 		panic(err)
@@ -31,3 +77,10 @@ func Ubuntu(keys []string, version string) string {
 
 	return cloudConfigBytes.String()
 }
+
+func tlsDirIfEnabled(creds auth.Credentials) string {
+	if creds.Enabled() {
+		return tlsDir
+	}
+	return ""
+}
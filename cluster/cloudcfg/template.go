@@ -58,8 +58,10 @@ initialize_minion() {
 	-v /var/run/docker.sock:/var/run/docker.sock \
 	-v /etc/ssl/certs/ca-certificates.crt:/etc/ssl/certs/ca-certificates.crt \
 	-v /home/quilt/.ssh:/home/quilt/.ssh:rw \
-	-v /run/docker:/run/docker:rw {{.QuiltImage}} \
-	quilt minion
+	-v /run/docker:/run/docker:rw \
+	{{if .TLSDir}}-v {{.TLSDir}}:{{.TLSDir}}:ro {{end}}{{.QuiltImage}} \
+	quilt minion{{if .TLSDir}} -tls-dir={{.TLSDir}}{{end}}{{if .DNSUpstream}} \
+	-dns-upstream={{.DNSUpstream}}{{end}}
 	Restart=on-failure
 
 	[Install]
@@ -67,6 +69,23 @@ initialize_minion() {
 	EOF
 }
 
+install_tls() {
+	install -d -m 700 {{.TLSDir}}
+
+	cat <<- 'EOF' > {{.TLSDir}}/ca.crt
+{{.CACert}}
+	EOF
+
+	cat <<- 'EOF' > {{.TLSDir}}/quilt.crt
+{{.Cert}}
+	EOF
+
+	install -m 600 /dev/null {{.TLSDir}}/quilt.key
+	cat <<- 'EOF' > {{.TLSDir}}/quilt.key
+{{.Key}}
+	EOF
+}
+
 install_docker() {
 	echo "deb https://apt.dockerproject.org/repo ubuntu-{{.UbuntuVersion}} main" > /etc/apt/sources.list.d/docker.list
 	apt-get update
@@ -102,7 +121,8 @@ setup_user quilt "$ssh_keys"
 sudo mkdir /run/docker/plugins
 sudo chmod -R /run/docker/plugins 0755
 
-install_docker
+{{if .TLSDir}}install_tls
+{{end}}install_docker
 initialize_ovs
 initialize_docker
 initialize_minion
@@ -219,8 +219,9 @@ func (clst cluster) join() (joinResult, error) {
 		return res, err
 	}
 
-	err = clst.conn.Txn(db.ACLTable, db.ClusterTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	txn := clst.conn.Txn(db.ACLTable, db.ClusterTable, db.MachineTable).WithReason(
+		"cluster: sync cloud machines")
+	err = txn.Run(func(view db.Database) error {
 
 		namespace, err := view.GetClusterNamespace()
 		if err != nil {
@@ -296,9 +297,10 @@ func (clst cluster) syncACLs(adminACLs []string, appACLs []db.PortRange,
 	}
 	for _, appACL := range appACLs {
 		acls = append(acls, acl.ACL{
-			CidrIP:  "0.0.0.0/0",
-			MinPort: appACL.MinPort,
-			MaxPort: appACL.MaxPort,
+			CidrIP:   "0.0.0.0/0",
+			MinPort:  appACL.MinPort,
+			MaxPort:  appACL.MaxPort,
+			Protocol: appACL.Protocol,
 		})
 	}
 
@@ -9,6 +9,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/pb"
 
@@ -17,6 +18,16 @@ import (
 
 var minions map[string]*minion
 
+// The credentials with which the foreman authenticates itself to minions.
+var credentials auth.Credentials
+
+// SetCredentials sets the credentials used to connect to minions.  Minions booted
+// with credentials from the same CA only accept connections from the foreman if it
+// has them.
+func SetCredentials(creds auth.Credentials) {
+	credentials = creds
+}
+
 type client interface {
 	setMinion(pb.MinionConfig) error
 	getMinion() (pb.MinionConfig, error)
@@ -87,14 +98,21 @@ func RunOnce(conn db.Conn) {
 
 	forEachMinion(updateConfig)
 	forEachMinion(func(m *minion) {
-		if m.connected != m.machine.Connected {
-			tr := conn.Txn(db.MachineTable)
-			tr.Run(func(view db.Database) error {
-				m.machine.Connected = m.connected
-				view.Commit(m.machine)
-				return nil
-			})
+		if m.connected == m.machine.Connected {
+			return
 		}
+
+		m.machine.Connected = m.connected
+		conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+			// Look the machine up again, as it may have been modified or
+			// removed since it was selected above.
+			for _, dbm := range view.SelectFromMachineByPublicIP(
+				m.machine.PublicIP, nil) {
+				dbm.Connected = m.connected
+				view.Commit(dbm)
+			}
+			return nil
+		})
 	})
 
 	var etcdIPs []string
@@ -189,7 +207,12 @@ func updateConfig(m *minion) {
 }
 
 func newClientImpl(ip string) (client, error) {
-	cc, err := grpc.Dial(ip+":9999", grpc.WithInsecure())
+	credsOpt, err := credentials.DialOption()
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := grpc.Dial(ip+":9999", credsOpt)
 	if err != nil {
 		return nil, err
 	}
@@ -330,7 +330,23 @@ func (clst *Cluster) parseACLs(fws []*compute.Firewall) (acls []acl.ACL) {
 		if fw.Name == clst.intFW {
 			continue
 		}
+
+		// Firewalls that allow a single protocol belong to ACLs restricted to
+		// it, and the others to ACLs that allow every protocol.
+		var protocol string
+		if len(fw.Allowed) == 1 {
+			protocol = fw.Allowed[0].IPProtocol
+		}
+
 		for _, cidrIP := range fw.SourceRanges {
+			if protocol == "icmp" {
+				acls = append(acls, acl.ACL{
+					CidrIP:   cidrIP,
+					Protocol: protocol,
+				})
+				continue
+			}
+
 			for _, allowed := range fw.Allowed {
 				for _, portsStr := range allowed.Ports {
 					for _, ports := range strings.Split(
@@ -352,9 +368,10 @@ func (clst *Cluster) parseACLs(fws []*compute.Firewall) (acls []acl.ACL) {
 								portRange[1])
 						}
 						acls = append(acls, acl.ACL{
-							CidrIP:  cidrIP,
-							MinPort: minPort,
-							MaxPort: maxPort,
+							CidrIP:   cidrIP,
+							MinPort:  minPort,
+							MaxPort:  maxPort,
+							Protocol: protocol,
 						})
 					}
 				}
@@ -385,14 +402,15 @@ func (clst *Cluster) SetACLs(acls []acl.ACL) error {
 	}
 	for _, a := range toRemove {
 		toSet = append(toSet, acl.ACL{
-			MinPort: a.(acl.ACL).MinPort,
-			MaxPort: a.(acl.ACL).MaxPort,
-			CidrIP:  "", // Remove all currently allowed IPs.
+			MinPort:  a.(acl.ACL).MinPort,
+			MaxPort:  a.(acl.ACL).MaxPort,
+			Protocol: a.(acl.ACL).Protocol,
+			CidrIP:   "", // Remove all currently allowed IPs.
 		})
 	}
 
 	for acl, cidrIPs := range groupACLsByPorts(toSet) {
-		fw, err := clst.getCreateFirewall(acl.MinPort, acl.MaxPort)
+		fw, err := clst.getCreateFirewall(acl)
 		if err != nil {
 			return err
 		}
@@ -405,6 +423,7 @@ func (clst *Cluster) SetACLs(acls []acl.ACL) error {
 		if len(cidrIPs) == 0 {
 			log.WithField("ports", fmt.Sprintf(
 				"%d-%d", acl.MinPort, acl.MaxPort)).
+				WithField("protocol", acl.Protocol).
 				Debug("Google: Deleting firewall")
 			op, err = clst.gce.DeleteFirewall(clst.projID, fw.Name)
 			if err != nil {
@@ -413,6 +432,7 @@ func (clst *Cluster) SetACLs(acls []acl.ACL) error {
 		} else {
 			log.WithField("ports", fmt.Sprintf(
 				"%d-%d", acl.MinPort, acl.MaxPort)).
+				WithField("protocol", acl.Protocol).
 				WithField("CidrIPs", cidrIPs).
 				Debug("Google: Setting ACLs")
 			op, err = clst.firewallPatch(fw.Name, cidrIPs)
@@ -479,18 +499,15 @@ func (clst *Cluster) getFirewall(name string) (*compute.Firewall, error) {
 	return nil, nil
 }
 
-func (clst *Cluster) getCreateFirewall(minPort int, maxPort int) (
-	*compute.Firewall, error) {
-
-	ports := fmt.Sprintf("%d-%d", minPort, maxPort)
-	fwName := fmt.Sprintf("%s-%s", clst.ns, ports)
-
+func (clst *Cluster) getCreateFirewall(a acl.ACL) (*compute.Firewall, error) {
+	fwName := clst.firewallName(a)
 	if fw, _ := clst.getFirewall(fwName); fw != nil {
 		return fw, nil
 	}
 
 	log.WithField("name", fwName).Debug("Creating firewall")
-	op, err := clst.insertFirewall(fwName, ports, []string{"127.0.0.1/32"})
+	op, err := clst.insertFirewall(fwName, allowedTraffic(a),
+		[]string{"127.0.0.1/32"})
 	if err != nil {
 		return nil, err
 	}
@@ -518,26 +535,43 @@ func (clst *Cluster) networkExists(name string) (bool, error) {
 // This creates a firewall but does nothing else
 //
 // XXX: Assumes there is only one network
-func (clst *Cluster) insertFirewall(name, ports string, sourceRanges []string) (
-	*compute.Operation, error) {
+// firewallName returns the name of the firewall that implements the ACLs with the
+// ports and protocol of 'a'.  Firewalls for ACLs that allow every protocol are named
+// by their ports alone.
+func (clst *Cluster) firewallName(a acl.ACL) string {
+	switch a.Protocol {
+	case "":
+		return fmt.Sprintf("%s-%d-%d", clst.ns, a.MinPort, a.MaxPort)
+	case "icmp":
+		return fmt.Sprintf("%s-icmp", clst.ns)
+	default:
+		return fmt.Sprintf("%s-%s-%d-%d", clst.ns, a.Protocol, a.MinPort,
+			a.MaxPort)
+	}
+}
+
+// allowedTraffic returns the traffic that a firewall implementing 'a' must allow.
+func allowedTraffic(a acl.ACL) []*compute.FirewallAllowed {
+	var allowed []*compute.FirewallAllowed
+	for _, protocol := range a.Protocols() {
+		fwAllowed := &compute.FirewallAllowed{IPProtocol: protocol}
+		if protocol != "icmp" {
+			fwAllowed.Ports = []string{
+				fmt.Sprintf("%d-%d", a.MinPort, a.MaxPort)}
+		}
+		allowed = append(allowed, fwAllowed)
+	}
+	return allowed
+}
+
+func (clst *Cluster) insertFirewall(name string, allowed []*compute.FirewallAllowed,
+	sourceRanges []string) (*compute.Operation, error) {
 	firewall := &compute.Firewall{
 		Name: name,
 		Network: fmt.Sprintf("%s/global/networks/%s",
 			clst.baseURL,
 			clst.ns),
-		Allowed: []*compute.FirewallAllowed{
-			{
-				IPProtocol: "tcp",
-				Ports:      []string{ports},
-			},
-			{
-				IPProtocol: "udp",
-				Ports:      []string{ports},
-			},
-			{
-				IPProtocol: "icmp",
-			},
-		},
+		Allowed:      allowed,
 		SourceRanges: sourceRanges,
 	}
 
@@ -621,8 +655,9 @@ func (clst *Cluster) fwInit() error {
 		log.Debug("internal firewall already exists")
 	} else {
 		log.Debug("creating internal firewall")
-		op, err := clst.insertFirewall(
-			clst.intFW, "1-65535", []string{clst.ipv4Range})
+		op, err := clst.insertFirewall(clst.intFW,
+			allowedTraffic(acl.ACL{MinPort: 1, MaxPort: 65535}),
+			[]string{clst.ipv4Range})
 		if err != nil {
 			return err
 		}
@@ -639,8 +674,9 @@ func groupACLsByPorts(acls []acl.ACL) map[acl.ACL][]string {
 	grouped := make(map[acl.ACL][]string)
 	for _, a := range acls {
 		key := acl.ACL{
-			MinPort: a.MinPort,
-			MaxPort: a.MaxPort,
+			MinPort:  a.MinPort,
+			MaxPort:  a.MaxPort,
+			Protocol: a.Protocol,
 		}
 		if _, ok := grouped[key]; !ok {
 			grouped[key] = nil
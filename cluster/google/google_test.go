@@ -3,6 +3,7 @@ package google
 import (
 	"testing"
 
+	"github.com/NetSys/quilt/cluster/acl"
 	"github.com/NetSys/quilt/cluster/machine"
 	"github.com/NetSys/quilt/db"
 	"github.com/stretchr/testify/suite"
@@ -62,6 +63,33 @@ func (s *GoogleTestSuite) TestList() {
 	})
 }
 
+func (s *GoogleTestSuite) TestParseACLs() {
+	udp := acl.ACL{MinPort: 53, MaxPort: 53, Protocol: "udp"}
+	icmp := acl.ACL{Protocol: "icmp"}
+	all := acl.ACL{MinPort: 80, MaxPort: 80}
+
+	s.Equal("namespace-udp-53-53", s.clst.firewallName(udp))
+	s.Equal("namespace-icmp", s.clst.firewallName(icmp))
+	s.Equal("namespace-80-80", s.clst.firewallName(all))
+
+	var fws []*compute.Firewall
+	for _, a := range []acl.ACL{udp, icmp, all} {
+		fws = append(fws, &compute.Firewall{
+			Name:         s.clst.firewallName(a),
+			Allowed:      allowedTraffic(a),
+			SourceRanges: []string{"1.2.3.4/32"},
+		})
+	}
+
+	// Firewalls that allow every protocol are parsed once each for TCP and UDP.
+	s.Equal([]acl.ACL{
+		{CidrIP: "1.2.3.4/32", MinPort: 53, MaxPort: 53, Protocol: "udp"},
+		{CidrIP: "1.2.3.4/32", Protocol: "icmp"},
+		{CidrIP: "1.2.3.4/32", MinPort: 80, MaxPort: 80},
+		{CidrIP: "1.2.3.4/32", MinPort: 80, MaxPort: 80},
+	}, s.clst.parseACLs(fws))
+}
+
 func TestGoogleTestSuite(t *testing.T) {
 	suite.Run(t, new(GoogleTestSuite))
 }
@@ -2,6 +2,8 @@ package machine
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/stitch"
@@ -45,6 +47,27 @@ func ChooseSize(provider db.Provider, ram, cpu stitch.Range, maxPrice float64) s
 	}
 }
 
+// Lookup returns the description of the machine 'size' offered by 'provider'.  It
+// returns false if the size isn't recognized.
+func Lookup(provider db.Provider, size string) (Description, bool) {
+	var descriptions []Description
+	switch provider {
+	case db.Amazon:
+		descriptions = amazonDescriptions
+	case db.Google:
+		descriptions = googleDescriptions
+	case db.Vagrant:
+		return vagrantDescription(size)
+	}
+
+	for _, d := range descriptions {
+		if d.Size == size {
+			return d, true
+		}
+	}
+	return Description{}, false
+}
+
 // GroupByRegion groups machines by region.
 func GroupByRegion(machines []Machine) map[string][]Machine {
 	grouped := make(map[string][]Machine)
@@ -87,3 +110,22 @@ func vagrantSize(ramRange, cpuRange stitch.Range) string {
 	}
 	return fmt.Sprintf("%g,%g", ram, cpu)
 }
+
+// vagrantDescription parses a size generated by vagrantSize.
+func vagrantDescription(size string) (Description, bool) {
+	parts := strings.Split(size, ",")
+	if len(parts) != 2 {
+		return Description{}, false
+	}
+
+	ram, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Description{}, false
+	}
+
+	cpu, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Description{}, false
+	}
+	return Description{Size: size, RAM: ram, CPU: int(cpu)}, true
+}
@@ -3,6 +3,7 @@ package machine
 import (
 	"testing"
 
+	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/stitch"
 )
 
@@ -61,3 +62,23 @@ func TestConstraints(t *testing.T) {
 	checkConstraint(testDescriptions, stitch.Range{Min: 3},
 		stitch.Range{}, 0, "size4")
 }
+
+func TestLookup(t *testing.T) {
+	if desc, ok := Lookup(db.Amazon, "m4.large"); !ok || desc.CPU != 2 ||
+		desc.RAM != 8 {
+		t.Errorf("bad m4.large description: %v", desc)
+	}
+
+	exp := Description{Size: "2,1", RAM: 2, CPU: 1}
+	if desc, ok := Lookup(db.Vagrant, "2,1"); !ok || desc != exp {
+		t.Errorf("expected %v, got %v", exp, desc)
+	}
+
+	if _, ok := Lookup(db.Amazon, "bogus"); ok {
+		t.Error("found description of bogus Amazon size")
+	}
+
+	if _, ok := Lookup(db.Vagrant, "bogus"); ok {
+		t.Error("found description of bogus Vagrant size")
+	}
+}
@@ -14,17 +14,27 @@ type ACL struct {
 	ApplicationPorts []PortRange
 }
 
-// PortRange represents a range of ports for which to allow traffic.
+// PortRange represents a range of ports for which to allow traffic.  If Protocol is
+// set, only traffic of that protocol is allowed.
 type PortRange struct {
-	MinPort int
-	MaxPort int
+	MinPort  int
+	MaxPort  int
+	Protocol string `json:",omitempty"`
 }
 
 func (pr PortRange) String() string {
+	if pr.Protocol == "icmp" {
+		return pr.Protocol
+	}
+
 	port := fmt.Sprintf("%d", pr.MinPort)
 	if pr.MaxPort != pr.MinPort {
 		port += fmt.Sprintf("-%d", pr.MaxPort)
 	}
+
+	if pr.Protocol != "" {
+		port += "/" + pr.Protocol
+	}
 	return port
 }
 
@@ -47,6 +57,16 @@ func (db Database) SelectFromACL(check func(ACL) bool) []ACL {
 	return result
 }
 
+// SelectFromACL gets all acls in the database that satisfy the 'check'.
+func (conn Conn) SelectFromACL(check func(ACL) bool) []ACL {
+	var acls []ACL
+	conn.Txn(ACLTable).Run(func(view Database) error {
+		acls = view.SelectFromACL(check)
+		return nil
+	})
+	return acls
+}
+
 // GetACL gets the ACL row from the database. There should only ever be a single
 // ACL row.
 func (db Database) GetACL() (ACL, error) {
@@ -5,14 +5,19 @@ import (
 )
 
 // A Connection allows the members of two labels to speak to each other on the port
-// range [MinPort, MaxPort] inclusive.
+// range [MinPort, MaxPort] inclusive.  If Protocol is set, only traffic of that
+// protocol is allowed.  Connections from the public internet forward MinPort to
+// ContainerPort, if it's set.
 type Connection struct {
 	ID int `json:"-"`
 
-	From    string
-	To      string
-	MinPort int
-	MaxPort int
+	From          string
+	To            string
+	MinPort       int
+	MaxPort       int
+	Protocol      string `json:",omitempty"`
+	ContainerPort int    `json:",omitempty"`
+	Ingress       bool   `json:",omitempty"`
 }
 
 // InsertConnection creates a new connection row and inserts it into the database.
@@ -51,11 +56,13 @@ func (conn Conn) SelectFromConnection(check func(Connection) bool) []Connection
 }
 
 func (c Connection) String() string {
-	port := fmt.Sprintf("%d", c.MinPort)
-	if c.MaxPort != c.MinPort {
-		port += fmt.Sprintf("-%d", c.MaxPort)
+	port := PortRange{c.MinPort, c.MaxPort, c.Protocol}.String()
+	if c.ContainerPort != 0 {
+		port += fmt.Sprintf("->%d", c.ContainerPort)
+	}
+	if c.Ingress {
+		port += ",ingress"
 	}
-
 	return fmt.Sprintf("Connection-%d{%s->%s:%s}", c.ID, c.From, c.To, port)
 }
 
@@ -71,6 +78,12 @@ func (c Connection) less(r row) bool {
 		return c.MaxPort < o.MaxPort
 	case c.MinPort != o.MaxPort:
 		return c.MinPort < o.MinPort
+	case c.Protocol != o.Protocol:
+		return c.Protocol < o.Protocol
+	case c.ContainerPort != o.ContainerPort:
+		return c.ContainerPort < o.ContainerPort
+	case c.Ingress != o.Ingress:
+		return !c.Ingress
 	default:
 		return c.ID < o.ID
 	}
@@ -24,7 +24,84 @@ type Container struct {
 	Command    []string          `json:",omitempty"`
 	Labels     []string          `json:",omitempty"`
 	Env        map[string]string `json:",omitempty"`
+	Volumes    []Volume          `json:",omitempty"`
+	CPU        float64           `json:",omitempty"`
+	RAM        float64           `json:",omitempty"`
 	Created    time.Time         `json:","`
+
+	HealthCheck   *HealthCheck `json:",omitempty"`
+	RestartPolicy string       `json:",omitempty"`
+
+	// The priority class of the container, and the reason it was last evicted to
+	// make room for a container of higher priority, if it hasn't been placed since.
+	Priority string `json:",omitempty"`
+	Evicted  string `json:",omitempty"`
+
+	// The minion that holds the data of the container's volumes, recorded when
+	// the container is unassigned or replaced so that it's placed there again.
+	VolumeMinion string `json:",omitempty"`
+
+	// The reasons that each minion, keyed by private IP, was ruled out when the
+	// scheduler last failed to place the container.
+	PlacementErrors map[string][]string `json:",omitempty"`
+
+	// The result of the container's health check.  It's empty until the check
+	// has passed or failed enough times to decide.
+	Health string `json:",omitempty"`
+
+	// The number of times the worker has restarted the container under its
+	// restart policy.
+	Restarts int `json:",omitempty"`
+}
+
+// The values of Container.Health.
+const (
+	Healthy   = "healthy"
+	Unhealthy = "unhealthy"
+)
+
+// A HealthCheck describes how a minion probes the health of a container.  See
+// stitch.HealthCheck for the meaning of the fields.
+type HealthCheck struct {
+	Kind               string
+	Command            []string `json:",omitempty"`
+	Port               int      `json:",omitempty"`
+	Path               string   `json:",omitempty"`
+	Interval           int
+	Timeout            int
+	HealthyThreshold   int
+	UnhealthyThreshold int
+}
+
+// A Volume is storage mounted into a container at Target.  Named volumes are managed
+// by Docker, while host volumes bind the directory Source of the minion.  Either way,
+// the data lives on the minion that runs the container.
+type Volume struct {
+	Kind     string
+	Source   string
+	Target   string
+	ReadOnly bool `json:",omitempty"`
+}
+
+// SharesVolume returns true if 'a' and 'b' mount the same volume.  Named volumes are
+// the same if they have the same name, and host volumes if they have the same path.
+func SharesVolume(a, b Container) bool {
+	for _, va := range a.Volumes {
+		for _, vb := range b.Volumes {
+			if va.Kind == vb.Kind && va.Source == vb.Source {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v Volume) String() string {
+	str := v.Kind + ":" + v.Source + ":" + v.Target
+	if v.ReadOnly {
+		str += ":ro"
+	}
+	return str
 }
 
 // ContainerSlice is an alias for []Container to allow for joins
@@ -93,10 +170,42 @@ func (c Container) String() string {
 		tags = append(tags, fmt.Sprintf("Env: %s", c.Env))
 	}
 
+	if len(c.Volumes) > 0 {
+		tags = append(tags, fmt.Sprintf("Volumes: %s", c.Volumes))
+	}
+
+	if c.VolumeMinion != "" {
+		tags = append(tags, fmt.Sprintf("VolumeMinion: %s", c.VolumeMinion))
+	}
+
+	if c.CPU != 0 || c.RAM != 0 {
+		tags = append(tags, fmt.Sprintf("CPU: %g, RAM: %gGB", c.CPU, c.RAM))
+	}
+
 	if len(c.Status) > 0 {
 		tags = append(tags, fmt.Sprintf("Status: %s", c.Status))
 	}
 
+	if c.Health != "" {
+		tags = append(tags, fmt.Sprintf("Health: %s", c.Health))
+	}
+
+	if c.Restarts > 0 {
+		tags = append(tags, fmt.Sprintf("Restarts: %d", c.Restarts))
+	}
+
+	if c.Priority != "" {
+		tags = append(tags, fmt.Sprintf("Priority: %s", c.Priority))
+	}
+
+	if c.Evicted != "" {
+		tags = append(tags, fmt.Sprintf("Evicted: %s", c.Evicted))
+	}
+
+	if len(c.PlacementErrors) > 0 {
+		tags = append(tags, fmt.Sprintf("PlacementErrors: %v", c.PlacementErrors))
+	}
+
 	if !c.Created.IsZero() {
 		tags = append(tags, fmt.Sprintf("Created: %s", c.Created.String()))
 	}
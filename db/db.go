@@ -15,6 +15,8 @@ import (
 type Database struct {
 	tables  map[TableType]*table
 	idAlloc *idCounter
+	persist *persister
+	subs    *subscriptions
 }
 
 // A Trigger sends notifications when anything in their corresponding table changes.
@@ -36,7 +38,8 @@ type Conn struct {
 
 // A Transaction is a database handle on which transactions may be executed.
 type Transaction struct {
-	db Database
+	db     Database
+	reason string
 }
 
 // An idCounter is a wrapper around the global DB id providing concurrency safe use
@@ -47,21 +50,54 @@ type idCounter struct {
 
 // New creates a connection to a brand new database.
 func New() Conn {
-	db := Database{make(map[TableType]*table), &idCounter{}}
+	cn := Conn{db: newDatabase()}
+	cn.runLogger()
+	return cn
+}
+
+func newDatabase() Database {
+	db := Database{
+		tables:  make(map[TableType]*table),
+		idAlloc: &idCounter{},
+		subs:    newSubscriptions(),
+	}
 	for _, t := range AllTables {
-		db.tables[t] = newTable()
+		db.tables[t] = newTable(t)
 	}
+	return db
+}
 
-	cn := Conn{db: db}
-	cn.runLogger()
-	return cn
+// Fork creates a connection to a brand new database that holds a copy of every row
+// in 'cn'.  Changes made to the copy don't affect the original, so it may be used to
+// preview the effect of a transaction.  Triggers, subscriptions, history, and
+// persistence are not carried over.
+func (cn Conn) Fork() Conn {
+	fork := newDatabase()
+	cn.Txn(AllTables...).Run(func(view Database) error {
+		for tt, t := range view.tables {
+			for _, r := range t.rows {
+				fork.tables[tt].put(r)
+			}
+		}
+
+		view.idAlloc.Lock()
+		fork.idAlloc.curID = view.idAlloc.curID
+		view.idAlloc.Unlock()
+		return nil
+	})
+	return Conn{db: fork}
 }
 
 // Txn creates a new Transaction object connected to the same database, but with
 // restricted access to only the given tables.
 func (cn Conn) Txn(tables ...TableType) Transaction {
 	// The Transaction has the same database data, just a subset of the tables.
-	db := Database{make(map[TableType]*table), cn.db.idAlloc}
+	db := Database{
+		tables:  make(map[TableType]*table),
+		idAlloc: cn.db.idAlloc,
+		persist: cn.db.persist,
+		subs:    cn.db.subs,
+	}
 	for _, t := range tables {
 		db.tables[t] = cn.db.accessTable(t)
 	}
@@ -69,6 +105,13 @@ func (cn Conn) Txn(tables ...TableType) Transaction {
 	return Transaction{db: db}
 }
 
+// WithReason returns a copy of the Transaction that records 'reason' in the database
+// history as the explanation for any changes it makes.
+func (tr Transaction) WithReason(reason string) Transaction {
+	tr.reason = reason
+	return tr
+}
+
 // Run executes database transactions.  It takes a closure, 'do', which is operates
 // on its 'db' argument.  Transactions may be concurrent, but only if they operate on
 // independent sets of tables. Otherwise, each transaction runs sequentially on it's
@@ -78,8 +121,17 @@ func (tr Transaction) Run(do func(db Database) error) error {
 	defer tr.unlockTables()
 
 	err := do(tr.db)
+	if tr.db.persist != nil {
+		tr.db.persist.commit(tr.db)
+	}
+	tr.db.subs.publish(tr.db, tr.reason)
+
 	var alertTables []*table
 	for _, table := range tr.db.tables {
+		if len(table.dirty) > 0 {
+			table.dirty = map[int]row{}
+		}
+
 		if table.shouldAlert {
 			alertTables = append(alertTables, table)
 			table.shouldAlert = false
@@ -166,7 +218,8 @@ func (t Trigger) Stop() {
 func (db Database) insert(r row) {
 	table := db.accessTable(getTableType(r))
 	table.shouldAlert = true
-	table.rows[r.getID()] = r
+	table.markDirty(r.getID())
+	table.put(r)
 }
 
 // Commit updates the database with the data contained in row.
@@ -180,7 +233,8 @@ func (db Database) Commit(r row) {
 	}
 
 	if table.shouldAlert || !reflect.DeepEqual(r, old) {
-		table.rows[rid] = r
+		table.markDirty(rid)
+		table.put(r)
 		table.shouldAlert = true
 	}
 }
@@ -188,7 +242,8 @@ func (db Database) Commit(r row) {
 // Remove deletes row from the database.
 func (db Database) Remove(r row) {
 	table := db.accessTable(getTableType(r))
-	delete(table.rows, r.getID())
+	table.markDirty(r.getID())
+	table.delete(r.getID())
 	table.shouldAlert = true
 }
 
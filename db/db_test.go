@@ -1,14 +1,18 @@
 package db
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
+	"os"
 	"reflect"
 	"sort"
 	"testing"
 	"time"
 
+	"github.com/NetSys/quilt/util"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -628,3 +632,454 @@ func (machines mSort) Swap(i, j int) {
 func (machines mSort) Less(i, j int) bool {
 	return machines[i].ID < machines[j].ID
 }
+
+func TestPersist(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	conn, err := Open("/quilt")
+	assert.NoError(t, err)
+
+	var m1, m2 Machine
+	var clst Cluster
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		m1 = view.InsertMachine()
+		m1.StitchID = "1"
+		m1.CloudID = "cloud1"
+		view.Commit(m1)
+
+		m2 = view.InsertMachine()
+		m2.StitchID = "2"
+		view.Commit(m2)
+
+		clst = view.InsertCluster()
+		clst.Spec = "spec"
+		view.Commit(clst)
+		return nil
+	})
+
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		view.Remove(m2)
+		return nil
+	})
+
+	recovered, err := Open("/quilt")
+	assert.NoError(t, err)
+	assert.Equal(t, []Machine{m1}, recovered.SelectFromMachine(nil))
+	assert.Equal(t, []Cluster{clst}, recovered.SelectFromCluster(nil))
+
+	// IDs must never be reused after recovery.
+	recovered.Txn(MachineTable).Run(func(view Database) error {
+		assert.Equal(t, clst.ID+1, view.InsertMachine().ID)
+		return nil
+	})
+}
+
+func TestPersistTornWrite(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	conn, err := Open("/quilt")
+	assert.NoError(t, err)
+
+	var m Machine
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		m = view.InsertMachine()
+		m.CloudID = "cloud"
+		view.Commit(m)
+		return nil
+	})
+
+	// Simulate a crash in the middle of appending a record.
+	f, err := util.AppFs.OpenFile("/quilt/"+walFile, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	assert.NoError(t, err)
+	f.Close()
+
+	recovered, err := Open("/quilt")
+	assert.NoError(t, err)
+	assert.Equal(t, []Machine{m}, recovered.SelectFromMachine(nil))
+
+	// The torn record is discarded, so new records are still recoverable.
+	var m2 Machine
+	recovered.Txn(MachineTable).Run(func(view Database) error {
+		m2 = view.InsertMachine()
+		return nil
+	})
+
+	recovered, err = Open("/quilt")
+	assert.NoError(t, err)
+	machines := recovered.SelectFromMachine(nil)
+	sort.Sort(mSort(machines))
+	assert.Equal(t, []Machine{m, m2}, machines)
+}
+
+func TestReadRecordTornLength(t *testing.T) {
+	t.Parallel()
+
+	// A torn header may claim an enormous payload, which mustn't be allocated
+	// before it's found to be missing.
+	torn := []byte{0xff, 0xff, 0xff, 0xff, 0xde, 0xad, 0xbe, 0xef, 1, 2, 3}
+	var m Machine
+	err := readRecord(bytes.NewReader(torn), &m)
+	assert.Equal(t, errCorruptRecord, err)
+}
+
+func TestPersistSnapshot(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	conn, err := Open("/quilt")
+	assert.NoError(t, err)
+
+	var dbc Container
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		dbc = view.InsertContainer()
+		dbc.Image = "alpine"
+		dbc.Env = map[string]string{"a": "b"}
+		view.Commit(dbc)
+		return nil
+	})
+
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		conn.db.persist.snapshot(view)
+		return nil
+	})
+
+	info, err := util.AppFs.Stat("/quilt/" + walFile)
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	var acl ACL
+	conn.Txn(ACLTable).Run(func(view Database) error {
+		acl = view.InsertACL()
+		acl.Admin = []string{"local"}
+		view.Commit(acl)
+		return nil
+	})
+
+	recovered, err := Open("/quilt")
+	assert.NoError(t, err)
+	assert.Equal(t, []Container{dbc}, recovered.SelectFromContainer(nil))
+	recovered.Txn(ACLTable).Run(func(view Database) error {
+		assert.Equal(t, []ACL{acl}, view.SelectFromACL(nil))
+		return nil
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	conn := New()
+
+	var m Machine
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		m = view.InsertMachine()
+		return nil
+	})
+
+	sub := conn.Subscribe(MachineTable, ClusterTable)
+	defer sub.Stop()
+
+	cs := subscriptionRecv(t, sub)
+	assert.Equal(t, []RowChange{{Table: MachineTable, New: m}}, cs.Inserted)
+	assert.Empty(t, cs.Modified)
+	assert.Empty(t, cs.Removed)
+
+	// Changes to tables outside of the subscription aren't delivered.
+	conn.Txn(ContainerTable).Run(func(view Database) error {
+		view.InsertContainer()
+		return nil
+	})
+	subscriptionNoRecv(t, sub)
+
+	old := m
+	var clst Cluster
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		m.PublicIP = "1.2.3.4"
+		view.Commit(m)
+
+		clst = view.InsertCluster()
+
+		// Rows that are inserted and removed in the same transaction never
+		// existed as far as subscribers are concerned.
+		view.Remove(view.InsertMachine())
+		return nil
+	})
+
+	cs = subscriptionRecv(t, sub)
+	assert.Equal(t, []RowChange{{Table: ClusterTable, New: clst}}, cs.Inserted)
+	assert.Equal(t, []RowChange{{Table: MachineTable, Old: old, New: m}},
+		cs.Modified)
+	assert.Empty(t, cs.Removed)
+
+	// Commits that don't change the row aren't delivered.
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		view.Commit(m)
+		return nil
+	})
+	subscriptionNoRecv(t, sub)
+
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		view.Remove(m)
+		return nil
+	})
+
+	removed := subscriptionRecv(t, sub)
+	assert.Equal(t, []RowChange{{Table: MachineTable, Old: m}}, removed.Removed)
+	assert.True(t, removed.Revision > cs.Revision)
+	assert.Equal(t, removed.Revision, conn.Revision())
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	conn := New()
+
+	var machines []Machine
+	for i := 0; i < 3; i++ {
+		conn.Txn(MachineTable).Run(func(view Database) error {
+			machines = append(machines, view.InsertMachine())
+			return nil
+		})
+	}
+
+	sub, err := conn.SubscribeFrom(conn.Revision()-2, MachineTable)
+	assert.NoError(t, err)
+	defer sub.Stop()
+
+	for _, m := range machines[1:] {
+		cs := subscriptionRecv(t, sub)
+		assert.Equal(t, []RowChange{{Table: MachineTable, New: m}}, cs.Inserted)
+	}
+	subscriptionNoRecv(t, sub)
+
+	for i := 0; i < changeHistorySize; i++ {
+		conn.Txn(ContainerTable).Run(func(view Database) error {
+			view.InsertContainer()
+			return nil
+		})
+	}
+
+	_, err = conn.SubscribeFrom(0, MachineTable)
+	assert.Equal(t, ErrRevisionCompacted, err)
+
+	_, err = conn.SubscribeFrom(conn.Revision()+1, MachineTable)
+	assert.Error(t, err)
+}
+
+func subscriptionRecv(t *testing.T, sub Subscription) ChangeSet {
+	select {
+	case cs := <-sub.C:
+		return cs
+	case <-time.Tick(5 * time.Second):
+		t.Fatal("Expected ChangeSet")
+	}
+	return ChangeSet{}
+}
+
+func subscriptionNoRecv(t *testing.T, sub Subscription) {
+	select {
+	case cs := <-sub.C:
+		t.Errorf("Unexpected ChangeSet: %v", cs)
+	case <-time.Tick(25 * time.Millisecond):
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	conn := New()
+
+	var a, b, c Container
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		a = view.InsertContainer()
+		a.StitchID = "a"
+		a.Minion = "1.1.1.1"
+		view.Commit(a)
+
+		b = view.InsertContainer()
+		b.StitchID = "b"
+		b.Minion = "1.1.1.1"
+		b.IP = "10.0.0.2"
+		view.Commit(b)
+
+		c = view.InsertContainer()
+		c.StitchID = "c"
+		view.Commit(c)
+		return nil
+	})
+
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		assert.Equal(t, []Container{b},
+			view.SelectFromContainerByStitchID("b", nil))
+		assert.Empty(t, view.SelectFromContainerByStitchID("d", nil))
+
+		onMinion := view.SelectFromContainerByMinion("1.1.1.1", nil)
+		sort.Sort(ContainerSlice(onMinion))
+		assert.Equal(t, []Container{a, b}, onMinion)
+
+		withIP := view.SelectFromContainerByMinion("1.1.1.1",
+			func(dbc Container) bool {
+				return dbc.IP != ""
+			})
+		assert.Equal(t, []Container{b}, withIP)
+
+		// Moving and removing rows updates the indexes.
+		a.Minion = ""
+		view.Commit(a)
+		view.Remove(b)
+		return nil
+	})
+
+	assert.Empty(t, conn.SelectFromContainerByMinion("1.1.1.1", nil))
+
+	unassigned := conn.SelectFromContainerByMinion("", nil)
+	sort.Sort(ContainerSlice(unassigned))
+	assert.Equal(t, []Container{a, c}, unassigned)
+
+	conn.Txn(AllTables...).Run(func(view Database) error {
+		m := view.InsertMachine()
+		m.PublicIP = "8.8.8.8"
+		view.Commit(m)
+
+		assert.Equal(t, []Machine{m}, view.SelectFromMachineByPublicIP("8.8.8.8",
+			nil))
+		assert.Nil(t, view.SelectFromMachineByPublicIP("8.8.8.8",
+			func(m Machine) bool {
+				return m.Connected
+			}))
+		assert.Nil(t, view.SelectFromMachineByPublicIP("1.1.1.1", nil))
+
+		l := view.InsertLabel()
+		l.Label = "web"
+		view.Commit(l)
+		assert.Equal(t, []Label{l}, view.SelectFromLabelByLabel("web", nil))
+
+		_, err := view.MinionSelf()
+		assert.Error(t, err)
+
+		self := view.InsertMinion()
+		self.Self = true
+		view.Commit(self)
+		view.InsertMinion()
+
+		found, err := view.MinionSelf()
+		assert.NoError(t, err)
+		assert.Equal(t, self, found)
+		return nil
+	})
+}
+
+func TestHistory(t *testing.T) {
+	conn := New()
+
+	var m Machine
+	conn.Txn(MachineTable).WithReason("insert").Run(func(view Database) error {
+		m = view.InsertMachine()
+		m.Role = Master
+		view.Commit(m)
+		return nil
+	})
+
+	conn.Txn(MachineTable).WithReason("modify").Run(func(view Database) error {
+		m.PublicIP = "1.2.3.4"
+		view.Commit(m)
+		return nil
+	})
+
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		view.Remove(m)
+		return nil
+	})
+
+	history := conn.SelectFromHistory(nil)
+	assert.Len(t, history, 3)
+
+	assert.Equal(t, "insert", history[0].Reason)
+	assert.Equal(t, []RowDiff{{
+		Table:  MachineTable,
+		ID:     m.ID,
+		Change: RowInserted,
+		Row:    Machine{ID: m.ID, Role: Master}.String(),
+		Fields: []FieldDiff{{Field: "Role", New: "Master"}},
+	}}, history[0].Rows)
+
+	assert.Equal(t, "modify", history[1].Reason)
+	assert.Equal(t, []FieldDiff{{Field: "PublicIP", New: "1.2.3.4"}},
+		history[1].Rows[0].Fields)
+	assert.Equal(t, RowModified, history[1].Rows[0].Change)
+
+	assert.Equal(t, "", history[2].Reason)
+	assert.Equal(t, RowRemoved, history[2].Rows[0].Change)
+	assert.Empty(t, history[2].Rows[0].Fields)
+	assert.True(t, history[1].Revision < history[2].Revision)
+
+	modified := conn.SelectFromHistory(func(rec TxnRecord) bool {
+		return rec.Reason == "modify"
+	})
+	assert.Equal(t, history[1:2], modified)
+
+	filtered := history[0].Filter(func(rd RowDiff) bool {
+		return rd.Table == ContainerTable
+	})
+	assert.Empty(t, filtered.Rows)
+	assert.Equal(t, history[0].Revision, filtered.Revision)
+
+	// Changes to containers record their stitch ID.
+	conn.Txn(ContainerTable).Run(func(view Database) error {
+		dbc := view.InsertContainer()
+		dbc.StitchID = "abc"
+		view.Commit(dbc)
+		return nil
+	})
+
+	history = conn.SelectFromHistory(nil)
+	assert.Equal(t, "abc", history[len(history)-1].Rows[0].StitchID)
+}
+
+func TestFork(t *testing.T) {
+	conn := New()
+
+	var m Machine
+	conn.Txn(MachineTable).Run(func(view Database) error {
+		m = view.InsertMachine()
+		m.Role = Master
+		view.Commit(m)
+		return nil
+	})
+
+	fork := conn.Fork()
+	assert.Equal(t, []Machine{m}, fork.SelectFromMachine(nil))
+
+	var inserted Machine
+	fork.Txn(MachineTable).Run(func(view Database) error {
+		view.Remove(m)
+		inserted = view.InsertMachine()
+		return nil
+	})
+
+	assert.Equal(t, []Machine{inserted}, fork.SelectFromMachine(nil))
+	assert.Equal(t, []Machine{m}, conn.SelectFromMachine(nil))
+	assert.True(t, inserted.ID > m.ID)
+
+	// Indexes are rebuilt in the fork.
+	fork.Txn(MachineTable).Run(func(view Database) error {
+		inserted.PublicIP = "1.2.3.4"
+		view.Commit(inserted)
+		return nil
+	})
+	assert.Len(t, fork.SelectFromMachine(func(dbm Machine) bool {
+		return dbm.PublicIP == "1.2.3.4"
+	}), 1)
+}
+
+func TestDeploymentRevisions(t *testing.T) {
+	conn := New()
+
+	for i := 0; i < 3; i++ {
+		conn.Txn(DeploymentTable).Run(func(view Database) error {
+			view.InsertDeployment()
+			return nil
+		})
+	}
+
+	var revisions []int
+	for _, d := range conn.SelectFromDeployment(nil) {
+		revisions = append(revisions, d.Revision)
+	}
+	sort.Ints(revisions)
+	assert.Equal(t, []int{1, 2, 3}, revisions)
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"time"
+)
+
+// A Deployment records a stitch accepted by the API, so that earlier revisions of
+// the cluster's spec may be inspected and redeployed.
+type Deployment struct {
+	ID int
+
+	// Revisions are numbered from 1, in the order the stitches were deployed.
+	Revision int
+	Time     time.Time
+	Message  string
+	Spec     string `rowStringer:"omit"`
+}
+
+// InsertDeployment creates a new deployment row and inserts it into the database.
+// The new row is assigned the next revision number.
+func (db Database) InsertDeployment() Deployment {
+	revision := 1
+	for _, d := range db.SelectFromDeployment(nil) {
+		if d.Revision >= revision {
+			revision = d.Revision + 1
+		}
+	}
+
+	result := Deployment{ID: db.nextID(), Revision: revision}
+	db.insert(result)
+	return result
+}
+
+// SelectFromDeployment gets all deployments in the database that satisfy 'check'.
+func (db Database) SelectFromDeployment(check func(Deployment) bool) []Deployment {
+	deploymentTable := db.accessTable(DeploymentTable)
+	var result []Deployment
+	for _, row := range deploymentTable.rows {
+		if check == nil || check(row.(Deployment)) {
+			result = append(result, row.(Deployment))
+		}
+	}
+	return result
+}
+
+// SelectFromDeployment gets all deployments in the database that satisfy 'check'.
+func (conn Conn) SelectFromDeployment(check func(Deployment) bool) []Deployment {
+	var deployments []Deployment
+	conn.Txn(DeploymentTable).Run(func(view Database) error {
+		deployments = view.SelectFromDeployment(check)
+		return nil
+	})
+	return deployments
+}
+
+func (d Deployment) String() string {
+	return defaultString(d)
+}
+
+func (d Deployment) less(r row) bool {
+	return d.Revision < r.(Deployment).Revision
+}
+
+func (d Deployment) getID() int {
+	return d.ID
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// The kinds of change a RowDiff may describe.
+const (
+	// RowInserted indicates that the row was created by the transaction.
+	RowInserted = "insert"
+
+	// RowModified indicates that some fields of the row were changed.
+	RowModified = "modify"
+
+	// RowRemoved indicates that the row was deleted by the transaction.
+	RowRemoved = "remove"
+)
+
+// A TxnRecord is an entry in the database history describing a single committed
+// transaction.  The history is bounded, so only recent transactions are retained.
+type TxnRecord struct {
+	Revision int
+	Time     time.Time
+	Reason   string    `json:",omitempty"`
+	Rows     []RowDiff `json:",omitempty"`
+}
+
+// A RowDiff describes how a transaction changed a single row.
+type RowDiff struct {
+	Table  TableType
+	ID     int
+	Change string
+
+	// The string representation of the row after the transaction, or before it if
+	// the row was removed.
+	Row string
+
+	// The stitch ID of the row, if it's a container.
+	StitchID string `json:",omitempty"`
+
+	// The fields whose values changed.  For inserted rows, these are the fields
+	// initialized to non-zero values.
+	Fields []FieldDiff `json:",omitempty"`
+}
+
+// A FieldDiff records the value of a field before and after a transaction.
+type FieldDiff struct {
+	Field string
+	Old   string `json:",omitempty"`
+	New   string `json:",omitempty"`
+}
+
+// SelectFromHistory gets all retained TxnRecords, oldest first, that satisfy 'check'.
+func (cn Conn) SelectFromHistory(check func(TxnRecord) bool) []TxnRecord {
+	subs := cn.db.subs
+	subs.Lock()
+	history := append([]ChangeSet{}, subs.history...)
+	subs.Unlock()
+
+	var result []TxnRecord
+	for _, cs := range history {
+		rec := cs.txnRecord()
+		if check == nil || check(rec) {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+// Filter returns a copy of the TxnRecord containing only the RowDiffs for which
+// 'check' returns true.
+func (rec TxnRecord) Filter(check func(RowDiff) bool) TxnRecord {
+	var rows []RowDiff
+	for _, rd := range rec.Rows {
+		if check(rd) {
+			rows = append(rows, rd)
+		}
+	}
+	rec.Rows = rows
+	return rec
+}
+
+func (cs ChangeSet) txnRecord() TxnRecord {
+	rec := TxnRecord{Revision: cs.Revision, Time: cs.Time, Reason: cs.Reason}
+	for _, c := range cs.Inserted {
+		rec.Rows = append(rec.Rows, c.diff(RowInserted))
+	}
+
+	for _, c := range cs.Modified {
+		rec.Rows = append(rec.Rows, c.diff(RowModified))
+	}
+
+	for _, c := range cs.Removed {
+		rec.Rows = append(rec.Rows, c.diff(RowRemoved))
+	}
+	return rec
+}
+
+func (rc RowChange) diff(change string) RowDiff {
+	current := rc.New
+	if current == nil {
+		current = rc.Old
+	}
+
+	rd := RowDiff{
+		Table:  rc.Table,
+		ID:     current.(row).getID(),
+		Change: change,
+		Row:    current.(row).String(),
+	}
+
+	if dbc, ok := current.(Container); ok {
+		rd.StitchID = dbc.StitchID
+	}
+
+	if change == RowRemoved {
+		return rd
+	}
+
+	newVal := reflect.ValueOf(rc.New)
+	oldVal := reflect.Zero(newVal.Type())
+	if rc.Old != nil {
+		oldVal = reflect.ValueOf(rc.Old)
+	}
+
+	for i := 0; i < newVal.NumField(); i++ {
+		field := newVal.Type().Field(i)
+		if field.Name == "ID" || field.Tag.Get("rowStringer") == "omit" {
+			continue
+		}
+
+		o, n := oldVal.Field(i).Interface(), newVal.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+
+		fd := FieldDiff{Field: field.Name, New: fmt.Sprint(n)}
+		if rc.Old != nil {
+			fd.Old = fmt.Sprint(o)
+		}
+		rd.Fields = append(rd.Fields, fd)
+	}
+	return rd
+}
@@ -0,0 +1,149 @@
+package db
+
+import "strconv"
+
+// Names of the secondary indexes maintained by the database.
+const (
+	containerStitchIDIndex = "StitchID"
+	containerMinionIndex   = "Minion"
+	machinePublicIPIndex   = "PublicIP"
+	minionSelfIndex        = "Self"
+	labelLabelIndex        = "Label"
+)
+
+// tableIndexes declares the secondary indexes of each table.  Each index is named, and
+// maps the key extracted from a row to the set of rows with that key.  Indexes are
+// kept up to date as rows are inserted, committed, and removed.
+var tableIndexes = map[TableType]map[string]func(row) string{
+	ContainerTable: {
+		containerStitchIDIndex: func(r row) string {
+			return r.(Container).StitchID
+		},
+		containerMinionIndex: func(r row) string {
+			return r.(Container).Minion
+		},
+	},
+	MachineTable: {
+		machinePublicIPIndex: func(r row) string {
+			return r.(Machine).PublicIP
+		},
+	},
+	MinionTable: {
+		minionSelfIndex: func(r row) string {
+			return strconv.FormatBool(r.(Minion).Self)
+		},
+	},
+	LabelTable: {
+		labelLabelIndex: func(r row) string {
+			return r.(Label).Label
+		},
+	},
+}
+
+type index struct {
+	key  func(row) string
+	rows map[string]map[int]struct{}
+}
+
+func newIndex(key func(row) string) *index {
+	return &index{key: key, rows: map[string]map[int]struct{}{}}
+}
+
+func (idx *index) add(r row) {
+	k := idx.key(r)
+	if idx.rows[k] == nil {
+		idx.rows[k] = map[int]struct{}{}
+	}
+	idx.rows[k][r.getID()] = struct{}{}
+}
+
+func (idx *index) remove(r row) {
+	k := idx.key(r)
+	delete(idx.rows[k], r.getID())
+	if len(idx.rows[k]) == 0 {
+		delete(idx.rows, k)
+	}
+}
+
+// lookup returns the rows of 't' whose key in the index named 'name' is 'key'.
+func (t *table) lookup(name, key string) []row {
+	idx, ok := t.indexes[name]
+	if !ok {
+		panic("No such index: " + name)
+	}
+
+	var result []row
+	for id := range idx.rows[key] {
+		result = append(result, t.rows[id])
+	}
+	return result
+}
+
+// SelectFromContainerByStitchID gets all containers in the database with the given
+// StitchID that satisfy 'check'.
+func (db Database) SelectFromContainerByStitchID(stitchID string,
+	check func(Container) bool) []Container {
+
+	return filterContainers(db.accessTable(ContainerTable).lookup(
+		containerStitchIDIndex, stitchID), check)
+}
+
+// SelectFromContainerByMinion gets all containers in the database assigned to the
+// minion with the private IP 'minion' that satisfy 'check'.
+func (db Database) SelectFromContainerByMinion(minion string,
+	check func(Container) bool) []Container {
+
+	return filterContainers(db.accessTable(ContainerTable).lookup(
+		containerMinionIndex, minion), check)
+}
+
+// SelectFromContainerByMinion gets all containers in the database assigned to the
+// minion with the private IP 'minion' that satisfy 'check'.
+func (conn Conn) SelectFromContainerByMinion(minion string,
+	check func(Container) bool) []Container {
+
+	var containers []Container
+	conn.Txn(ContainerTable).Run(func(view Database) error {
+		containers = view.SelectFromContainerByMinion(minion, check)
+		return nil
+	})
+	return containers
+}
+
+// SelectFromMachineByPublicIP gets all machines in the database with the public IP
+// 'ip' that satisfy 'check'.
+func (db Database) SelectFromMachineByPublicIP(ip string,
+	check func(Machine) bool) []Machine {
+
+	var result []Machine
+	for _, r := range db.accessTable(MachineTable).lookup(machinePublicIPIndex, ip) {
+		if check == nil || check(r.(Machine)) {
+			result = append(result, r.(Machine))
+		}
+	}
+	return result
+}
+
+// SelectFromLabelByLabel gets all labels in the database with the name 'label' that
+// satisfy 'check'.
+func (db Database) SelectFromLabelByLabel(label string,
+	check func(Label) bool) []Label {
+
+	var result []Label
+	for _, r := range db.accessTable(LabelTable).lookup(labelLabelIndex, label) {
+		if check == nil || check(r.(Label)) {
+			result = append(result, r.(Label))
+		}
+	}
+	return result
+}
+
+func filterContainers(rows []row, check func(Container) bool) []Container {
+	var result []Container
+	for _, r := range rows {
+		if check == nil || check(r.(Container)) {
+			result = append(result, r.(Container))
+		}
+	}
+	return result
+}
@@ -7,6 +7,14 @@ type Label struct {
 	Label        string
 	IP           string
 	ContainerIPs []string
+
+	// The ContainerIPs of containers that are failing their health checks.
+	UnhealthyIPs []string `json:",omitempty"`
+
+	// The private IP of the minion running each of the label's healthy containers,
+	// so minions are listed once for every such container they run.  Ingress
+	// connections from the public internet are forwarded to them in proportion.
+	HealthyMinions []string `json:",omitempty"`
 }
 
 // LabelSlice is an alias for []Label to allow for joins
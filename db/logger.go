@@ -1,48 +1,49 @@
 package db
 
 import (
-	"sort"
+	"fmt"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+// The maximum number of rows logged per table for a single ChangeSet.
+const maxLoggedRows = 50
+
 func (conn Conn) runLogger() {
-	for _, t := range AllTables {
-		t := t
-		go func() {
-			for range conn.Trigger(t).C {
-				conn.logTable(t)
+	sub := conn.Subscribe(AllTables...)
+	go func() {
+		for cs := range sub.C {
+			for _, t := range AllTables {
+				logChanges(t, cs)
 			}
-		}()
-	}
+		}
+	}()
 }
 
-func (conn Conn) logTable(t TableType) {
-	var truncated bool
+func logChanges(t TableType, cs ChangeSet) {
+	inserted, modified, removed := cs.Changes(t)
+
 	var strs []string
-	conn.Txn(AllTables...).Run(func(view Database) error {
-		var rows []row
-		for _, v := range view.tables[t].rows {
-			if len(rows) > 50 {
-				truncated = true
-				break
-			}
+	for _, c := range inserted {
+		strs = append(strs, fmt.Sprintf("+ %s", c.New))
+	}
 
-			rows = append(rows, v)
-		}
+	for _, c := range modified {
+		strs = append(strs, fmt.Sprintf("~ %s", c.New))
+	}
 
-		sort.Sort(rowSlice(rows))
-		for _, r := range rows {
-			strs = append(strs, r.String())
-		}
-		return nil
-	})
+	for _, c := range removed {
+		strs = append(strs, fmt.Sprintf("- %s", c.Old))
+	}
 
-	if truncated {
-		strs = append(strs, "Truncated ...")
+	if len(strs) == 0 {
+		return
 	}
 
-	log.Infof("%s:\n\t%s", t, strings.Join(strs, "\n\t"))
+	if len(strs) > maxLoggedRows {
+		strs = append(strs[:maxLoggedRows], "Truncated ...")
+	}
 
+	log.Infof("%s (revision %d):\n\t%s", t, cs.Revision, strings.Join(strs, "\n\t"))
 }
@@ -19,6 +19,10 @@ type Minion struct {
 	Size       string
 	Region     string
 	FloatingIP string
+
+	// Draining minions accept no new containers, and the leader migrates the
+	// containers they already run elsewhere.
+	Draining bool
 }
 
 // InsertMinion creates a new Minion and inserts it into 'db'.
@@ -43,9 +47,7 @@ func (db Database) SelectFromMinion(check func(Minion) bool) []Minion {
 // MinionSelf returns the Minion Row corresponding to the currently running minion, or an
 // error if no such row exists.
 func (db Database) MinionSelf() (Minion, error) {
-	minions := db.SelectFromMinion(func(m Minion) bool {
-		return m.Self
-	})
+	minions := db.accessTable(MinionTable).lookup(minionSelfIndex, "true")
 
 	if len(minions) > 1 {
 		panic("multiple minions labeled Self")
@@ -55,7 +57,7 @@ func (db Database) MinionSelf() (Minion, error) {
 		return Minion{}, errors.New("no self minion")
 	}
 
-	return minions[0], nil
+	return minions[0].(Minion), nil
 }
 
 // MinionSelf returns the Minion Row corresponding to the currently running minion, or an
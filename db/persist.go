@@ -0,0 +1,356 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/NetSys/quilt/util"
+	"github.com/spf13/afero"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	walFile      = "db.wal"
+	snapshotFile = "db.snapshot"
+
+	// The number of transactions appended to the write-ahead log before it's
+	// compacted into a new snapshot.
+	snapshotInterval = 1000
+)
+
+// A persister makes a Database durable.  Every transaction that modifies the database
+// is appended to a write-ahead log as a single record, which is periodically
+// compacted into a snapshot of the entire database.  On startup, the snapshot is
+// loaded and the log replayed on top of it.
+type persister struct {
+	sync.Mutex
+
+	dir     string
+	wal     afero.File
+	records int // Records appended to the log since the last snapshot.
+
+	conn         Conn // Used to lock all tables when taking a snapshot.
+	snapshotting bool
+}
+
+// A walOp records the new value of a single row.  'Row' is nil if the row was removed.
+type walOp struct {
+	Table TableType
+	ID    int
+	Row   interface{}
+}
+
+// A walRecord contains all of the changes made by a single transaction.
+type walRecord struct {
+	NextID int
+	Ops    []walOp
+}
+
+// A snapshot contains the contents of every table in the database.
+type snapshot struct {
+	NextID int
+	Rows   []interface{}
+}
+
+func init() {
+	gob.Register(Cluster{})
+	gob.Register(Machine{})
+	gob.Register(Container{})
+	gob.Register(Minion{})
+	gob.Register(Connection{})
+	gob.Register(Label{})
+	gob.Register(Etcd{})
+	gob.Register(Placement{})
+	gob.Register(ACL{})
+	gob.Register(Deployment{})
+}
+
+// Open creates a connection to a database persisted in the directory 'dir'.  Any
+// state recorded by a previous instance of the database is recovered before Open
+// returns, and from then on, every transaction is durably logged as it commits.
+func Open(dir string) (Conn, error) {
+	if err := util.AppFs.MkdirAll(dir, 0700); err != nil {
+		return Conn{}, err
+	}
+
+	cn := Conn{db: newDatabase()}
+	if err := loadSnapshot(cn.db, filepath.Join(dir, snapshotFile)); err != nil {
+		return Conn{}, fmt.Errorf("failed to load snapshot: %s", err)
+	}
+
+	walPath := filepath.Join(dir, walFile)
+	wal, err := util.AppFs.OpenFile(walPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return Conn{}, err
+	}
+
+	records, err := replayWAL(cn.db, wal)
+	if err != nil {
+		wal.Close()
+		return Conn{}, fmt.Errorf("failed to replay write-ahead log: %s", err)
+	}
+
+	cn.db.persist = &persister{dir: dir, wal: wal, records: records, conn: cn}
+	cn.runLogger()
+	return cn, nil
+}
+
+// commit appends the rows modified by the transaction operating on 'db' to the
+// write-ahead log.  The caller must hold the locks of all tables in 'db'.
+func (p *persister) commit(db Database) {
+	var tables []TableType
+	for tt, t := range db.tables {
+		if len(t.dirty) > 0 {
+			tables = append(tables, tt)
+		}
+	}
+
+	if len(tables) == 0 {
+		return
+	}
+	sort.Sort(tableSlice(tables))
+
+	var rec walRecord
+	for _, tt := range tables {
+		t := db.tables[tt]
+
+		var ids []int
+		for id := range t.dirty {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			op := walOp{Table: tt, ID: id}
+			if r, ok := t.rows[id]; ok {
+				op.Row = r
+			}
+			rec.Ops = append(rec.Ops, op)
+		}
+	}
+
+	db.idAlloc.Lock()
+	rec.NextID = db.idAlloc.curID
+	db.idAlloc.Unlock()
+
+	p.Lock()
+	defer p.Unlock()
+
+	if err := writeRecord(p.wal, rec); err != nil {
+		log.WithError(err).Error("Failed to append to the write-ahead log.")
+		return
+	}
+
+	if err := p.wal.Sync(); err != nil {
+		log.WithError(err).Error("Failed to sync the write-ahead log.")
+		return
+	}
+
+	p.records++
+	if p.records >= snapshotInterval && !p.snapshotting {
+		p.snapshotting = true
+		go p.conn.Txn(AllTables...).Run(func(view Database) error {
+			p.snapshot(view)
+			return nil
+		})
+	}
+}
+
+// snapshot writes the entire contents of 'db' to a new snapshot and truncates the
+// write-ahead log.  The caller must hold the locks of every table.
+func (p *persister) snapshot(db Database) {
+	p.Lock()
+	defer p.Unlock()
+	p.snapshotting = false
+
+	var snap snapshot
+	for _, tt := range AllTables {
+		for _, r := range db.tables[tt].rows {
+			snap.Rows = append(snap.Rows, r)
+		}
+	}
+
+	db.idAlloc.Lock()
+	snap.NextID = db.idAlloc.curID
+	db.idAlloc.Unlock()
+
+	if err := writeSnapshot(p.dir, snap); err != nil {
+		log.WithError(err).Error("Failed to write database snapshot.")
+		return
+	}
+
+	// The write-ahead log is only truncated after the new snapshot is in place.  If
+	// we crash in between, the records already in the snapshot are simply replayed
+	// a second time, which is harmless as each records the full value of its rows.
+	if err := p.wal.Truncate(0); err != nil {
+		log.WithError(err).Error("Failed to truncate the write-ahead log.")
+		return
+	}
+
+	if _, err := p.wal.Seek(0, io.SeekStart); err != nil {
+		log.WithError(err).Error("Failed to rewind the write-ahead log.")
+		return
+	}
+
+	p.records = 0
+}
+
+func writeSnapshot(dir string, snap snapshot) error {
+	tmpPath := filepath.Join(dir, snapshotFile+".tmp")
+	f, err := util.AppFs.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := writeRecord(f, snap); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return util.AppFs.Rename(tmpPath, filepath.Join(dir, snapshotFile))
+}
+
+func loadSnapshot(db Database, path string) error {
+	f, err := util.AppFs.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := readRecord(f, &snap); err != nil {
+		return err
+	}
+
+	for _, r := range snap.Rows {
+		r := r.(row)
+		db.accessTable(getTableType(r)).put(r)
+		db.idAlloc.curID = maxInt(db.idAlloc.curID, r.getID())
+	}
+	db.idAlloc.curID = maxInt(db.idAlloc.curID, snap.NextID)
+	return nil
+}
+
+// replayWAL applies every intact record in 'wal' to 'db', and returns the number of
+// records applied.  A record that was only partially written when the log was last
+// appended to is discarded, so that each transaction is recovered in its entirety or
+// not at all.
+func replayWAL(db Database, wal afero.File) (int, error) {
+	var records int
+	var offset int64
+	for {
+		var rec walRecord
+		err := readRecord(wal, &rec)
+		if err == io.EOF {
+			break
+		} else if err == errCorruptRecord {
+			log.WithField("offset", offset).Warn(
+				"Discarding incomplete write-ahead log record.")
+			if err := wal.Truncate(offset); err != nil {
+				return 0, err
+			}
+			break
+		} else if err != nil {
+			return 0, err
+		}
+
+		for _, op := range rec.Ops {
+			t := db.accessTable(op.Table)
+			if op.Row == nil {
+				t.delete(op.ID)
+			} else {
+				t.put(op.Row.(row))
+			}
+			db.idAlloc.curID = maxInt(db.idAlloc.curID, op.ID)
+		}
+		db.idAlloc.curID = maxInt(db.idAlloc.curID, rec.NextID)
+
+		records++
+		if offset, err = wal.Seek(0, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := wal.Seek(offset, io.SeekStart)
+	return records, err
+}
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// Records are framed by their length and a checksum so that torn writes can be
+// detected.
+const recordHeaderSize = 8
+
+func writeRecord(w io.Writer, val interface{}) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(val); err != nil {
+		return err
+	}
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+payload.Len())
+	binary.BigEndian.PutUint32(buf[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	buf = append(buf, payload.Bytes()...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func readRecord(r io.Reader, val interface{}) error {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err == io.EOF {
+		return io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return errCorruptRecord
+	} else if err != nil {
+		return err
+	}
+
+	// The length may come from a torn record, so rather than trusting it with an
+	// allocation up front, the buffer only grows as the payload is actually read.
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, size); err == io.EOF {
+		return errCorruptRecord
+	} else if err != nil {
+		return err
+	}
+
+	payload := buf.Bytes()
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return errCorruptRecord
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(val); err != nil {
+		return errCorruptRecord
+	}
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -18,6 +18,12 @@ type Placement struct {
 	Size       string
 	Region     string
 	FloatingIP string
+
+	// Spread Constraint.  The containers of TargetLabel are spread across the
+	// failure domains named by SpreadAcross, so that no two domains differ by more
+	// than MaxSkew containers.
+	SpreadAcross string
+	MaxSkew      int
 }
 
 // PlacementSlice is an alias for []Placement to allow for joins
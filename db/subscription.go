@@ -0,0 +1,353 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The number of committed ChangeSets retained so that subscribers may resume from an
+// earlier revision.
+const changeHistorySize = 1024
+
+// ErrRevisionCompacted is returned when a subscriber attempts to resume from a
+// revision that is no longer retained by the database.
+var ErrRevisionCompacted = errors.New("revision has been compacted")
+
+// A RowChange records the value of a row before and after a transaction.  Old is nil
+// for inserted rows, and New is nil for removed rows.
+type RowChange struct {
+	Table TableType
+	Old   interface{}
+	New   interface{}
+}
+
+// A ChangeSet contains the rows modified by a single committed transaction.  Each
+// transaction that modifies the database is assigned a strictly increasing Revision.
+type ChangeSet struct {
+	Revision int
+	Time     time.Time
+	Reason   string // Why the transaction was run, as supplied by the caller.
+
+	Inserted []RowChange
+	Modified []RowChange
+	Removed  []RowChange
+}
+
+// A Subscription delivers the ChangeSets committed to a set of tables.
+type Subscription struct {
+	C    chan ChangeSet // The channel on which ChangeSets are delivered.
+	stop chan struct{}
+}
+
+type subscriber struct {
+	tables map[TableType]struct{}
+	sub    Subscription
+
+	sync.Mutex
+	queue  []ChangeSet
+	notify chan struct{}
+}
+
+// subscriptions tracks the subscribers of a database, along with a bounded history
+// of the ChangeSets committed to it.
+type subscriptions struct {
+	sync.Mutex
+	revision    int
+	history     []ChangeSet
+	subscribers map[*subscriber]struct{}
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{subscribers: map[*subscriber]struct{}{}}
+}
+
+// Subscribe registers a new Subscription that watches changes to the tables 'tt'.  So
+// that subscribers properly initialize, the first ChangeSet delivered lists every
+// row currently in those tables as inserted.  Each subsequent ChangeSet contains only
+// the rows modified by a single transaction.
+func (cn Conn) Subscribe(tt ...TableType) Subscription {
+	var sub *subscriber
+	cn.Txn(tt...).Run(func(view Database) error {
+		subs := view.subs
+		subs.Lock()
+		defer subs.Unlock()
+
+		initial := ChangeSet{Revision: subs.revision}
+		for _, t := range sortedTables(tt) {
+			for _, r := range sortedRows(view.accessTable(t).rows) {
+				initial.Inserted = append(initial.Inserted,
+					RowChange{Table: t, New: r})
+			}
+		}
+
+		sub = subs.subscribe(tt)
+		sub.enqueue(initial)
+		return nil
+	})
+
+	return sub.sub
+}
+
+// SubscribeFrom registers a new Subscription that watches changes to the tables 'tt',
+// beginning with the first ChangeSet committed after 'revision'.  This allows a
+// subscriber to resume where it left off without missing any changes.  If the
+// database no longer retains the ChangeSets following 'revision',
+// ErrRevisionCompacted is returned.
+func (cn Conn) SubscribeFrom(revision int, tt ...TableType) (Subscription, error) {
+	var sub *subscriber
+	err := cn.Txn(tt...).Run(func(view Database) error {
+		subs := view.subs
+		subs.Lock()
+		defer subs.Unlock()
+
+		if revision > subs.revision {
+			return errors.New("revision has not been committed")
+		}
+
+		oldest := subs.revision - len(subs.history)
+		if revision < oldest {
+			return ErrRevisionCompacted
+		}
+
+		sub = subs.subscribe(tt)
+		missed := subs.history[len(subs.history)-(subs.revision-revision):]
+		for _, cs := range missed {
+			if filtered, ok := sub.filter(cs); ok {
+				sub.enqueue(filtered)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return Subscription{}, err
+	}
+	return sub.sub, nil
+}
+
+// Revision returns the revision of the most recently committed ChangeSet.
+func (cn Conn) Revision() int {
+	subs := cn.db.subs
+	subs.Lock()
+	defer subs.Unlock()
+	return subs.revision
+}
+
+// Stop a running subscription thus allowing resources to be deallocated.
+func (s Subscription) Stop() {
+	close(s.stop)
+}
+
+// Changes returns every RowChange in the ChangeSet that affects the table 't'.
+func (cs ChangeSet) Changes(t TableType) (inserted, modified, removed []RowChange) {
+	for _, c := range cs.Inserted {
+		if c.Table == t {
+			inserted = append(inserted, c)
+		}
+	}
+
+	for _, c := range cs.Modified {
+		if c.Table == t {
+			modified = append(modified, c)
+		}
+	}
+
+	for _, c := range cs.Removed {
+		if c.Table == t {
+			removed = append(removed, c)
+		}
+	}
+	return inserted, modified, removed
+}
+
+func (cs ChangeSet) empty() bool {
+	return len(cs.Inserted) == 0 && len(cs.Modified) == 0 && len(cs.Removed) == 0
+}
+
+// The caller must hold the lock on 'subs'.
+func (subs *subscriptions) subscribe(tt []TableType) *subscriber {
+	sub := &subscriber{
+		tables: map[TableType]struct{}{},
+		sub: Subscription{
+			C:    make(chan ChangeSet),
+			stop: make(chan struct{}),
+		},
+		notify: make(chan struct{}, 1),
+	}
+
+	for _, t := range tt {
+		sub.tables[t] = struct{}{}
+	}
+
+	subs.subscribers[sub] = struct{}{}
+	go sub.run()
+	return sub
+}
+
+// publish assigns the next revision to the changes made by the transaction operating
+// on 'db', and delivers them to the interested subscribers.  The caller must hold the
+// locks of all tables in 'db'.
+func (subs *subscriptions) publish(db Database, reason string) {
+	cs := collectChanges(db)
+	if cs.empty() {
+		return
+	}
+
+	subs.Lock()
+	defer subs.Unlock()
+
+	subs.revision++
+	cs.Revision = subs.revision
+	cs.Time = time.Now()
+	cs.Reason = reason
+
+	subs.history = append(subs.history, cs)
+	if len(subs.history) > changeHistorySize {
+		subs.history = subs.history[len(subs.history)-changeHistorySize:]
+	}
+
+	for sub := range subs.subscribers {
+		select {
+		case <-sub.sub.stop:
+			delete(subs.subscribers, sub)
+			continue
+		default:
+		}
+
+		if filtered, ok := sub.filter(cs); ok {
+			sub.enqueue(filtered)
+		}
+	}
+}
+
+// collectChanges computes the rows that were actually modified in each table of 'db'
+// by comparing their current values with those from before the transaction.
+func collectChanges(db Database) ChangeSet {
+	var cs ChangeSet
+	for tt, t := range db.tables {
+		for id, old := range t.dirty {
+			new, ok := t.rows[id]
+			switch {
+			case old == nil && !ok:
+				continue
+			case old == nil:
+				cs.Inserted = append(cs.Inserted,
+					RowChange{Table: tt, New: new})
+			case !ok:
+				cs.Removed = append(cs.Removed,
+					RowChange{Table: tt, Old: old})
+			case !reflect.DeepEqual(old, new):
+				cs.Modified = append(cs.Modified,
+					RowChange{Table: tt, Old: old, New: new})
+			}
+		}
+	}
+
+	sort.Sort(rowChangeSlice(cs.Inserted))
+	sort.Sort(rowChangeSlice(cs.Modified))
+	sort.Sort(rowChangeSlice(cs.Removed))
+	return cs
+}
+
+// filter returns the subset of 'cs' that concerns the subscriber's tables, or false
+// if there is none.
+func (sub *subscriber) filter(cs ChangeSet) (ChangeSet, bool) {
+	keep := func(changes []RowChange) []RowChange {
+		var res []RowChange
+		for _, c := range changes {
+			if _, ok := sub.tables[c.Table]; ok {
+				res = append(res, c)
+			}
+		}
+		return res
+	}
+
+	filtered := ChangeSet{
+		Revision: cs.Revision,
+		Time:     cs.Time,
+		Reason:   cs.Reason,
+		Inserted: keep(cs.Inserted),
+		Modified: keep(cs.Modified),
+		Removed:  keep(cs.Removed),
+	}
+	return filtered, !filtered.empty()
+}
+
+// Subscribers may fall behind the database, so each buffers its pending ChangeSets
+// rather than blocking the transactions that commit them.
+func (sub *subscriber) enqueue(cs ChangeSet) {
+	sub.Lock()
+	sub.queue = append(sub.queue, cs)
+	sub.Unlock()
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscriber) run() {
+	for {
+		select {
+		case <-sub.notify:
+		case <-sub.sub.stop:
+			return
+		}
+
+		sub.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		sub.Unlock()
+
+		for _, cs := range queue {
+			select {
+			case sub.sub.C <- cs:
+			case <-sub.sub.stop:
+				return
+			}
+		}
+	}
+}
+
+func sortedTables(tt []TableType) []TableType {
+	tables := append(tableSlice{}, tt...)
+	sort.Sort(tables)
+	return tables
+}
+
+func sortedRows(rows map[int]row) []row {
+	var res []row
+	for _, r := range rows {
+		res = append(res, r)
+	}
+	sort.Sort(rowSlice(res))
+	return res
+}
+
+type rowChangeSlice []RowChange
+
+func (rcs rowChangeSlice) Len() int {
+	return len(rcs)
+}
+
+func (rcs rowChangeSlice) Swap(i, j int) {
+	rcs[i], rcs[j] = rcs[j], rcs[i]
+}
+
+func (rcs rowChangeSlice) Less(i, j int) bool {
+	if rcs[i].Table != rcs[j].Table {
+		return rcs[i].Table < rcs[j].Table
+	}
+	return rcs[i].ID() < rcs[j].ID()
+}
+
+// ID returns the database ID of the row that changed.
+func (rc RowChange) ID() int {
+	if rc.New != nil {
+		return rc.New.(row).getID()
+	}
+	return rc.Old.(row).getID()
+}
@@ -35,26 +35,72 @@ var PlacementTable = TableType(reflect.TypeOf(Placement{}).String())
 // ACLTable is the type of the ACL table.
 var ACLTable = TableType(reflect.TypeOf(ACL{}).String())
 
+// DeploymentTable is the type of the deployment table.
+var DeploymentTable = TableType(reflect.TypeOf(Deployment{}).String())
+
 // AllTables is a slice of all the db TableTypes. It is used primarily for tests,
 // where there is no reason to put lots of thought into which tables a Transaction
 // should use.
 var AllTables = []TableType{ClusterTable, MachineTable, ContainerTable, MinionTable,
-	ConnectionTable, LabelTable, EtcdTable, PlacementTable, ACLTable}
+	ConnectionTable, LabelTable, EtcdTable, PlacementTable, ACLTable,
+	DeploymentTable}
 
 type table struct {
 	rows map[int]row
 
+	// The rows modified by the current transaction, mapped to their values from
+	// before the transaction began (nil for newly inserted rows).
+	dirty map[int]row
+
+	indexes map[string]*index
+
 	triggers    map[Trigger]struct{}
 	shouldAlert bool
 	sync.Mutex
 }
 
-func newTable() *table {
-	return &table{
+func newTable(tt TableType) *table {
+	t := &table{
 		rows:        make(map[int]row),
+		dirty:       make(map[int]row),
+		indexes:     make(map[string]*index),
 		triggers:    make(map[Trigger]struct{}),
 		shouldAlert: false,
 	}
+
+	for name, key := range tableIndexes[tt] {
+		t.indexes[name] = newIndex(key)
+	}
+	return t
+}
+
+// put stores 'r' in the table, replacing any previous version of the row, and updates
+// the table's indexes accordingly.
+func (t *table) put(r row) {
+	id := r.getID()
+	if old, ok := t.rows[id]; ok {
+		for _, idx := range t.indexes {
+			idx.remove(old)
+		}
+	}
+
+	t.rows[id] = r
+	for _, idx := range t.indexes {
+		idx.add(r)
+	}
+}
+
+// delete removes the row 'id' from the table and its indexes.
+func (t *table) delete(id int) {
+	old, ok := t.rows[id]
+	if !ok {
+		return
+	}
+
+	for _, idx := range t.indexes {
+		idx.remove(old)
+	}
+	delete(t.rows, id)
 }
 
 func (t *table) alert() {
@@ -72,3 +118,11 @@ func (t *table) alert() {
 		}
 	}
 }
+
+// markDirty records that the row 'id' is about to be modified by the current
+// transaction, preserving its original value.
+func (t *table) markDirty(id int) {
+	if _, ok := t.dirty[id]; !ok {
+		t.dirty[id] = t.rows[id]
+	}
+}
@@ -16,8 +16,8 @@ var defaultDiskSize = 32
 // Run updates the database in response to stitch changes in the cluster table.
 func Run(conn db.Conn) {
 	for range conn.TriggerTick(30, db.ClusterTable, db.MachineTable, db.ACLTable).C {
-		conn.Txn(db.ACLTable, db.ClusterTable,
-			db.MachineTable).Run(updateTxn)
+		conn.Txn(db.ACLTable, db.ClusterTable, db.MachineTable).WithReason(
+			"engine: update machines").Run(updateTxn)
 	}
 }
 
@@ -52,8 +52,9 @@ func aclTxn(view db.Database, specHandle stitch.Stitch) {
 	for _, conn := range specHandle.Connections {
 		if conn.From == stitch.PublicInternetLabel {
 			applicationPorts = append(applicationPorts, db.PortRange{
-				MinPort: conn.MinPort,
-				MaxPort: conn.MaxPort,
+				MinPort:  conn.MinPort,
+				MaxPort:  conn.MaxPort,
+				Protocol: conn.Protocol,
 			})
 		}
 	}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
+)
+
+// A Plan describes the changes that deploying a stitch would make to the cluster.
+type Plan struct {
+	Boot      []db.Machine `json:",omitempty"`
+	Terminate []db.Machine `json:",omitempty"`
+	Keep      []db.Machine `json:",omitempty"`
+
+	ACL ACLPlan
+
+	// The containers added to and removed from each label.  Labels whose
+	// containers are unchanged are omitted.
+	Containers []LabelPlan `json:",omitempty"`
+}
+
+// An ACLPlan describes the changes to the cluster's access control list.
+type ACLPlan struct {
+	AddAdmin    []string       `json:",omitempty"`
+	RemoveAdmin []string       `json:",omitempty"`
+	AddPorts    []db.PortRange `json:",omitempty"`
+	RemovePorts []db.PortRange `json:",omitempty"`
+}
+
+// A LabelPlan describes the containers that would be added to and removed from a
+// label.  Containers that aren't part of any label are listed under the empty label.
+type LabelPlan struct {
+	Label  string
+	Add    []db.Container `json:",omitempty"`
+	Remove []db.Container `json:",omitempty"`
+}
+
+// Empty returns true if the plan makes no changes.
+func (plan Plan) Empty() bool {
+	acl := plan.ACL
+	return len(plan.Boot) == 0 && len(plan.Terminate) == 0 &&
+		len(plan.Containers) == 0 && len(acl.AddAdmin) == 0 &&
+		len(acl.RemoveAdmin) == 0 && len(acl.AddPorts) == 0 &&
+		len(acl.RemovePorts) == 0
+}
+
+// PlanDeployment computes the changes that deploying 'spec' would make, without
+// making them.  The engine runs against a fork of 'conn', so the plan reflects
+// exactly the decisions it would make on the real database.  Containers are
+// compared against those in the currently deployed stitch, as the daemon doesn't
+// track the containers themselves.
+func PlanDeployment(conn db.Conn, spec stitch.Stitch) Plan {
+	var plan Plan
+	fork := conn.Fork()
+	fork.Txn(db.ACLTable, db.ClusterTable, db.MachineTable).Run(
+		func(view db.Database) error {
+			var oldSpec stitch.Stitch
+			if cluster, err := view.GetCluster(); err == nil {
+				oldSpec, _ = stitch.FromJSON(cluster.Spec)
+			}
+
+			oldMachines := view.SelectFromMachine(nil)
+			oldACL, _ := view.GetACL()
+
+			machineTxn(view, spec)
+			aclTxn(view, spec)
+
+			newACL, _ := view.GetACL()
+			plan.Boot, plan.Terminate, plan.Keep = planMachines(oldMachines,
+				view.SelectFromMachine(nil))
+			plan.ACL = planACL(oldACL, newACL)
+			plan.Containers = planContainers(oldSpec, spec)
+			return nil
+		})
+	return plan
+}
+
+func planMachines(oldMachines, newMachines []db.Machine) (boot, terminate,
+	keep []db.Machine) {
+
+	oldByID := map[int]db.Machine{}
+	for _, m := range oldMachines {
+		oldByID[m.ID] = m
+	}
+
+	newByID := map[int]db.Machine{}
+	for _, m := range newMachines {
+		newByID[m.ID] = m
+	}
+
+	for _, id := range machineIDs(newByID) {
+		if _, ok := oldByID[id]; ok {
+			keep = append(keep, newByID[id])
+		} else {
+			boot = append(boot, newByID[id])
+		}
+	}
+
+	for _, id := range machineIDs(oldByID) {
+		if _, ok := newByID[id]; !ok {
+			terminate = append(terminate, oldByID[id])
+		}
+	}
+	return boot, terminate, keep
+}
+
+func machineIDs(machines map[int]db.Machine) []int {
+	var ids []int
+	for id := range machines {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func planACL(oldACL, newACL db.ACL) ACLPlan {
+	var plan ACLPlan
+	plan.AddAdmin, plan.RemoveAdmin = diffStrings(oldACL.Admin, newACL.Admin)
+
+	oldPorts := map[db.PortRange]struct{}{}
+	for _, p := range oldACL.ApplicationPorts {
+		oldPorts[p] = struct{}{}
+	}
+
+	newPorts := map[db.PortRange]struct{}{}
+	for _, p := range newACL.ApplicationPorts {
+		newPorts[p] = struct{}{}
+		if _, ok := oldPorts[p]; !ok {
+			plan.AddPorts = append(plan.AddPorts, p)
+		}
+	}
+
+	for _, p := range oldACL.ApplicationPorts {
+		if _, ok := newPorts[p]; !ok {
+			plan.RemovePorts = append(plan.RemovePorts, p)
+		}
+	}
+	return plan
+}
+
+// diffStrings returns the strings in 'new' but not 'old', and those in 'old' but not
+// 'new'.
+func diffStrings(old, new []string) (added, removed []string) {
+	oldSet := map[string]struct{}{}
+	for _, s := range old {
+		oldSet[s] = struct{}{}
+	}
+
+	newSet := map[string]struct{}{}
+	for _, s := range new {
+		newSet[s] = struct{}{}
+		if _, ok := oldSet[s]; !ok {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range old {
+		if _, ok := newSet[s]; !ok {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func planContainers(oldSpec, newSpec stitch.Stitch) []LabelPlan {
+	oldLabels := labelContainers(oldSpec)
+	newLabels := labelContainers(newSpec)
+
+	labelSet := map[string]struct{}{}
+	for label := range oldLabels {
+		labelSet[label] = struct{}{}
+	}
+	for label := range newLabels {
+		labelSet[label] = struct{}{}
+	}
+
+	var labels []string
+	for label := range labelSet {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var plans []LabelPlan
+	for _, label := range labels {
+		plan := LabelPlan{
+			Label:  label,
+			Add:    subtractContainers(newLabels[label], oldLabels[label]),
+			Remove: subtractContainers(oldLabels[label], newLabels[label]),
+		}
+
+		if len(plan.Add) > 0 || len(plan.Remove) > 0 {
+			plans = append(plans, plan)
+		}
+	}
+	return plans
+}
+
+// labelContainers maps the name of each label in 'spec' to its containers, keyed by
+// stitch ID.  Containers are identified by their stitch ID, just as when the minion
+// joins the stitch against its running containers.
+func labelContainers(spec stitch.Stitch) map[string]map[string]db.Container {
+	containers := map[string]db.Container{}
+	for _, c := range spec.Containers {
+		containers[c.ID] = db.Container{
+			StitchID: c.ID,
+			Image:    c.Image,
+			Command:  c.Command,
+			Env:      c.Env,
+		}
+	}
+
+	labeled := map[string]struct{}{}
+	result := map[string]map[string]db.Container{}
+	addContainer := func(label, id string) {
+		if result[label] == nil {
+			result[label] = map[string]db.Container{}
+		}
+		result[label][id] = containers[id]
+	}
+
+	for _, label := range spec.Labels {
+		for _, id := range label.IDs {
+			if _, ok := containers[id]; ok {
+				labeled[id] = struct{}{}
+				addContainer(label.Name, id)
+			}
+		}
+	}
+
+	for id := range containers {
+		if _, ok := labeled[id]; !ok {
+			addContainer("", id)
+		}
+	}
+	return result
+}
+
+// subtractContainers returns the containers in 'a' but not 'b', sorted by stitch ID.
+func subtractContainers(a, b map[string]db.Container) []db.Container {
+	var ids []string
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var result []db.Container
+	for _, id := range ids {
+		result = append(result, a[id])
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDeployment(t *testing.T) {
+	myIP = func() (string, error) {
+		return "5.6.7.8", nil
+	}
+
+	pre := `var deployment = createDeployment({adminACL: ["1.2.3.4/32"]});
+	var baseMachine = new Machine({provider: "Amazon", size: "m4.large"});
+	deployment.deploy(baseMachine.asMaster());`
+	conn := db.New()
+
+	code := pre + `deployment.deploy(baseMachine.asWorker().replicate(2));
+		var red = new Service("red", [new Container("alpine")]);
+		deployment.deploy(red);`
+	spec := prog(t, code)
+
+	plan := PlanDeployment(conn, spec)
+	assert.Len(t, plan.Boot, 3)
+	assert.Empty(t, plan.Terminate)
+	assert.Empty(t, plan.Keep)
+	assert.Equal(t, []string{"1.2.3.4/32"}, plan.ACL.AddAdmin)
+	assert.Len(t, plan.Containers, 1)
+	assert.Equal(t, "red", plan.Containers[0].Label)
+	assert.Len(t, plan.Containers[0].Add, 1)
+	assert.Equal(t, "alpine", plan.Containers[0].Add[0].Image)
+
+	// Planning must leave the database untouched.
+	masters, workers := selectMachines(conn)
+	assert.Empty(t, masters)
+	assert.Empty(t, workers)
+
+	updateStitch(t, conn, spec)
+	assert.True(t, PlanDeployment(conn, spec).Empty())
+
+	code = pre + `deployment.deploy(baseMachine.asWorker());
+		var blue = new Service("blue", [new Container("nginx")]);
+		deployment.deploy(blue);
+		publicInternet.connect(80, blue);`
+
+	plan = PlanDeployment(conn, prog(t, code))
+	assert.Empty(t, plan.Boot)
+	assert.Len(t, plan.Terminate, 1)
+	assert.Equal(t, db.Role(db.Worker), plan.Terminate[0].Role)
+	assert.Len(t, plan.Keep, 2)
+	assert.Equal(t, []db.PortRange{{MinPort: 80, MaxPort: 80}}, plan.ACL.AddPorts)
+	assert.Len(t, plan.Containers, 2)
+	assert.Equal(t, "blue", plan.Containers[0].Label)
+	assert.Len(t, plan.Containers[0].Add, 1)
+	assert.Equal(t, "red", plan.Containers[1].Label)
+	assert.Len(t, plan.Containers[1].Remove, 1)
+
+	masters, workers = selectMachines(conn)
+	assert.Len(t, masters, 1)
+	assert.Len(t, workers, 2)
+}
@@ -12,6 +12,9 @@ import (
 	dkc "github.com/fsouza/go-dockerclient"
 )
 
+// The period, in microseconds, over which a container's CPU usage is limited.
+const cpuPeriod = 100000
+
 var pullCacheTimeout = time.Minute
 var networkTimeout = time.Minute
 
@@ -33,7 +36,13 @@ type Container struct {
 	Pid     int
 	Env     map[string]string
 	Labels  map[string]string
+	Binds   []string
+	CPU     float64
+	Memory  int64
 	Created time.Time
+
+	// The exit status of the container, if it has exited.
+	ExitCode int
 }
 
 // ContainerSlice is an alias for []Container to allow for joins
@@ -67,6 +76,16 @@ type RunOptions struct {
 	PidMode     string
 	Privileged  bool
 	VolumesFrom []string
+
+	// Volumes to mount, in Docker's "<source>:<target>[:ro]" format.  A source
+	// that's an absolute path is a directory of the host, otherwise it names a
+	// Docker volume.
+	Binds []string
+
+	// The number of cores, and bytes of memory, the container may use.  Zero
+	// means unlimited.
+	CPU    float64
+	Memory int64
 }
 
 type client interface {
@@ -79,6 +98,9 @@ type client interface {
 	InspectContainer(id string) (*dkc.Container, error)
 	CreateContainer(dkc.CreateContainerOptions) (*dkc.Container, error)
 	CreateNetwork(dkc.CreateNetworkOptions) (*dkc.Network, error)
+	CreateExec(dkc.CreateExecOptions) (*dkc.Exec, error)
+	StartExec(id string, opts dkc.StartExecOptions) error
+	InspectExec(id string) (*dkc.ExecInspect, error)
 }
 
 // New creates client to the docker daemon.
@@ -110,8 +132,15 @@ func (dk Client) Run(opts RunOptions) (string, error) {
 		PidMode:     opts.PidMode,
 		Privileged:  opts.Privileged,
 		VolumesFrom: opts.VolumesFrom,
+		Binds:       opts.Binds,
 		DNS:         opts.DNS,
 		DNSSearch:   opts.DNSSearch,
+		Memory:      opts.Memory,
+	}
+
+	if opts.CPU > 0 {
+		hc.CPUPeriod = cpuPeriod
+		hc.CPUQuota = int64(opts.CPU*cpuPeriod + 0.5)
 	}
 
 	var nc *dkc.NetworkingConfig
@@ -176,6 +205,25 @@ func (dk Client) RemoveID(id string) error {
 	return nil
 }
 
+// Exec runs 'cmd' inside the container with the given ID, and returns its exit code
+// once it completes.
+func (dk Client) Exec(id string, cmd []string) (int, error) {
+	exec, err := dk.CreateExec(dkc.CreateExecOptions{Container: id, Cmd: cmd})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := dk.StartExec(exec.ID, dkc.StartExecOptions{}); err != nil {
+		return 0, err
+	}
+
+	inspect, err := dk.InspectExec(exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
 // Pull retrieves the given docker image from an image cache.
 // The `image` argument can be of the form <repo>, <repo>:<tag>, or
 // <repo>:<tag>@<digestFormat>:<digest>.
@@ -228,6 +276,11 @@ func (dk Client) List(filters map[string][]string) ([]Container, error) {
 	return dk.list(filters, false)
 }
 
+// ListAll is like List, but also returns containers that have exited.
+func (dk Client) ListAll(filters map[string][]string) ([]Container, error) {
+	return dk.list(filters, true)
+}
+
 func (dk Client) list(filters map[string][]string, all bool) ([]Container, error) {
 	opts := dkc.ListContainersOptions{All: all, Filters: filters}
 	apics, err := dk.ListContainers(opts)
@@ -279,6 +332,16 @@ func (dk Client) Get(id string) (Container, error) {
 		Labels:  dkc.Config.Labels,
 		Status:  dkc.State.Status,
 		Created: dkc.Created,
+
+		ExitCode: dkc.State.ExitCode,
+	}
+
+	if hc := dkc.HostConfig; hc != nil {
+		c.Binds = hc.Binds
+		c.Memory = hc.Memory
+		if hc.CPUPeriod > 0 {
+			c.CPU = float64(hc.CPUQuota) / float64(hc.CPUPeriod)
+		}
 	}
 
 	networks := keys(dkc.NetworkSettings.Networks)
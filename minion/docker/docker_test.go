@@ -182,7 +182,7 @@ func TestRun(t *testing.T) {
 	assert.Equal(t, 1, len(containers))
 	assert.Equal(t, id1, containers[0].ID)
 
-	containers, err = dk.list(nil, true)
+	containers, err = dk.ListAll(nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(containers))
 	assert.True(t, containers[0].ID == id2 || containers[1].ID == id2)
@@ -279,3 +279,29 @@ func cacheKeys(cache map[string]*cacheEntry) map[string]struct{} {
 	}
 	return res
 }
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "foo", Image: "image"})
+	assert.NoError(t, err)
+
+	code, err := dk.Exec(id, []string{"true"})
+	assert.NoError(t, err)
+	assert.Zero(t, code)
+	assert.Equal(t, []string{"true"}, md.Executions[id])
+
+	md.ExitCodes[id] = 1
+	code, err = dk.Exec(id, []string{"false"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code)
+
+	md.StartExecError = true
+	_, err = dk.Exec(id, []string{"true"})
+	assert.Error(t, err)
+	md.StartExecError = false
+
+	_, err = dk.Exec("missing", []string{"true"})
+	assert.Error(t, err)
+}
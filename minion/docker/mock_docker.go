@@ -25,6 +25,9 @@ type MockClient struct {
 	createdExecs map[string]dkc.CreateExecOptions
 	Executions   map[string][]string
 
+	// The exit code of executions in each container, by container ID.
+	ExitCodes map[string]int
+
 	CreateError     bool
 	NetworkError    bool
 	CreateExecError bool
@@ -46,6 +49,7 @@ func NewMock() (*MockClient, Client) {
 		Networks:     map[string]*dkc.Network{},
 		createdExecs: map[string]dkc.CreateExecOptions{},
 		Executions:   map[string][]string{},
+		ExitCodes:    map[string]int{},
 	}
 	return md, Client{md, &sync.Mutex{}, map[string]*cacheEntry{}}
 }
@@ -72,6 +76,7 @@ func (dk MockClient) StopContainer(id string) {
 	defer dk.Unlock()
 	container := dk.Containers[id]
 	container.Running = false
+	container.State.Status = "exited"
 	dk.Containers[id] = container
 }
 
@@ -191,13 +196,24 @@ func (dk *MockClient) CreateContainer(opts dkc.CreateContainerOptions) (*dkc.Con
 
 	id := uuid.NewV4().String()
 
+	networks := map[string]dkc.ContainerNetwork{}
+	if nc := opts.NetworkingConfig; nc != nil {
+		for name, ep := range nc.EndpointsConfig {
+			var ip string
+			if ep.IPAMConfig != nil {
+				ip = ep.IPAMConfig.IPv4Address
+			}
+			networks[name] = dkc.ContainerNetwork{IPAddress: ip}
+		}
+	}
+
 	container := &dkc.Container{
 		ID:              id,
 		Name:            opts.Name,
 		Args:            opts.Config.Cmd,
 		Config:          opts.Config,
 		HostConfig:      opts.HostConfig,
-		NetworkSettings: &dkc.NetworkSettings{},
+		NetworkSettings: &dkc.NetworkSettings{Networks: networks},
 	}
 	dk.Containers[id] = mockContainer{container, false}
 	return container, nil
@@ -236,6 +252,18 @@ func (dk MockClient) StartExec(id string, opts dkc.StartExecOptions) error {
 	return nil
 }
 
+// InspectExec returns the exit code of the supplied execution object.
+func (dk MockClient) InspectExec(id string) (*dkc.ExecInspect, error) {
+	dk.Lock()
+	defer dk.Unlock()
+
+	exec, ok := dk.createdExecs[id]
+	if !ok {
+		return nil, errors.New("unknown exec")
+	}
+	return &dkc.ExecInspect{ID: id, ExitCode: dk.ExitCodes[exec.Container]}, nil
+}
+
 // ResetExec clears the list of created and started executions, for use by the unit
 // tests.
 func (dk *MockClient) ResetExec() {
@@ -23,17 +23,7 @@ func updatePolicy(view db.Database, spec string) {
 }
 
 func updatePlacements(view db.Database, spec stitch.Stitch) {
-	var placements db.PlacementSlice
-	for _, sp := range spec.Placements {
-		placements = append(placements, db.Placement{
-			TargetLabel: sp.TargetLabel,
-			Exclusive:   sp.Exclusive,
-			OtherLabel:  sp.OtherLabel,
-			Provider:    sp.Provider,
-			Size:        sp.Size,
-			Region:      sp.Region,
-		})
-	}
+	placements := queryPlacements(spec)
 
 	key := func(val interface{}) interface{} {
 		p := val.(db.Placement)
@@ -57,6 +47,24 @@ func updatePlacements(view db.Database, spec stitch.Stitch) {
 	}
 }
 
+func queryPlacements(spec stitch.Stitch) db.PlacementSlice {
+	var placements db.PlacementSlice
+	for _, sp := range spec.Placements {
+		placements = append(placements, db.Placement{
+			TargetLabel: sp.TargetLabel,
+			Exclusive:   sp.Exclusive,
+			OtherLabel:  sp.OtherLabel,
+			Provider:    sp.Provider,
+			Size:        sp.Size,
+			Region:      sp.Region,
+
+			SpreadAcross: sp.SpreadAcross,
+			MaxSkew:      sp.MaxSkew,
+		})
+	}
+	return placements
+}
+
 func updateConnections(view db.Database, spec stitch.Stitch) {
 	scs, vcs := stitch.ConnectionSlice(spec.Connections),
 		view.SelectFromConnection(nil)
@@ -64,10 +72,13 @@ func updateConnections(view db.Database, spec stitch.Stitch) {
 	dbcKey := func(val interface{}) interface{} {
 		c := val.(db.Connection)
 		return stitch.Connection{
-			From:    c.From,
-			To:      c.To,
-			MinPort: c.MinPort,
-			MaxPort: c.MaxPort,
+			From:          c.From,
+			To:            c.To,
+			MinPort:       c.MinPort,
+			MaxPort:       c.MaxPort,
+			Protocol:      c.Protocol,
+			ContainerPort: c.ContainerPort,
+			Ingress:       c.Ingress,
 		}
 	}
 
@@ -89,6 +100,9 @@ func updateConnections(view db.Database, spec stitch.Stitch) {
 		dbc.To = stitchc.To
 		dbc.MinPort = stitchc.MinPort
 		dbc.MaxPort = stitchc.MaxPort
+		dbc.Protocol = stitchc.Protocol
+		dbc.ContainerPort = stitchc.ContainerPort
+		dbc.Ingress = stitchc.Ingress
 		view.Commit(dbc)
 	}
 }
@@ -101,12 +115,45 @@ func queryContainers(spec stitch.Stitch) []db.Container {
 			Command:  c.Command,
 			Image:    c.Image,
 			Env:      c.Env,
+			CPU:      c.CPU,
+			RAM:      c.RAM,
+
+			RestartPolicy: c.RestartPolicy,
+		}
+
+		if hc := c.HealthCheck; hc != nil {
+			containers[c.ID].HealthCheck = &db.HealthCheck{
+				Kind:               hc.Kind,
+				Command:            hc.Command,
+				Port:               hc.Port,
+				Path:               hc.Path,
+				Interval:           hc.Interval,
+				Timeout:            hc.Timeout,
+				HealthyThreshold:   hc.HealthyThreshold,
+				UnhealthyThreshold: hc.UnhealthyThreshold,
+			}
+		}
+
+		for _, v := range c.Volumes {
+			containers[c.ID].Volumes = append(containers[c.ID].Volumes,
+				db.Volume{
+					Kind:     v.Kind,
+					Source:   v.Source,
+					Target:   v.Target,
+					ReadOnly: v.ReadOnly,
+				})
 		}
 	}
 
+	// Containers take the highest priority of their labels.
 	for _, label := range spec.Labels {
 		for _, id := range label.IDs {
-			containers[id].Labels = append(containers[id].Labels, label.Name)
+			dbc := containers[id]
+			dbc.Labels = append(dbc.Labels, label.Name)
+			if stitch.PriorityLevel(label.Priority) >
+				stitch.PriorityLevel(dbc.Priority) {
+				dbc.Priority = label.Priority
+			}
 		}
 	}
 
@@ -126,12 +173,19 @@ func updateContainers(view db.Database, spec stitch.Stitch) {
 	pairs, news, dbcs := join.HashJoin(db.ContainerSlice(queryContainers(spec)),
 		db.ContainerSlice(view.SelectFromContainer(nil)), key, key)
 
+	var removed []db.Container
 	for _, dbc := range dbcs {
+		removed = append(removed, dbc.(db.Container))
 		view.Remove(dbc.(db.Container))
 	}
 
 	for _, new := range news {
-		pairs = append(pairs, join.Pair{L: new, R: view.InsertContainer()})
+		// A replacement for a container with volumes must run on the same
+		// minion, as that's where the volumes' data lives.  The scheduler
+		// places it there if the minion can still accept it.
+		dbc := view.InsertContainer()
+		dbc.VolumeMinion = volumeHost(new.(db.Container), removed)
+		pairs = append(pairs, join.Pair{L: new, R: dbc})
 	}
 
 	for _, pair := range pairs {
@@ -146,7 +200,30 @@ func updateContainers(view db.Database, spec stitch.Stitch) {
 		dbc.Command = newc.Command
 		dbc.Image = newc.Image
 		dbc.Env = newc.Env
+		dbc.Volumes = newc.Volumes
+		dbc.CPU = newc.CPU
+		dbc.RAM = newc.RAM
+		dbc.HealthCheck = newc.HealthCheck
+		dbc.RestartPolicy = newc.RestartPolicy
+		dbc.Priority = newc.Priority
 		dbc.StitchID = newc.StitchID
 		view.Commit(dbc)
 	}
 }
+
+// volumeHost returns the minion holding the data of any of 'others' volumes that
+// 'dbc' mounts, or the empty string if there is none.
+func volumeHost(dbc db.Container, others []db.Container) string {
+	for _, other := range others {
+		if !db.SharesVolume(dbc, other) {
+			continue
+		}
+
+		if other.Minion != "" {
+			return other.Minion
+		} else if other.VolumeMinion != "" {
+			return other.VolumeMinion
+		}
+	}
+	return ""
+}
@@ -83,6 +83,41 @@ func TestContainerTxn(t *testing.T) {
 	assert.False(t, fired(trigg))
 }
 
+func TestContainerVolumes(t *testing.T) {
+	conn := db.New()
+
+	spec := `var data = new Volume({name: "data"});
+	var c = new Container("mysql").mount(data, "/var/lib/mysql");
+	deployment.deploy(new Service("db", [c]));`
+	testContainerTxn(t, conn, spec)
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbcs := view.SelectFromContainer(nil)
+		assert.Len(t, dbcs, 1)
+		assert.Equal(t, []db.Volume{{
+			Kind:   stitch.NamedVolume,
+			Source: "data",
+			Target: "/var/lib/mysql",
+		}}, dbcs[0].Volumes)
+
+		dbcs[0].Minion = "1.2.3.4"
+		view.Commit(dbcs[0])
+		return nil
+	})
+
+	// A new version of the container stays with its data.
+	spec = `var data = new Volume({name: "data"});
+	var c = new Container("mysql:5.7").mount(data, "/var/lib/mysql");
+	deployment.deploy(new Service("db", [c]));`
+	testContainerTxn(t, conn, spec)
+
+	dbcs := conn.SelectFromContainer(nil)
+	assert.Len(t, dbcs, 1)
+	assert.Equal(t, "mysql:5.7", dbcs[0].Image)
+	assert.Empty(t, dbcs[0].Minion)
+	assert.Equal(t, "1.2.3.4", dbcs[0].VolumeMinion)
+}
+
 func testContainerTxn(t *testing.T, conn db.Conn, spec string) {
 	compiled, err := stitch.FromJavascript(spec, stitch.DefaultImportGetter)
 	assert.Nil(t, err)
@@ -274,6 +309,16 @@ func TestPlacementTxn(t *testing.T) {
 		},
 	)
 
+	// Spread placement
+	spec = pre + `foo.spreadAcross("region");`
+	checkPlacement(spec,
+		db.Placement{
+			TargetLabel:  "foo",
+			SpreadAcross: "region",
+			MaxSkew:      1,
+		},
+	)
+
 	// Port placement
 	spec = pre + `publicInternet.connect(80, foo);
 	publicInternet.connect(81, foo);`
@@ -336,3 +381,27 @@ func TestPlacementTxn(t *testing.T) {
 		},
 	)
 }
+
+func TestContainerPriority(t *testing.T) {
+	conn := db.New()
+
+	spec := `var batch = new Service("batch", [new Container("a")]);
+	batch.setPriority("low");
+	var web = new Service("web", [new Container("b")]);
+	web.setPriority("critical");
+	deployment.deploy([batch, web, new Service("all", batch.containers.concat(
+		web.containers))]);`
+	testContainerTxn(t, conn, spec)
+
+	// Containers take the highest priority of their services.
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		switch dbc.Image {
+		case "a":
+			assert.Empty(t, dbc.Priority)
+		case "b":
+			assert.Equal(t, stitch.PriorityCritical, dbc.Priority)
+		default:
+			t.Errorf("unexpected container: %v", dbc)
+		}
+	}
+}
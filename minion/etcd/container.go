@@ -65,7 +65,8 @@ func updateNonLeader(conn db.Conn, etcdStr string) {
 		}
 	}
 
-	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+	txn := conn.Txn(db.ContainerTable).WithReason("etcd: sync containers")
+	txn.Run(func(view db.Database) error {
 		joinContainers(view, etcdDBCs)
 		return nil
 	})
@@ -90,12 +91,18 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 			Image    string
 			Command  string
 			Env      string
+			Volumes  string
+			CPU      float64
+			RAM      float64
 		}{
 			IP:       dbc.IP,
 			StitchID: dbc.StitchID,
 			Image:    dbc.Image,
 			Command:  fmt.Sprintf("%v", dbc.Command),
 			Env:      fmt.Sprintf("%v", env),
+			Volumes:  fmt.Sprintf("%v", dbc.Volumes),
+			CPU:      dbc.CPU,
+			RAM:      dbc.RAM,
 		}
 	}
 
@@ -123,6 +130,13 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 		dbc.Command = edbc.Command
 		dbc.Labels = edbc.Labels
 		dbc.Env = edbc.Env
+		dbc.Volumes = edbc.Volumes
+		dbc.CPU = edbc.CPU
+		dbc.RAM = edbc.RAM
+		dbc.HealthCheck = edbc.HealthCheck
+		dbc.RestartPolicy = edbc.RestartPolicy
+		dbc.Priority = edbc.Priority
+		dbc.Evicted = edbc.Evicted
 		view.Commit(dbc)
 	}
 }
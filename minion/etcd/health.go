@@ -0,0 +1,81 @@
+package etcd
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/NetSys/quilt/db"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Each worker publishes the health of its containers, keyed by container IP, under
+// its private IP in the health directory.  The leader reads them to decide which
+// containers to remove from DNS.
+const healthPath = "/health"
+
+func runHealth(conn db.Conn, store Store) {
+	for range conn.TriggerTick(minionTimeout/2, db.ContainerTable).C {
+		writeHealth(conn, store)
+		if conn.EtcdLeader() {
+			readHealth(conn, store)
+		}
+	}
+}
+
+func writeHealth(conn db.Conn, store Store) {
+	self, err := conn.MinionSelf()
+	if err != nil || self.Role != db.Worker || self.PrivateIP == "" {
+		return
+	}
+
+	health := map[string]string{}
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		if dbc.IP != "" && dbc.Health != "" {
+			health[dbc.IP] = dbc.Health
+		}
+	}
+
+	js, err := jsonMarshal(health)
+	if err != nil {
+		panic("Failed to convert container health to JSON")
+	}
+
+	key := path.Join(healthPath, self.PrivateIP)
+	if err := store.Set(key, string(js), minionTimeout*time.Second); err != nil {
+		log.WithError(err).Warningf("Failed to write: %s", key)
+	}
+}
+
+func readHealth(conn db.Conn, store Store) {
+	tree, err := store.GetTree(healthPath)
+	if err != nil {
+		log.WithError(err).Warning("Failed to get container health from Etcd.")
+		return
+	}
+
+	health := map[string]string{}
+	for _, t := range tree.Children {
+		var minionHealth map[string]string
+		if err := json.Unmarshal([]byte(t.Value), &minionHealth); err != nil {
+			log.WithField("json", t.Value).Warning("Failed to parse health.")
+			continue
+		}
+
+		for ip, h := range minionHealth {
+			health[ip] = h
+		}
+	}
+
+	txn := conn.Txn(db.ContainerTable).WithReason("etcd: sync container health")
+	txn.Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			if dbc.Health != health[dbc.IP] {
+				dbc.Health = health[dbc.IP]
+				view.Commit(dbc)
+			}
+		}
+		return nil
+	})
+}
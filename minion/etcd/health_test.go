@@ -0,0 +1,62 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	store := NewMock()
+	store.Mkdir(healthPath, 0)
+
+	worker := db.New()
+	worker.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		m.Role = db.Worker
+		m.PrivateIP = "1.2.3.4"
+		view.Commit(m)
+
+		dbc := view.InsertContainer()
+		dbc.IP = "10.0.0.2"
+		dbc.Health = db.Unhealthy
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.IP = "10.0.0.3"
+		view.Commit(dbc)
+		return nil
+	})
+	writeHealth(worker, store)
+
+	val, err := store.Get(healthPath + "/1.2.3.4")
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+    "10.0.0.2": "unhealthy"
+}`, val)
+
+	leader := db.New()
+	leader.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, ip := range []string{"10.0.0.2", "10.0.0.3"} {
+			dbc := view.InsertContainer()
+			dbc.IP = ip
+			dbc.Health = db.Healthy
+			view.Commit(dbc)
+		}
+		return nil
+	})
+	readHealth(leader, store)
+
+	for _, dbc := range leader.SelectFromContainer(nil) {
+		switch dbc.IP {
+		case "10.0.0.2":
+			assert.Equal(t, db.Unhealthy, dbc.Health)
+		case "10.0.0.3":
+			assert.Empty(t, dbc.Health)
+		}
+	}
+}
@@ -54,13 +54,17 @@ func joinLabels(view db.Database, etcdLabels []db.Label) {
 	key := func(iface interface{}) interface{} {
 		label := iface.(db.Label)
 		return struct {
-			Label        string
-			IP           string
-			ContainerIPs string
+			Label          string
+			IP             string
+			ContainerIPs   string
+			UnhealthyIPs   string
+			HealthyMinions string
 		}{
-			Label:        label.Label,
-			IP:           label.IP,
-			ContainerIPs: fmt.Sprintf("%v", label.ContainerIPs),
+			Label:          label.Label,
+			IP:             label.IP,
+			ContainerIPs:   fmt.Sprintf("%v", label.ContainerIPs),
+			UnhealthyIPs:   fmt.Sprintf("%v", label.UnhealthyIPs),
+			HealthyMinions: fmt.Sprintf("%v", label.HealthyMinions),
 		}
 	}
 
@@ -0,0 +1,173 @@
+// Package health runs the health checks of the containers scheduled on a worker, and
+// records their results in the Health field of each container in the database.  The
+// scheduler restarts containers that become unhealthy, and the network removes them
+// from DNS.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/minion/docker"
+	"github.com/NetSys/quilt/stitch"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The progress of a container's health check, tracked by docker ID so that restarted
+// containers are checked afresh.
+type status struct {
+	running   bool
+	next      time.Time
+	successes int
+	failures  int
+	health    string
+}
+
+type checker struct {
+	sync.Mutex
+	dk     docker.Client
+	status map[string]*status
+}
+
+// Run blocks checking the health of the containers running on this minion.
+func Run(conn db.Conn, dk docker.Client) {
+	c := &checker{dk: dk, status: map[string]*status{}}
+	for range time.Tick(time.Second) {
+		self, err := conn.MinionSelf()
+		if err != nil || self.Role != db.Worker {
+			continue
+		}
+		c.runOnce(conn, time.Now())
+	}
+}
+
+// runOnce starts the checks that are due, and records the health of each container
+// in the database.
+func (c *checker) runOnce(conn db.Conn, now time.Time) {
+	txn := conn.Txn(db.ContainerTable).WithReason("health: update container health")
+	txn.Run(func(view db.Database) error {
+		dbcs := view.SelectFromContainer(func(dbc db.Container) bool {
+			return dbc.DockerID != "" && dbc.HealthCheck != nil
+		})
+
+		c.Lock()
+		defer c.Unlock()
+
+		checked := map[string]struct{}{}
+		for _, dbc := range dbcs {
+			checked[dbc.DockerID] = struct{}{}
+			st := c.status[dbc.DockerID]
+			if st == nil {
+				st = &status{}
+				c.status[dbc.DockerID] = st
+			}
+
+			interval := time.Duration(dbc.HealthCheck.Interval) * time.Second
+			if !st.running && !now.Before(st.next) {
+				st.running = true
+				st.next = now.Add(interval)
+				go c.check(dbc, st)
+			}
+
+			if dbc.Health != st.health {
+				dbc.Health = st.health
+				view.Commit(dbc)
+			}
+		}
+
+		for id := range c.status {
+			if _, ok := checked[id]; !ok {
+				delete(c.status, id)
+			}
+		}
+		return nil
+	})
+}
+
+// check probes 'dbc' once, and updates 'st' with the result.
+func (c *checker) check(dbc db.Container, st *status) {
+	err := probe(c.dk, dbc)
+
+	c.Lock()
+	defer c.Unlock()
+
+	hc := dbc.HealthCheck
+	st.running = false
+	if err == nil {
+		st.failures = 0
+		st.successes++
+		if st.successes >= hc.HealthyThreshold {
+			st.health = db.Healthy
+		}
+		return
+	}
+
+	st.successes = 0
+	st.failures++
+	if st.failures >= hc.UnhealthyThreshold && st.health != db.Unhealthy {
+		log.WithError(err).WithField("container", dbc).Warn(
+			"Container is unhealthy.")
+		st.health = db.Unhealthy
+	}
+}
+
+// probe runs the health check of 'dbc', and returns an error if it fails or takes
+// longer than the check's timeout.
+var probe = func(dk docker.Client, dbc db.Container) error {
+	hc := dbc.HealthCheck
+	timeout := time.Duration(hc.Timeout) * time.Second
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- runProbe(dk, dbc, timeout)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-time.After(timeout):
+		return errors.New("health check timed out")
+	}
+}
+
+func runProbe(dk docker.Client, dbc db.Container, timeout time.Duration) error {
+	hc := dbc.HealthCheck
+	addr := net.JoinHostPort(dbc.IP, strconv.Itoa(hc.Port))
+
+	switch hc.Kind {
+	case stitch.CommandCheck:
+		code, err := dk.Exec(dbc.DockerID, hc.Command)
+		if err == nil && code != 0 {
+			err = fmt.Errorf("health check exited with status %d", code)
+		}
+		return err
+	case stitch.HTTPCheck:
+		client := http.Client{Timeout: timeout}
+		resp, err := client.Get("http://" + addr + hc.Path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("health check received status %d",
+				resp.StatusCode)
+		}
+		return nil
+	case stitch.TCPCheck:
+		tcpConn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return err
+		}
+		return tcpConn.Close()
+	default:
+		return fmt.Errorf("unknown health check kind: %s", hc.Kind)
+	}
+}
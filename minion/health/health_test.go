@@ -0,0 +1,134 @@
+package health
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/minion/docker"
+	"github.com/NetSys/quilt/stitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	var probeErr error
+	probe = func(dk docker.Client, dbc db.Container) error {
+		return probeErr
+	}
+
+	_, dk := docker.NewMock()
+	c := &checker{dk: dk, status: map[string]*status{}}
+	dbc := db.Container{DockerID: "a", HealthCheck: &db.HealthCheck{
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 2,
+	}}
+
+	st := &status{running: true}
+	c.check(dbc, st)
+	assert.False(t, st.running)
+	assert.Empty(t, st.health)
+
+	c.check(dbc, st)
+	assert.Equal(t, db.Healthy, st.health)
+
+	probeErr = errors.New("probe failed")
+	c.check(dbc, st)
+	assert.Equal(t, db.Healthy, st.health)
+
+	c.check(dbc, st)
+	assert.Equal(t, db.Unhealthy, st.health)
+
+	// A single success isn't enough to be healthy again.
+	probeErr = nil
+	c.check(dbc, st)
+	assert.Equal(t, db.Unhealthy, st.health)
+
+	c.check(dbc, st)
+	assert.Equal(t, db.Healthy, st.health)
+}
+
+func TestRunOnce(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.DockerID = "a"
+		dbc.HealthCheck = &db.HealthCheck{Kind: stitch.TCPCheck}
+		view.Commit(dbc)
+
+		dbc = view.InsertContainer()
+		dbc.DockerID = "b"
+		view.Commit(dbc)
+		return nil
+	})
+
+	// Checks that are already running aren't started again.
+	_, dk := docker.NewMock()
+	c := &checker{dk: dk, status: map[string]*status{
+		"a":    {running: true, health: db.Unhealthy},
+		"gone": {health: db.Healthy},
+	}}
+	c.runOnce(conn, time.Now())
+
+	for _, dbc := range conn.SelectFromContainer(nil) {
+		switch dbc.DockerID {
+		case "a":
+			assert.Equal(t, db.Unhealthy, dbc.Health)
+		case "b":
+			assert.Empty(t, dbc.Health)
+		}
+	}
+
+	assert.Len(t, c.status, 1)
+	assert.Contains(t, c.status, "a")
+}
+
+func TestRunProbe(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	id, err := dk.Run(docker.RunOptions{Name: "foo", Image: "image"})
+	assert.NoError(t, err)
+
+	dbc := db.Container{DockerID: id, HealthCheck: &db.HealthCheck{
+		Kind:    stitch.CommandCheck,
+		Command: []string{"check"},
+	}}
+	assert.NoError(t, runProbe(dk, dbc, time.Second))
+
+	md.ExitCodes[id] = 1
+	assert.EqualError(t, runProbe(dk, dbc, time.Second),
+		"health check exited with status 1")
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/health", r.URL.Path)
+			w.WriteHeader(status)
+		}))
+	defer server.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	dbc = db.Container{IP: host, HealthCheck: &db.HealthCheck{
+		Kind: stitch.HTTPCheck,
+		Port: port,
+		Path: "/health",
+	}}
+	assert.NoError(t, runProbe(dk, dbc, time.Second))
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, runProbe(dk, dbc, time.Second),
+		"health check received status 503")
+
+	dbc.HealthCheck = &db.HealthCheck{Kind: stitch.TCPCheck, Port: port}
+	assert.NoError(t, runProbe(dk, dbc, time.Second))
+
+	server.Close()
+	assert.Error(t, runProbe(dk, dbc, time.Second))
+}
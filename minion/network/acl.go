@@ -160,15 +160,25 @@ func matchString(c db.Connection) string {
 	return or(
 		and(
 			and(from(c.From), to(c.To)),
-			portConstraint(c.MinPort, c.MaxPort, "dst")),
+			portConstraint(c, "dst")),
 		and(
 			and(from(c.To), to(c.From)),
-			portConstraint(c.MinPort, c.MaxPort, "src")))
+			portConstraint(c, "src")))
 }
 
-func portConstraint(minPort, maxPort int, direction string) string {
-	return fmt.Sprintf("(icmp || %[1]d <= udp.%[2]s <= %[3]d || "+
-		"%[1]d <= tcp.%[2]s <= %[3]d)", minPort, direction, maxPort)
+// portConstraint matches the traffic allowed by 'c' to or from its ports, depending
+// on 'direction'.  Connections without a protocol allow ICMP, UDP and TCP.
+func portConstraint(c db.Connection, direction string) string {
+	match := []string{}
+	if c.Protocol == "" || c.Protocol == stitch.ICMP {
+		match = append(match, "icmp")
+	}
+
+	for _, protocol := range stitch.PortProtocols(c.Protocol) {
+		match = append(match, fmt.Sprintf("%d <= %s.%s <= %d", c.MinPort,
+			protocol, direction, c.MaxPort))
+	}
+	return or(match...)
 }
 
 func from(label string) string {
@@ -3,11 +3,13 @@ package network
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/ipdef"
+	"github.com/NetSys/quilt/stitch"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/miekg/dns"
@@ -15,16 +17,44 @@ import (
 
 const dnsTTL = 60 // Seconds
 
+// The most ports a SRV record set advertises, so that responses fit in a UDP packet.
+const maxSRVPorts = 16
+
 type dnsTable struct {
-	server dns.Server
+	// The servers listening for queries over UDP, and over TCP for responses too
+	// large to fit in a UDP packet.
+	servers []*dns.Server
+
+	// The addresses of the resolvers that queries outside of Quilt are forwarded
+	// to.  If empty, those in resolvConf are used.
+	upstreams []string
 
 	recordLock sync.Mutex
-	records    map[string]net.IP
+	records    dnsRecords
+}
+
+// dnsRecords are the records the DNS server is authoritative for: names within .q, and
+// the reverse names of Quilt IPs.
+type dnsRecords struct {
+	// The address of each label and container hostname.
+	a map[string]net.IP
+
+	// The ports of each service name, e.g. _tcp.label.q., whose target is the label.
+	srv map[string][]uint16
+
+	// The hostname of each IP's reverse name.
+	ptr map[string]string
+
+	// The names within .q that may have records once containers become healthy.
+	// Queries for other names without records get NXDOMAIN.
+	names map[string]struct{}
 }
 
 var table *dnsTable
 
-func runDNS(conn db.Conn) {
+var resolvConf = "/etc/resolv.conf"
+
+func runDNS(conn db.Conn, upstreams []string) {
 	self, err := conn.MinionSelf()
 	if err != nil {
 		log.WithError(err).Debug("Failed to get self")
@@ -36,10 +66,7 @@ func runDNS(conn db.Conn) {
 			return
 		}
 
-		err := table.server.Shutdown()
-		if err != nil {
-			log.WithError(err).Error("Failed to shut down DNS server")
-		}
+		shutdown(table.servers)
 		table = nil
 		return
 	}
@@ -48,32 +75,47 @@ func runDNS(conn db.Conn) {
 		return
 	}
 
-	table = updateTable(table, conn.SelectFromLabel(nil))
+	records := makeRecords(conn.SelectFromLabel(nil), conn.SelectFromConnection(nil))
+	table = updateTable(table, records, upstreams)
 }
 
-func updateTable(table *dnsTable, labels []db.Label) *dnsTable {
-	records := labelsToDNS(labels)
+func updateTable(table *dnsTable, records dnsRecords, upstreams []string) *dnsTable {
 	if table != nil {
 		table.recordLock.Lock()
 		table.records = records
 		table.recordLock.Unlock()
 		return table
 	}
-	table = makeTable(records)
+	table = makeTable(records, upstreams)
 
+	for i, server := range table.servers {
+		if err := start(server); err != nil {
+			log.WithError(err).Error("Failed to start DNS server")
+			shutdown(table.servers[:i])
+			return nil
+		}
+	}
+
+	log.Info("Started DNS Server")
+	return table
+}
+
+// start runs 'server' in the background, and returns once it's listening.
+func start(server *dns.Server) error {
 	// There could be multiple messages depending on how listenAndServe is
 	// implemented.  We don't want anyone to block, so we make a bit of a buffer.
 	errChan := make(chan error, 8)
-	table.server.NotifyStartedFunc = func() { errChan <- nil }
-	go func() { errChan <- listenAndServe(table) }()
+	server.NotifyStartedFunc = func() { errChan <- nil }
+	go func() { errChan <- listenAndServe(server) }()
+	return <-errChan
+}
 
-	if err := <-errChan; err != nil {
-		log.WithError(err).Error("Failed to start DNS server")
-		return nil
+func shutdown(servers []*dns.Server) {
+	for _, server := range servers {
+		if err := server.Shutdown(); err != nil {
+			log.WithError(err).Error("Failed to shut down DNS server")
+		}
 	}
-
-	log.Info("Started DNS Server")
-	return table
 }
 
 func (table *dnsTable) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
@@ -81,7 +123,12 @@ func (table *dnsTable) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 
 	log.Debug("DNS Request: ", req)
 
-	resp := table.genResponse(req)
+	network := "udp"
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		network = "tcp"
+	}
+
+	resp := table.genResponse(req, network)
 	if resp == nil {
 		return
 	}
@@ -92,92 +139,193 @@ func (table *dnsTable) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	}
 }
 
-func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
+// genResponse answers 'req', which the client sent over 'network'.  Names are case
+// insensitive, so they're looked up in lower case.
+func (table *dnsTable) genResponse(req *dns.Msg, network string) *dns.Msg {
 	resp := &dns.Msg{}
 	if len(req.Question) != 1 {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 	q := req.Question[0]
-	if q.Qclass != dns.ClassINET || q.Qtype != dns.TypeA {
+	if q.Qclass != dns.ClassINET {
 		return resp.SetRcode(req, dns.RcodeNotImplemented)
 	}
 
-	ips := table.lookupA(q.Name)
-	if len(ips) == 0 {
-		// Even though the client asked for a hostname within `.q` that we know
-		// nothing about, it's possible we'll learn about it in the future.  For
-		// now, we'll just not respond, the client will time out, and try again
-		// later.  Hopefully by then we have a response for them -- or if not,
-		// eventually they'll give up.
-		//
-		// XXX: The above logic is correct for things in the .q domain name, but
-		// we're also doing the same thing for failures to resolve external
-		// hosts.  This isn't entirely correct, it would be much better to return
-		// whatever upstream gave us in case of a failure.
-		return nil
+	name := strings.ToLower(q.Name)
+	ip := reverseIP(name)
+	if !strings.HasSuffix(name, ".q.") &&
+		(ip == nil || !ipdef.QuiltSubnet.Contains(ip)) {
+		return table.forward(req, network)
 	}
 
-	resp.SetReply(req)
-	for _, ip := range ips {
-		resp.Answer = append(resp.Answer, &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   q.Name,
-				Rrtype: dns.TypeA,
-				Class:  dns.ClassINET,
-				Ttl:    dnsTTL,
-			},
-			A: ip,
-		})
+	table.recordLock.Lock()
+	records := table.records
+	table.recordLock.Unlock()
+
+	answer, extra, exists := records.lookup(q)
+	if !exists {
+		if _, ok := records.names[name]; ok {
+			// Even though the client asked for a hostname within `.q` that we
+			// know nothing about, it's possible we'll learn about it in the
+			// future.  For now, we'll just not respond, the client will time
+			// out, and try again later.  Hopefully by then we have a response
+			// for them -- or if not, eventually they'll give up.
+			return nil
+		}
+		return resp.SetRcode(req, dns.RcodeNameError)
 	}
+
+	// Names that exist, but have no records of the requested type, get an empty
+	// answer.  In particular, Quilt addresses are IPv4 only, so AAAA queries are
+	// answered this way for clients to fall back to A.
+	resp.SetReply(req)
+	resp.Authoritative = true
+	resp.Answer = answer
+	resp.Extra = extra
 	return resp
 }
 
-func (table *dnsTable) lookupA(name string) []net.IP {
-	if strings.HasSuffix(name, ".q.") {
-		table.recordLock.Lock()
-		ip := table.records[name]
-		table.recordLock.Unlock()
-		if ip == nil {
-			return nil
+// lookup returns the records that answer 'q', along with any additional records
+// (the addresses of SRV targets), and whether the name exists at all.  The answers
+// keep the case of the question's name.
+func (records dnsRecords) lookup(q dns.Question) (answer, extra []dns.RR,
+	exists bool) {
+
+	name := strings.ToLower(q.Name)
+
+	hdr := func(name string, rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{
+			Name:   name,
+			Rrtype: rrtype,
+			Class:  dns.ClassINET,
+			Ttl:    dnsTTL,
 		}
-		return []net.IP{ip}
 	}
 
-	ipStrs, err := lookupHost(strings.TrimRight(name, "."))
-	if err != nil {
-		log.WithError(err).Debug("Failed to lookup external record: ", name)
-		return nil
+	if ip, ok := records.a[name]; ok {
+		exists = true
+		if q.Qtype == dns.TypeA || q.Qtype == dns.TypeANY {
+			answer = append(answer, &dns.A{
+				Hdr: hdr(q.Name, dns.TypeA),
+				A:   ip,
+			})
+		}
+	}
+
+	if ports, ok := records.srv[name]; ok {
+		exists = true
+		target := name[strings.Index(name, ".")+1:]
+		if q.Qtype == dns.TypeSRV || q.Qtype == dns.TypeANY {
+			for _, port := range ports {
+				answer = append(answer, &dns.SRV{
+					Hdr:    hdr(q.Name, dns.TypeSRV),
+					Weight: 1,
+					Port:   port,
+					Target: target,
+				})
+			}
+
+			if ip, ok := records.a[target]; ok {
+				extra = append(extra, &dns.A{
+					Hdr: hdr(target, dns.TypeA),
+					A:   ip,
+				})
+			}
+		}
+	}
+
+	if host, ok := records.ptr[name]; ok {
+		exists = true
+		if q.Qtype == dns.TypePTR || q.Qtype == dns.TypeANY {
+			answer = append(answer, &dns.PTR{
+				Hdr: hdr(q.Name, dns.TypePTR),
+				Ptr: host,
+			})
+		}
+	}
+	return answer, extra, exists
+}
+
+// forward sends 'req' to each upstream resolver in turn over 'network', and returns
+// the first response.  Responses truncated to fit in a UDP packet are passed on as
+// they are, so that the client retries over TCP.  If none of the resolvers respond,
+// the client is told that the server failed.
+func (table *dnsTable) forward(req *dns.Msg, network string) *dns.Msg {
+	upstreams := table.upstreams
+	if len(upstreams) == 0 {
+		conf, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			log.WithError(err).Debug("Failed to read upstream resolvers")
+		} else {
+			for _, server := range conf.Servers {
+				upstreams = append(upstreams,
+					net.JoinHostPort(server, conf.Port))
+			}
+		}
 	}
 
-	var ips []net.IP
-	for _, ipStr := range ipStrs {
-		if ip := net.ParseIP(ipStr); ip != nil && ip.To4() != nil {
-			ips = append(ips, ip)
+	for _, upstream := range upstreams {
+		resp, err := exchange(req, network, upstream)
+		if err == nil {
+			return resp
 		}
+		log.WithError(err).WithField("upstream", upstream).Debug(
+			"Failed to forward DNS query")
 	}
-	return ips
+	return (&dns.Msg{}).SetRcode(req, dns.RcodeServerFailure)
 }
 
-func makeTable(records map[string]net.IP) *dnsTable {
-	tbl := &dnsTable{
-		records: records,
-		server: dns.Server{
-			Addr: fmt.Sprintf("%s:53", ipdef.GatewayIP),
-			Net:  "udp",
-		},
+func makeTable(records dnsRecords, upstreams []string) *dnsTable {
+	tbl := &dnsTable{records: records, upstreams: upstreams}
+	for _, network := range []string{"udp", "tcp"} {
+		tbl.servers = append(tbl.servers, &dns.Server{
+			Addr:    fmt.Sprintf("%s:53", ipdef.GatewayIP),
+			Net:     network,
+			Handler: tbl,
+		})
 	}
-	tbl.server.Handler = tbl
 	return tbl
 }
 
+func makeRecords(labels []db.Label, connections []db.Connection) dnsRecords {
+	records := dnsRecords{
+		a:     labelsToDNS(labels),
+		srv:   connectionsToSRV(labels, connections),
+		names: map[string]struct{}{},
+	}
+	records.ptr = reverseRecords(records.a)
+
+	for _, label := range labels {
+		records.names[label.Label+".q."] = struct{}{}
+		for i := range label.ContainerIPs {
+			name := fmt.Sprintf("%d.%s.q.", i+1, label.Label)
+			records.names[name] = struct{}{}
+		}
+	}
+	return records
+}
+
+// labelsToDNS maps the hostname of each label, and of each container within it, to
+// its IP address.  Containers that are failing their health checks are left out, as
+// are labels with no healthy containers to balance across.
 func labelsToDNS(labels []db.Label) map[string]net.IP {
 	records := map[string]net.IP{}
 	for _, label := range labels {
-		if ip := net.ParseIP(label.IP); ip != nil {
+		unhealthy := map[string]struct{}{}
+		for _, ip := range label.UnhealthyIPs {
+			unhealthy[ip] = struct{}{}
+		}
+
+		ip := net.ParseIP(label.IP)
+		if ip != nil && len(unhealthy) < len(label.ContainerIPs) {
 			records[label.Label+".q."] = ip
 		}
 
 		for i, ipStr := range label.ContainerIPs {
+			if _, ok := unhealthy[ipStr]; ok {
+				continue
+			}
+
 			if ip := net.ParseIP(ipStr); ip != nil {
 				records[fmt.Sprintf("%d.%s.q.", i+1, label.Label)] = ip
 			}
@@ -186,8 +334,94 @@ func labelsToDNS(labels []db.Label) map[string]net.IP {
 	return records
 }
 
-var listenAndServe = func(table *dnsTable) error {
-	return table.server.ListenAndServe()
+// connectionsToSRV maps the service name of each protocol that containers may connect
+// to each label with, e.g. _tcp.label.q., to the ports they may connect on.
+func connectionsToSRV(labels []db.Label,
+	connections []db.Connection) map[string][]uint16 {
+
+	known := map[string]struct{}{}
+	for _, label := range labels {
+		known[label.Label] = struct{}{}
+	}
+
+	ports := map[string]map[uint16]struct{}{}
+	for _, conn := range connections {
+		_, ok := known[conn.To]
+		if !ok || conn.From == stitch.PublicInternetLabel {
+			continue
+		}
+
+		for _, p := range stitch.PortProtocols(conn.Protocol) {
+			name := fmt.Sprintf("_%s.%s.q.", p, conn.To)
+			if ports[name] == nil {
+				ports[name] = map[uint16]struct{}{}
+			}
+
+			for port := conn.MinPort; port <= conn.MaxPort; port++ {
+				if len(ports[name]) >= maxSRVPorts {
+					break
+				}
+				ports[name][uint16(port)] = struct{}{}
+			}
+		}
+	}
+
+	records := map[string][]uint16{}
+	for name, portSet := range ports {
+		var sorted []int
+		for port := range portSet {
+			sorted = append(sorted, int(port))
+		}
+		sort.Ints(sorted)
+
+		for _, port := range sorted {
+			records[name] = append(records[name], uint16(port))
+		}
+	}
+	return records
+}
+
+// reverseRecords maps the reverse name of each IP in 'records' to its hostname.  IPs
+// with several hostnames, such as containers with several labels, get the first in
+// alphabetical order.
+func reverseRecords(records map[string]net.IP) map[string]string {
+	ptr := map[string]string{}
+	for name, ip := range records {
+		arpa, err := dns.ReverseAddr(ip.String())
+		if err != nil {
+			continue
+		}
+
+		if host, ok := ptr[arpa]; !ok || name < host {
+			ptr[arpa] = name
+		}
+	}
+	return ptr
 }
 
-var lookupHost = net.LookupHost
+// reverseIP returns the IPv4 address whose reverse name is 'name', or nil if 'name'
+// isn't one.
+func reverseIP(name string) net.IP {
+	if !strings.HasSuffix(name, ".in-addr.arpa.") {
+		return nil
+	}
+
+	octets := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+	if len(octets) != 4 {
+		return nil
+	}
+
+	for i, j := 0, len(octets)-1; i < j; i, j = i+1, j-1 {
+		octets[i], octets[j] = octets[j], octets[i]
+	}
+	return net.ParseIP(strings.Join(octets, ".")).To4()
+}
+
+var listenAndServe = func(server *dns.Server) error {
+	return server.ListenAndServe()
+}
+
+var exchange = func(req *dns.Msg, network, addr string) (*dns.Msg, error) {
+	resp, _, err := (&dns.Client{Net: network}).Exchange(req, addr)
+	return resp, err
+}
@@ -1,10 +1,13 @@
 package network
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
 	"testing"
 
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 )
@@ -12,101 +15,233 @@ import (
 func TestUpdateTable(t *testing.T) {
 	t.Parallel()
 
-	listenAndServe = func(table *dnsTable) error { return assert.AnError }
-	assert.Nil(t, updateTable(nil, nil))
+	listenAndServe = func(server *dns.Server) error { return assert.AnError }
+	assert.Nil(t, updateTable(nil, dnsRecords{}, nil))
 
-	listenAndServe = func(table *dnsTable) error {
-		table.server.NotifyStartedFunc()
+	listenAndServe = func(server *dns.Server) error {
+		server.NotifyStartedFunc()
 		return nil
 	}
 
-	table := updateTable(nil, []db.Label{{Label: "foo", IP: "1.2.3.4"}})
+	records := makeRecords([]db.Label{
+		{Label: "foo", IP: "1.2.3.4", ContainerIPs: []string{"bad"}}}, nil)
+	table := updateTable(nil, records, []string{"8.8.8.8:53"})
 	assert.NotNil(t, table)
-	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(1, 2, 3, 4)}, table.records)
+	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(1, 2, 3, 4)},
+		table.records.a)
+	assert.Equal(t, []string{"8.8.8.8:53"}, table.upstreams)
 
-	newTable := updateTable(table, []db.Label{{Label: "foo", IP: "5.6.7.8"}})
+	records = makeRecords([]db.Label{
+		{Label: "foo", IP: "5.6.7.8", ContainerIPs: []string{"bad"}}}, nil)
+	newTable := updateTable(table, records, nil)
 	assert.NotNil(t, newTable)
 	assert.True(t, table == newTable) // Pointer Equality.
 	assert.Equal(t, map[string]net.IP{"foo.q.": net.IPv4(5, 6, 7, 8)},
-		newTable.records)
+		newTable.records.a)
 }
 
 func TestGenResponse(t *testing.T) {
-	t.Parallel()
+	table := makeTable(makeRecords([]db.Label{{
+		Label:        "a",
+		IP:           "10.0.0.9",
+		ContainerIPs: []string{"10.0.0.2", "10.0.0.3"},
+		UnhealthyIPs: []string{"10.0.0.3"},
+	}}, []db.Connection{
+		{From: "b", To: "a", MinPort: 80, MaxPort: 80, Protocol: stitch.TCP},
+	}), nil)
+	table.upstreams = []string{"upstream"}
 
-	table := makeTable(map[string]net.IP{
-		"a.q.": net.IPv4(1, 2, 3, 4),
-	})
+	hdr := func(name string, rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{
+			Name:   name,
+			Rrtype: rrtype,
+			Class:  dns.ClassINET,
+			Ttl:    dnsTTL,
+		}
+	}
 
-	req := &dns.Msg{}
-	req.SetQuestion("foo.", dns.TypeAAAA)
-	resp := table.genResponse(req)
-	assert.Equal(t, req.Id, resp.Id)
-	assert.Equal(t, resp.Rcode, dns.RcodeNotImplemented)
+	reply := func(req *dns.Msg, answer ...dns.RR) *dns.Msg {
+		exp := *req
+		exp.Response = true
+		exp.Authoritative = true
+		exp.Rcode = dns.RcodeSuccess
+		exp.Answer = answer
+		return &exp
+	}
 
+	req := &dns.Msg{}
 	req.Question = nil
-	resp = table.genResponse(req)
+	resp := table.genResponse(req, "udp")
 	assert.Equal(t, req.Id, resp.Id)
 	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
-	req.SetQuestion("bad.q.", dns.TypeA)
-	resp = table.genResponse(req)
-	assert.Nil(t, resp)
+	req.SetQuestion("a.q.", dns.TypeA)
+	req.Question[0].Qclass = dns.ClassCHAOS
+	resp = table.genResponse(req, "udp")
+	assert.Equal(t, dns.RcodeNotImplemented, resp.Rcode)
 
 	req.SetQuestion("a.q.", dns.TypeA)
-	resp = table.genResponse(req)
-	exp := *req
-	exp.Response = true
-	exp.Rcode = dns.RcodeSuccess
-	exp.Answer = []dns.RR{&dns.A{
-		Hdr: dns.RR_Header{
-			Name:   "a.q.",
-			Rrtype: dns.TypeA,
-			Class:  dns.ClassINET,
-			Ttl:    dnsTTL,
-		},
-		A: net.IPv4(1, 2, 3, 4),
-	}}
-	assert.Equal(t, &exp, resp)
+	assert.Equal(t, reply(req, &dns.A{Hdr: hdr("a.q.", dns.TypeA),
+		A: net.IPv4(10, 0, 0, 9)}), table.genResponse(req, "udp"))
 
-}
+	// Names are case insensitive, though answers keep the case they were asked in.
+	req.SetQuestion("A.Q.", dns.TypeA)
+	assert.Equal(t, reply(req, &dns.A{Hdr: hdr("A.Q.", dns.TypeA),
+		A: net.IPv4(10, 0, 0, 9)}), table.genResponse(req, "udp"))
 
-func TestLookupA(t *testing.T) {
-	t.Parallel()
+	// Quilt addresses are IPv4 only.
+	req.SetQuestion("a.q.", dns.TypeAAAA)
+	assert.Equal(t, reply(req), table.genResponse(req, "udp"))
 
-	table := makeTable(map[string]net.IP{
-		"a.q.": net.IPv4(1, 2, 3, 4),
-	})
+	req.SetQuestion("_tcp.a.q.", dns.TypeSRV)
+	exp := reply(req, &dns.SRV{Hdr: hdr("_tcp.a.q.", dns.TypeSRV), Weight: 1,
+		Port: 80, Target: "a.q."})
+	exp.Extra = []dns.RR{&dns.A{Hdr: hdr("a.q.", dns.TypeA),
+		A: net.IPv4(10, 0, 0, 9)}}
+	assert.Equal(t, exp, table.genResponse(req, "udp"))
 
-	assert.Empty(t, table.lookupA("bad.q."))
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("a.q."))
+	req.SetQuestion("2.0.0.10.in-addr.arpa.", dns.TypePTR)
+	assert.Equal(t, reply(req, &dns.PTR{
+		Hdr: hdr("2.0.0.10.in-addr.arpa.", dns.TypePTR), Ptr: "1.a.q."}),
+		table.genResponse(req, "udp"))
 
-	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
-	assert.Empty(t, table.lookupA("quilt.io."))
+	// The unhealthy container may have a record in the future.
+	req.SetQuestion("2.a.q.", dns.TypeA)
+	assert.Nil(t, table.genResponse(req, "udp"))
 
-	lookupHost = func(string) ([]string, error) { return []string{"bad"}, nil }
-	assert.Empty(t, table.lookupA("quilt.io."))
+	for _, name := range []string{"3.a.q.", "b.q.", "_udp.a.q.",
+		"3.0.0.10.in-addr.arpa."} {
+		req.SetQuestion(name, dns.TypeA)
+		resp = table.genResponse(req, "udp")
+		assert.Equal(t, req.Id, resp.Id)
+		assert.Equal(t, dns.RcodeNameError, resp.Rcode, name)
+	}
 
-	lookupHost = func(string) ([]string, error) {
-		return []string{"2601:644:380:cde:fc06:2533:adf9:2891"}, nil
+	// Everything else is forwarded upstream.
+	upstreamResp := &dns.Msg{}
+	var upstreamAddrs []string
+	exchange = func(req *dns.Msg, network, addr string) (*dns.Msg, error) {
+		upstreamAddrs = append(upstreamAddrs, addr)
+		return upstreamResp.SetReply(req), nil
 	}
-	assert.Empty(t, table.lookupA("quilt.io."))
 
-	lookupHost = func(string) ([]string, error) {
-		return []string{"1.2.3.4", "5.6.7.8"}, nil
+	req.SetQuestion("quilt.io.", dns.TypeAAAA)
+	assert.Equal(t, upstreamResp, table.genResponse(req, "udp"))
+	assert.Equal(t, []string{"upstream"}, upstreamAddrs)
+
+	req.SetQuestion("4.3.2.1.in-addr.arpa.", dns.TypePTR)
+	assert.Equal(t, upstreamResp, table.genResponse(req, "udp"))
+}
+
+func TestForward(t *testing.T) {
+	var upstreamAddrs []string
+	exchange = func(req *dns.Msg, network, addr string) (*dns.Msg, error) {
+		upstreamAddrs = append(upstreamAddrs, addr)
+		if addr == "bad:53" {
+			return nil, assert.AnError
+		}
+
+		resp := (&dns.Msg{}).SetReply(req)
+		resp.Truncated = addr == "big:53" && network == "udp"
+		return resp, nil
 	}
-	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4), net.IPv4(5, 6, 7, 8)},
-		table.lookupA("quilt.io."))
+
+	req := &dns.Msg{}
+	req.SetQuestion("quilt.io.", dns.TypeA)
+
+	// Upstreams are tried in order until one responds.
+	table := makeTable(dnsRecords{}, []string{"bad:53", "good:53", "other:53"})
+	resp := table.forward(req, "udp")
+	assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	assert.Equal(t, []string{"bad:53", "good:53"}, upstreamAddrs)
+
+	// Truncated responses are passed on for the client to retry over TCP, which is
+	// then used upstream too.
+	upstreamAddrs = nil
+	table.upstreams = []string{"big:53"}
+	resp = table.forward(req, "udp")
+	assert.True(t, resp.Truncated)
+	assert.Equal(t, []string{"big:53"}, upstreamAddrs)
+
+	resp = table.forward(req, "tcp")
+	assert.False(t, resp.Truncated)
+
+	upstreamAddrs = nil
+	table.upstreams = []string{"bad:53"}
+	resp = table.forward(req, "udp")
+	assert.Equal(t, req.Id, resp.Id)
+	assert.Equal(t, dns.RcodeServerFailure, resp.Rcode)
+
+	// Without upstreams, the host's resolvers are used.
+	f, err := ioutil.TempFile("", "resolv.conf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("nameserver bad\nnameserver 1.2.3.4\n")
+	f.Close()
+
+	resolvConf = f.Name()
+	upstreamAddrs = nil
+	table.upstreams = nil
+	resp = table.forward(req, "udp")
+	assert.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	assert.Equal(t, []string{"bad:53", "1.2.3.4:53"}, upstreamAddrs)
 }
 
 func TestMakeTable(t *testing.T) {
 	t.Parallel()
 
-	records := map[string]net.IP{"a": net.IPv4(1, 2, 3, 4)}
-	tbl := makeTable(records)
+	records := dnsRecords{a: map[string]net.IP{"a": net.IPv4(1, 2, 3, 4)}}
+	tbl := makeTable(records, []string{"8.8.8.8:53"})
 	assert.Equal(t, tbl.records, records)
-	assert.Equal(t, tbl.server.Addr, "10.0.0.1:53")
-	assert.Equal(t, tbl.server.Net, "udp")
+	assert.Equal(t, tbl.upstreams, []string{"8.8.8.8:53"})
+	assert.Len(t, tbl.servers, 2)
+	for i, network := range []string{"udp", "tcp"} {
+		assert.Equal(t, "10.0.0.1:53", tbl.servers[i].Addr)
+		assert.Equal(t, network, tbl.servers[i].Net)
+	}
+}
+
+func TestConnectionsToSRV(t *testing.T) {
+	t.Parallel()
+
+	labels := []db.Label{{Label: "a"}, {Label: "b"}}
+	res := connectionsToSRV(labels, []db.Connection{
+		{From: "b", To: "a", MinPort: 80, MaxPort: 80},
+		{From: "c", To: "a", MinPort: 22, MaxPort: 22, Protocol: stitch.TCP},
+		{From: "b", To: "a", Protocol: stitch.ICMP},
+		{From: "a", To: "b", MinPort: 1000, MaxPort: 2000, Protocol: stitch.UDP},
+		{From: "a", To: "c", MinPort: 80, MaxPort: 80},
+		{From: stitch.PublicInternetLabel, To: "a", MinPort: 443, MaxPort: 443},
+	})
+
+	var ranged []uint16
+	for port := uint16(1000); port < 1000+maxSRVPorts; port++ {
+		ranged = append(ranged, port)
+	}
+	assert.Equal(t, map[string][]uint16{
+		"_tcp.a.q.": {22, 80},
+		"_udp.a.q.": {80},
+		"_udp.b.q.": ranged,
+	}, res)
+}
+
+func TestReverseRecords(t *testing.T) {
+	t.Parallel()
+
+	res := reverseRecords(map[string]net.IP{
+		"a.q.":   net.IPv4(10, 0, 0, 9),
+		"1.a.q.": net.IPv4(10, 0, 0, 2),
+		"1.b.q.": net.IPv4(10, 0, 0, 2),
+	})
+	assert.Equal(t, map[string]string{
+		"9.0.0.10.in-addr.arpa.": "a.q.",
+		"2.0.0.10.in-addr.arpa.": "1.a.q.",
+	}, res)
+
+	assert.Equal(t, net.IPv4(10, 0, 0, 2).To4(), reverseIP("2.0.0.10.in-addr.arpa."))
+	assert.Nil(t, reverseIP("0.10.in-addr.arpa."))
+	assert.Nil(t, reverseIP("a.0.0.10.in-addr.arpa."))
+	assert.Nil(t, reverseIP("a.q."))
 }
 
 func TestLabelsToDNS(t *testing.T) {
@@ -127,10 +262,26 @@ func TestLabelsToDNS(t *testing.T) {
 		ContainerIPs: []string{"1.1.1.1", "2.2.2.2"},
 	}})
 	exp := map[string]net.IP{
-		"l3.q.":   net.IPv4(1, 2, 3, 4),
 		"l4.q.":   net.IPv4(5, 6, 7, 8),
 		"1.l4.q.": net.IPv4(1, 1, 1, 1),
 		"2.l4.q.": net.IPv4(2, 2, 2, 2),
 	}
 	assert.Equal(t, exp, res)
+
+	res = labelsToDNS([]db.Label{{
+		Label:        "l1",
+		IP:           "3.3.3.3",
+		ContainerIPs: []string{"1.1.1.1"},
+		UnhealthyIPs: []string{"1.1.1.1"},
+	}, {
+		Label:        "l2",
+		IP:           "4.4.4.4",
+		ContainerIPs: []string{"1.1.1.1", "2.2.2.2"},
+		UnhealthyIPs: []string{"1.1.1.1"},
+	}})
+	exp = map[string]net.IP{
+		"l2.q.":   net.IPv4(4, 4, 4, 4),
+		"2.l2.q.": net.IPv4(2, 2, 2, 2),
+	}
+	assert.Equal(t, exp, res)
 }
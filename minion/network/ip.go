@@ -15,7 +15,8 @@ import (
 )
 
 func runUpdateIPs(conn db.Conn) {
-	txn := conn.Txn(db.ContainerTable, db.LabelTable)
+	txn := conn.Txn(db.ContainerTable, db.LabelTable).WithReason(
+		"network: allocate IPs")
 	err := txn.Run(func(view db.Database) error {
 		err := allocateContainerIPs(view)
 		if err == nil {
@@ -39,6 +40,14 @@ func allocateContainerIPs(view db.Database) error {
 		ipdef.QuiltSubnet.IP.String(): {},
 	}
 
+	// Labels' virtual IPs come from the same subnet, so they mustn't be handed out
+	// to containers.
+	for _, dbl := range view.SelectFromLabel(nil) {
+		if dbl.IP != "" {
+			ipSet[dbl.IP] = struct{}{}
+		}
+	}
+
 	var unassigned []db.Container
 	for _, dbc := range dbcs {
 		if dbc.IP != "" {
@@ -70,10 +79,23 @@ func updateLabelIPs(view db.Database) error {
 	// ordering is consistent between function calls.  This is pretty darn fragile.
 	sort.Sort(db.ContainerSlice(dbcs))
 
+	ipSet := map[string]struct{}{
+		ipdef.GatewayIP.String():      {},
+		ipdef.QuiltSubnet.IP.String(): {},
+	}
+
 	containerIPs := map[string][]string{}
+	unhealthyIPs := map[string][]string{}
+	healthyMinions := map[string][]string{}
 	for _, dbc := range dbcs {
+		ipSet[dbc.IP] = struct{}{}
 		for _, l := range dbc.Labels {
 			containerIPs[l] = append(containerIPs[l], dbc.IP)
+			if dbc.Health == db.Unhealthy {
+				unhealthyIPs[l] = append(unhealthyIPs[l], dbc.IP)
+			} else if dbc.Minion != "" {
+				healthyMinions[l] = append(healthyMinions[l], dbc.Minion)
+			}
 		}
 	}
 
@@ -97,18 +119,35 @@ func updateLabelIPs(view db.Database) error {
 		pairs = append(pairs, join.Pair{L: view.InsertLabel(), R: label})
 	}
 
+	// Each label has a virtual IP of its own, which the workers load balance across
+	// its healthy containers.  A label keeps its IP for as long as it exists, so
+	// clients that cache its DNS record aren't affected by changes to its members.
+	var unassigned []db.Label
 	for _, pair := range pairs {
 		dbl := pair.L.(db.Label)
 		dbl.Label = pair.R.(string)
 		dbl.ContainerIPs = containerIPs[dbl.Label]
+		dbl.UnhealthyIPs = unhealthyIPs[dbl.Label]
 
-		// XXX: In effect, we're implementing a dumb load balancer where all
-		// traffic goes to the first container.  Something more sophisticated is
-		// coming (hopefully).
-		dbl.IP = ""
-		if len(dbl.ContainerIPs) > 0 {
-			dbl.IP = dbl.ContainerIPs[0]
+		dbl.HealthyMinions = healthyMinions[dbl.Label]
+		sort.Strings(dbl.HealthyMinions)
+
+		if _, ok := ipSet[dbl.IP]; dbl.IP == "" || ok {
+			unassigned = append(unassigned, dbl)
+			continue
 		}
+
+		ipSet[dbl.IP] = struct{}{}
+		view.Commit(dbl)
+	}
+
+	for _, dbl := range unassigned {
+		ip, err := allocateIP(ipSet, ipdef.QuiltSubnet)
+		if err != nil {
+			return err
+		}
+
+		dbl.IP = ip
 		view.Commit(dbl)
 	}
 
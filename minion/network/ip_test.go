@@ -51,12 +51,14 @@ func TestUpdateLabelIPs(t *testing.T) {
 		dbc.Labels = []string{"red", "blue"}
 		dbc.StitchID = "1"
 		dbc.IP = "1.1.1.1"
+		dbc.Minion = "m1"
 		view.Commit(dbc)
 
 		dbc = view.InsertContainer()
 		dbc.Labels = []string{"red"}
 		dbc.StitchID = "2"
 		dbc.IP = "2.2.2.2"
+		dbc.Minion = "m2"
 		view.Commit(dbc)
 
 		label := view.InsertLabel()
@@ -71,24 +73,70 @@ func TestUpdateLabelIPs(t *testing.T) {
 		return nil
 	})
 
-	var labels []db.Label
+	labelIPs := map[string]string{}
 	for _, label := range conn.SelectFromLabel(nil) {
+		assert.True(t, ipdef.QuiltSubnet.Contains(net.ParseIP(label.IP)))
+		assert.NotContains(t, []string{"1.1.1.1", "2.2.2.2"}, label.IP)
+		labelIPs[label.Label] = label.IP
+
 		label.ID = 0
-		labels = append(labels, label)
+		label.IP = ""
+		switch label.Label {
+		case "red":
+			assert.Equal(t, db.Label{Label: "red",
+				ContainerIPs:   []string{"1.1.1.1", "2.2.2.2"},
+				HealthyMinions: []string{"m1", "m2"}}, label)
+		case "blue":
+			assert.Equal(t, db.Label{Label: "blue",
+				ContainerIPs:   []string{"1.1.1.1"},
+				HealthyMinions: []string{"m1"}}, label)
+		default:
+			t.Errorf("unexpected label: %s", label.Label)
+		}
+	}
+	assert.Len(t, labelIPs, 2)
+	assert.NotEqual(t, labelIPs["red"], labelIPs["blue"])
+
+	// Labels keep their virtual IPs as the health of their containers changes.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			if dbc.IP == "1.1.1.1" {
+				dbc.Health = db.Unhealthy
+				view.Commit(dbc)
+			}
+		}
+		assert.NoError(t, updateLabelIPs(view))
+		return nil
+	})
+
+	for _, label := range conn.SelectFromLabel(nil) {
+		assert.Equal(t, []string{"1.1.1.1"}, label.UnhealthyIPs)
+		assert.Equal(t, labelIPs[label.Label], label.IP)
+		if label.Label == "red" {
+			assert.Equal(t, []string{"m2"}, label.HealthyMinions)
+		} else {
+			assert.Empty(t, label.HealthyMinions)
+		}
+	}
+
+	// A label whose IP collides with a container is given a new one.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, label := range view.SelectFromLabel(nil) {
+			if label.Label == "red" {
+				label.IP = "2.2.2.2"
+				view.Commit(label)
+			}
+		}
+		assert.NoError(t, updateLabelIPs(view))
+		return nil
+	})
+
+	for _, label := range conn.SelectFromLabel(nil) {
+		assert.True(t, ipdef.QuiltSubnet.Contains(net.ParseIP(label.IP)))
+		if label.Label == "blue" {
+			assert.Equal(t, labelIPs["blue"], label.IP)
+		}
 	}
-	sort.Sort(db.LabelSlice(labels))
-
-	assert.Equal(t, []db.Label{
-		{
-			Label:        "blue",
-			IP:           "1.1.1.1",
-			ContainerIPs: []string{"1.1.1.1"},
-		}, {
-			Label:        "red",
-			IP:           "1.1.1.1",
-			ContainerIPs: []string{"1.1.1.1", "2.2.2.2"},
-		},
-	}, labels)
 }
 
 func TestAllocate(t *testing.T) {
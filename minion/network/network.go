@@ -20,8 +20,9 @@ const lSwitch = "quilt"
 const quiltBridge = "quilt-int"
 const ovnBridge = "br-int"
 
-// Run blocks implementing the network services.
-func Run(conn db.Conn) {
+// Run blocks implementing the network services.  The DNS server forwards queries
+// outside of Quilt to 'dnsUpstreams', or to the host's resolvers if it's empty.
+func Run(conn db.Conn, dnsUpstreams []string) {
 	loopLog := util.NewEventTimer("Network")
 	for range conn.TriggerTick(30, db.MinionTable, db.ContainerTable,
 		db.ConnectionTable, db.LabelTable, db.EtcdTable).C {
@@ -31,7 +32,7 @@ func Run(conn db.Conn) {
 			runUpdateIPs(conn)
 			runMaster(conn)
 		} else {
-			runDNS(conn)
+			runDNS(conn, dnsUpstreams)
 			runWorker(conn)
 		}
 		loopLog.LogEnd()
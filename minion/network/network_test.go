@@ -11,6 +11,7 @@ import (
 	"github.com/NetSys/quilt/join"
 	"github.com/NetSys/quilt/minion/ipdef"
 	"github.com/NetSys/quilt/minion/ovsdb"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -297,3 +298,17 @@ func TestGenerateOFPorts(t *testing.T) {
 			Mac: ipdef.IPStrToMac("1.1.1.1")}},
 		generateOFPorts(ifaces, containers))
 }
+
+func TestMatchStringProtocol(t *testing.T) {
+	t.Parallel()
+
+	udp := db.Connection{From: "red", To: "blue", MinPort: 53, MaxPort: 53,
+		Protocol: stitch.UDP}
+	assert.Equal(t, "(((ip4.src == $red && ip4.dst == $blue) && "+
+		"(53 <= udp.dst <= 53)) || ((ip4.src == $blue && ip4.dst == $red) && "+
+		"(53 <= udp.src <= 53)))", matchString(udp))
+
+	icmp := db.Connection{From: "red", To: "blue", Protocol: stitch.ICMP}
+	assert.Equal(t, "(((ip4.src == $red && ip4.dst == $blue) && (icmp)) || "+
+		"((ip4.src == $blue && ip4.dst == $red) && (icmp)))", matchString(icmp))
+}
@@ -1,7 +1,10 @@
 package network
 
 import (
+	"encoding/binary"
 	"fmt"
+	"net"
+	"sort"
 	"strings"
 
 	"github.com/NetSys/quilt/minion/ipdef"
@@ -44,6 +47,26 @@ the gateway.
 Reg2 -- Contains the OpenFlow port number of the patch port, or zero if the packet came
 from the gateway.
 
+Load Balancing
+--------------
+
+Connections to each label's virtual IP are balanced across the label's healthy
+containers.  The veth's ARP requests for it are answered with labelMac.  The first
+packet of a connection picks a container with the label's select group, which hashes
+the packet's headers.  Conntrack (in zone lbZone) then rewrites the connection's
+destination to that container, and its replies' source back to the label IP, so the
+rest of the connection follows the first packet.
+
+// Each select group has a bucket per healthy container in its label.
+Group_label {
+	for each healthy container IP {
+		bucket {
+			ct(commit, zone=lbZone, nat(dst=IP))
+			goto Table_4
+		}
+	}
+}
+
 Tables
 ------
 
@@ -73,6 +96,14 @@ Table_0 { // Initial Table
 // Table_1 handles special cases for broadcast packets and the default gateway.  If no
 special cases apply, it outputs the packet.
 Table_1 {
+	// Answer the veth's ARP requests for label IPs.
+	for each label {
+		if reg0=1 && arp_op=request && arp_tpa=label.IP {
+			arp_reply(labelMac)
+			output:in_port
+		}
+	}
+
 	// If the veth sends a broadcast, send it to the gateway and the patch port.
 	if reg0=1 && dl_dst=ff:ff:ff:ff:ff:ff {
 		output:LOCAL,reg2
@@ -104,6 +135,20 @@ Table_1 {
 		goto Table_2
 	}
 
+	// Load balance the veth's connections to label IPs.
+	for each label {
+		if reg0=1 && nw_dst=label.IP {
+			ct(zone=lbZone, nat)
+			goto Table_3
+		}
+	}
+
+	// Undo the load balancer's NAT on replies to the veth.
+	if reg0=2 && ip {
+		ct(zone=lbZone, nat)
+		goto Table_5
+	}
+
 	// Send packets from the veth to the patch port.
 	if reg0=1 {
 		output:reg2
@@ -124,14 +169,55 @@ Table_2 {
 		}
 	}
 }
+
+// Table_3 picks a container for new connections to a label IP.  Connections that have
+// already been assigned one were translated to it by Table_1's ct().
+Table_3 {
+	for each label {
+		if ct_state=+trk+new && nw_dst=label.IP {
+			group:Group_label
+		}
+	}
+
+	if ct_state=+trk+est {
+		goto Table_4
+	}
+}
+
+// Table_4 addresses load balanced packets to their container and sends them to the
+// patch port.
+Table_4 {
+	for each healthy container IP in a label {
+		if nw_dst=IP {
+			dl_dst <- IP's Mac
+			output:reg2
+		}
+	}
+}
+
+// Table_5 sends replies to the veth once their NAT has been undone.
+Table_5 {
+	output:reg1
+}
 */
 
+// The conntrack zone used by the load balancer.  Zones are shared by every bridge on
+// the host, so it's chosen to stay clear of those OVN allocates from 1 upwards.
+const lbZone = 0xfff0
+
 type ofPort struct {
 	PatchPort int
 	VethPort  int
 	Mac       string
 }
 
+// A loadBalancer balances the connections to a label's IP across the IPs of the label's
+// healthy containers.
+type loadBalancer struct {
+	IP       string
+	Backends []string
+}
+
 var staticFlows = []string{
 	// Table 0
 	"table=0,priority=1000,in_port=LOCAL,actions=resubmit(,1)",
@@ -146,10 +232,18 @@ var staticFlows = []string{
 	fmt.Sprintf("table=1,priority=700,dl_dst=%s,actions=drop", ipdef.GatewayMac),
 	"table=1,priority=600,in_port=LOCAL,actions=resubmit(,2)",
 	"table=1,priority=500,reg0=1,actions=output:NXM_NX_REG2[]",
+	fmt.Sprintf("table=1,priority=450,reg0=2,ip,actions=ct(zone=%d,nat,table=5)",
+		lbZone),
 	"table=1,priority=400,reg0=2,actions=output:NXM_NX_REG1[]",
+
+	// Table 3
+	"table=3,priority=900,ct_state=+trk+est,ip,actions=resubmit(,4)",
+
+	// Table 5
+	"table=5,priority=1000,actions=output:NXM_NX_REG1[]",
 }
 
-func generateOpenFlow(ofps []ofPort) []string {
+func generateOpenFlow(ofps []ofPort, lbs []loadBalancer) []string {
 	flows := staticFlows
 	var gatewayBroadcastActions []string
 	for _, ofp := range ofps {
@@ -167,5 +261,64 @@ func generateOpenFlow(ofps []ofPort) []string {
 	}
 	flows = append(flows, "table=1,priority=850,dl_dst=ff:ff:ff:ff:ff:ff,actions="+
 		strings.Join(gatewayBroadcastActions, ","))
+
+	backends := map[string]struct{}{}
+	for _, lb := range lbs {
+		flows = append(flows,
+			fmt.Sprintf("table=1,priority=1050,reg0=1,arp,arp_op=1,"+
+				"arp_tpa=%s,actions="+
+				"move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[],"+
+				"mod_dl_src:%s,load:0x2->NXM_OF_ARP_OP[],"+
+				"move:NXM_NX_ARP_SHA[]->NXM_NX_ARP_THA[],"+
+				"move:NXM_OF_ARP_SPA[]->NXM_OF_ARP_TPA[],"+
+				"set_field:%s->arp_sha,set_field:%s->arp_spa,in_port",
+				lb.IP, labelMac, labelMac, lb.IP),
+			fmt.Sprintf("table=1,priority=550,reg0=1,ip,nw_dst=%s,"+
+				"actions=ct(zone=%d,nat,table=3)", lb.IP, lbZone),
+			fmt.Sprintf("table=3,priority=1000,ct_state=+trk+new,ip,"+
+				"nw_dst=%s,actions=group:%d", lb.IP, groupID(lb.IP)))
+
+		for _, ip := range lb.Backends {
+			backends[ip] = struct{}{}
+		}
+	}
+
+	var backendIPs []string
+	for ip := range backends {
+		backendIPs = append(backendIPs, ip)
+	}
+	sort.Strings(backendIPs)
+
+	for _, ip := range backendIPs {
+		flows = append(flows, fmt.Sprintf("table=4,priority=1000,ip,nw_dst=%s,"+
+			"actions=mod_dl_dst:%s,output:NXM_NX_REG2[]",
+			ip, ipdef.IPStrToMac(ip)))
+	}
 	return flows
 }
+
+// generateGroups returns a select group for each of the load balancers, numbered by
+// groupID.
+func generateGroups(lbs []loadBalancer) []string {
+	var groups []string
+	for _, lb := range lbs {
+		group := fmt.Sprintf("group_id=%d,type=select", groupID(lb.IP))
+		for _, ip := range lb.Backends {
+			group += fmt.Sprintf(",bucket=actions=ct(commit,zone=%d,"+
+				"nat(dst=%s),table=4)", lbZone, ip)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// groupID returns the number of the select group that balances connections to the
+// label IP 'ip'.  It's derived from the IP itself, so that a label keeps its group,
+// and the connections using it, as other labels come and go.
+func groupID(ip string) uint32 {
+	ip4 := net.ParseIP(ip).To4()
+	if ip4 == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(ip4)
+}
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,7 +11,7 @@ func TestGenerateOpenFlow(t *testing.T) {
 	t.Parallel()
 	flows := generateOpenFlow([]ofPort{
 		{PatchPort: 4, VethPort: 5, Mac: "66:66:66:66:66:66"},
-		{PatchPort: 9, VethPort: 8, Mac: "99:99:99:99:99:99"}})
+		{PatchPort: 9, VethPort: 8, Mac: "99:99:99:99:99:99"}}, nil)
 	exp := append(staticFlows,
 		"table=0,priority=1000,in_port=5,dl_src=66:66:66:66:66:66,"+
 			"actions=load:0x1->NXM_NX_REG0[],load:0x5->NXM_NX_REG1[],"+
@@ -29,3 +30,56 @@ func TestGenerateOpenFlow(t *testing.T) {
 		"table=1,priority=850,dl_dst=ff:ff:ff:ff:ff:ff,actions=output:5,output:8")
 	assert.Equal(t, exp, flows)
 }
+
+func TestGenerateOpenFlowLoadBalancers(t *testing.T) {
+	t.Parallel()
+	flows := generateOpenFlow(nil, []loadBalancer{
+		{IP: "10.0.0.5", Backends: []string{"10.0.0.3", "10.0.0.2"}},
+		{IP: "10.0.0.6", Backends: []string{"10.0.0.3"}},
+		{IP: "10.0.0.7"}})
+
+	lbFlows := func(ip string, group uint32) []string {
+		return []string{
+			"table=1,priority=1050,reg0=1,arp,arp_op=1,arp_tpa=" + ip +
+				",actions=move:NXM_OF_ETH_SRC[]->NXM_OF_ETH_DST[]," +
+				"mod_dl_src:0a:00:00:00:00:00," +
+				"load:0x2->NXM_OF_ARP_OP[]," +
+				"move:NXM_NX_ARP_SHA[]->NXM_NX_ARP_THA[]," +
+				"move:NXM_OF_ARP_SPA[]->NXM_OF_ARP_TPA[]," +
+				"set_field:0a:00:00:00:00:00->arp_sha," +
+				"set_field:" + ip + "->arp_spa,in_port",
+			"table=1,priority=550,reg0=1,ip,nw_dst=" + ip +
+				",actions=ct(zone=65520,nat,table=3)",
+			fmt.Sprintf("table=3,priority=1000,ct_state=+trk+new,ip,"+
+				"nw_dst=%s,actions=group:%d", ip, group),
+		}
+	}
+
+	var exp []string
+	exp = append(exp, staticFlows...)
+	exp = append(exp, "table=1,priority=850,dl_dst=ff:ff:ff:ff:ff:ff,actions=")
+	exp = append(exp, lbFlows("10.0.0.5", 0x0a000005)...)
+	exp = append(exp, lbFlows("10.0.0.6", 0x0a000006)...)
+	exp = append(exp, lbFlows("10.0.0.7", 0x0a000007)...)
+	exp = append(exp,
+		"table=4,priority=1000,ip,nw_dst=10.0.0.2,"+
+			"actions=mod_dl_dst:02:00:0a:00:00:02,output:NXM_NX_REG2[]",
+		"table=4,priority=1000,ip,nw_dst=10.0.0.3,"+
+			"actions=mod_dl_dst:02:00:0a:00:00:03,output:NXM_NX_REG2[]")
+	assert.Equal(t, exp, flows)
+}
+
+func TestGenerateGroups(t *testing.T) {
+	t.Parallel()
+	groups := generateGroups([]loadBalancer{
+		{IP: "10.0.0.5", Backends: []string{"10.0.0.3", "10.0.0.2"}},
+		{IP: "10.0.0.7"}})
+	assert.Equal(t, []string{
+		"group_id=167772165,type=select," +
+			"bucket=actions=ct(commit,zone=65520," +
+			"nat(dst=10.0.0.3),table=4)," +
+			"bucket=actions=ct(commit,zone=65520," +
+			"nat(dst=10.0.0.2),table=4)",
+		"group_id=167772167,type=select",
+	}, groups)
+}
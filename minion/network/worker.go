@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
@@ -45,7 +47,7 @@ func runWorker(conn db.Conn) {
 	// XXX: By doing all the work within a transaction, we (kind of) guarantee that
 	// containers won't be removed while we're in the process of setting them up.
 	// Not ideal, but for now it's good enough.
-	conn.Txn(db.ConnectionTable, db.ContainerTable,
+	conn.Txn(db.ConnectionTable, db.ContainerTable, db.LabelTable,
 		db.MinionTable).Run(func(view db.Database) error {
 
 		if !checkSupervisorInit(view) {
@@ -58,33 +60,38 @@ func runWorker(conn db.Conn) {
 			return c.DockerID != "" && c.IP != ""
 		})
 		connections := view.SelectFromConnection(nil)
+		labels := view.SelectFromLabel(nil)
+		minions := view.SelectFromMinion(nil)
 
 		var wg sync.WaitGroup
 
 		wg.Add(1)
 		go func() {
-			updateNAT(containers, connections)
+			updateNAT(containers, connections, labels, minions)
 			wg.Done()
 		}()
 
 		// Ports must be updated before OpenFlow so they must be done in the same
 		// go routine.
 		updatePorts(odb, containers)
-		updateOpenFlow(odb, containers)
+		updateOpenFlow(odb, containers, labels)
 
 		wg.Wait()
 		return nil
 	})
 }
 
-func updateNAT(containers []db.Container, connections []db.Connection) {
+func updateNAT(containers []db.Container, connections []db.Connection,
+	labels []db.Label, minions []db.Minion) {
+
 	publicInterface, err := getPublicInterface()
 	if err != nil {
 		log.WithError(err).Error("Failed to get public interface")
 		return
 	}
 
-	targetRules := generateTargetNatRules(publicInterface, containers, connections)
+	targetRules := generateTargetNatRules(publicInterface, containers, connections,
+		labels, minions)
 	currRules, err := generateCurrentNatRules()
 	if err != nil {
 		log.WithError(err).Error("failed to get NAT rules")
@@ -134,7 +141,7 @@ func generateCurrentNatRules() (ipRuleSlice, error) {
 }
 
 func generateTargetNatRules(publicInterface string, containers []db.Container,
-	connections []db.Connection) ipRuleSlice {
+	connections []db.Connection, labels []db.Label, minions []db.Minion) ipRuleSlice {
 	strRules := []string{
 		"-P PREROUTING ACCEPT",
 		"-P INPUT ACCEPT",
@@ -144,15 +151,20 @@ func generateTargetNatRules(publicInterface string, containers []db.Container,
 			publicInterface),
 	}
 
-	protocols := []string{"tcp", "udp"}
-	// Map each container IP to all ports on which it can receive packets
-	// from the public internet.
-	portsFromWeb := make(map[string]map[int]struct{})
+	// Map each container IP, and the public ports and protocols on which it can
+	// receive packets from the public internet, to the container port that those
+	// packets are forwarded to.
+	type publicPort struct {
+		ip       string
+		port     int
+		protocol string
+	}
+	portsFromWeb := map[publicPort]int{}
 
 	for _, dbc := range containers {
 		for _, conn := range connections {
 
-			if conn.From != stitch.PublicInternetLabel {
+			if conn.From != stitch.PublicInternetLabel || conn.Ingress {
 				continue
 			}
 
@@ -162,28 +174,30 @@ func generateTargetNatRules(publicInterface string, containers []db.Container,
 					continue
 				}
 
-				if _, ok := portsFromWeb[dbc.IP]; !ok {
-					portsFromWeb[dbc.IP] = make(map[int]struct{})
+				containerPort := conn.MinPort
+				if conn.ContainerPort != 0 {
+					containerPort = conn.ContainerPort
 				}
 
-				portsFromWeb[dbc.IP][conn.MinPort] = struct{}{}
+				for _, p := range stitch.PortProtocols(conn.Protocol) {
+					key := publicPort{dbc.IP, conn.MinPort, p}
+					portsFromWeb[key] = containerPort
+				}
 			}
 		}
 	}
 
-	// Map the container's port to the same port of the host.
-	for ip, ports := range portsFromWeb {
-		for port := range ports {
-			for _, protocol := range protocols {
-				strRules = append(strRules, fmt.Sprintf(
-					"-A PREROUTING -i %[1]s "+
-						"-p %[2]s -m %[2]s --dport %[3]d -j "+
-						"DNAT --to-destination %[4]s:%[3]d",
-					publicInterface, protocol, port, ip))
-			}
-		}
+	// Map the port of the host to the container's port.
+	for pub, containerPort := range portsFromWeb {
+		strRules = append(strRules, fmt.Sprintf(
+			"-A PREROUTING -i %[1]s -p %[2]s -m %[2]s --dport %[3]d -j "+
+				"DNAT --to-destination %[4]s:%[5]d", publicInterface,
+			pub.protocol, pub.port, pub.ip, containerPort))
 	}
 
+	strRules = append(strRules, generateIngressRules(publicInterface, containers,
+		connections, labels, minions)...)
+
 	var rules ipRuleSlice
 	for _, r := range strRules {
 		rule, err := makeIPRule(r)
@@ -195,6 +209,135 @@ func generateTargetNatRules(publicInterface string, containers []db.Container,
 	return rules
 }
 
+// generateIngressRules returns the rules that balance the public internet's connections
+// to ingress labels across the labels' healthy containers.  Each connection goes either
+// to a healthy container on this minion, or to another minion running one, which is
+// forwarded the connection on the same public port.  Other minions are weighted by the
+// number of healthy containers they run, so that each container gets an even share.
+// Connections forwarded by other minions only go to local containers, so they're
+// never forwarded twice.
+//
+// iptables tries a chain's rules in order, and the statistic matches only split
+// connections evenly in the order the rules are generated.  Because updateNAT appends
+// the rules that are missing, every rule is tagged with a hash of them all, so that
+// any change replaces them all in order.
+func generateIngressRules(publicInterface string, containers []db.Container,
+	connections []db.Connection, labels []db.Label, minions []db.Minion) []string {
+
+	var self string
+	var peers []string
+	for _, m := range minions {
+		if m.Self {
+			self = m.PrivateIP
+		} else if m.Role == db.Worker && m.PrivateIP != "" {
+			peers = append(peers, m.PrivateIP)
+		}
+	}
+	sort.Strings(peers)
+
+	labelMap := map[string]db.Label{}
+	for _, label := range labels {
+		labelMap[label.Label] = label
+	}
+
+	// Each rule's tag goes between its match and its action.
+	type ingressRule struct {
+		match  string
+		action string
+	}
+
+	var rules []ingressRule
+	balance := func(match string, dsts []string) {
+		for i, dst := range dsts {
+			action := fmt.Sprintf(" -j DNAT --to-destination %s", dst)
+			if n := len(dsts) - i; n > 1 {
+				action = fmt.Sprintf(" -m statistic --mode nth "+
+					"--every %d --packet 0", n) + action
+			}
+			rules = append(rules, ingressRule{match, action})
+		}
+	}
+
+	// The slices are shared with other goroutines, so sort copies of them.
+	connections = append([]db.Connection(nil), connections...)
+	sort.Sort(db.ConnectionSlice(connections))
+	for _, conn := range connections {
+		if conn.From != stitch.PublicInternetLabel || !conn.Ingress {
+			continue
+		}
+
+		label := labelMap[conn.To]
+		unhealthy := map[string]struct{}{}
+		for _, ip := range label.UnhealthyIPs {
+			unhealthy[ip] = struct{}{}
+		}
+
+		containerPort := conn.MinPort
+		if conn.ContainerPort != 0 {
+			containerPort = conn.ContainerPort
+		}
+
+		var local []string
+		for _, dbc := range containers {
+			_, sick := unhealthy[dbc.IP]
+			if !sick && contains(dbc.Labels, conn.To) {
+				local = append(local, fmt.Sprintf("%s:%d", dbc.IP,
+					containerPort))
+			}
+		}
+		sort.Strings(local)
+
+		dsts := append([]string(nil), local...)
+		for _, minion := range label.HealthyMinions {
+			if minion != self {
+				dsts = append(dsts, fmt.Sprintf("%s:%d", minion,
+					conn.MinPort))
+			}
+		}
+
+		for _, p := range stitch.PortProtocols(conn.Protocol) {
+			match := fmt.Sprintf("-i %[1]s -p %[2]s -m %[2]s --dport %[3]d",
+				publicInterface, p, conn.MinPort)
+
+			if len(local) > 0 {
+				for _, peer := range peers {
+					balance(fmt.Sprintf("-A PREROUTING -s %s/32 %s",
+						peer, match), local)
+				}
+			}
+			balance("-A PREROUTING "+match, dsts)
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	hash := fnv.New32a()
+	for _, rule := range rules {
+		hash.Write([]byte(rule.match + rule.action + "\n"))
+	}
+	tag := fmt.Sprintf(" -m comment --comment quilt-ingress-%08x", hash.Sum32())
+
+	// Connections that are forwarded to another minion must return through this one.
+	strRules := []string{fmt.Sprintf(
+		"-A POSTROUTING -o %s -m conntrack --ctstate DNAT -j MASQUERADE",
+		publicInterface)}
+	for _, rule := range rules {
+		strRules = append(strRules, rule.match+tag+rule.action)
+	}
+	return strRules
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
 // There certain exceptions, as certain ports will never be deleted.
 func updatePorts(odb ovsdb.Client, containers []db.Container) {
 	// An Open vSwitch patch port is referred to as a "port".
@@ -280,15 +423,22 @@ func generateTargetPorts(containers []db.Container) ovsdb.InterfaceSlice {
 	return configs
 }
 
-func updateOpenFlow(odb ovsdb.Client, containers []db.Container) {
+func updateOpenFlow(odb ovsdb.Client, containers []db.Container, labels []db.Label) {
 	ifaces, err := odb.ListInterfaces()
 	if err != nil {
 		log.WithError(err).Error("failed to list OVS interfaces")
 		return
 	}
 
-	err = ofctlReplaceFlows(generateOpenFlow(generateOFPorts(ifaces, containers)))
-	if err != nil {
+	// The groups must exist before the flows that refer to them are installed.
+	lbs := generateLoadBalancers(labels)
+	if err := ofctlReplace("replace-groups", generateGroups(lbs)); err != nil {
+		log.WithError(err).Error("error replacing OpenFlow groups")
+		return
+	}
+
+	ofps := generateOFPorts(ifaces, containers)
+	if err := ofctlReplace("replace-flows", generateOpenFlow(ofps, lbs)); err != nil {
 		log.WithError(err).Error("error replacing OpenFlow")
 		return
 	}
@@ -326,6 +476,34 @@ func generateOFPorts(ifaces []ovsdb.Interface, dbcs []db.Container) []ofPort {
 	return ofcs
 }
 
+// generateLoadBalancers returns a load balancer for each label with an IP, ordered by
+// label so that the generated flows are deterministic.
+func generateLoadBalancers(labels []db.Label) []loadBalancer {
+	labels = append([]db.Label(nil), labels...)
+	sort.Sort(db.LabelSlice(labels))
+
+	var lbs []loadBalancer
+	for _, label := range labels {
+		if label.IP == "" {
+			continue
+		}
+
+		unhealthy := map[string]struct{}{}
+		for _, ip := range label.UnhealthyIPs {
+			unhealthy[ip] = struct{}{}
+		}
+
+		lb := loadBalancer{IP: label.IP}
+		for _, ip := range label.ContainerIPs {
+			if _, ok := unhealthy[ip]; !ok {
+				lb.Backends = append(lb.Backends, ip)
+			}
+		}
+		lbs = append(lbs, lb)
+	}
+	return lbs
+}
+
 func patchPorts(id string) (br, quilt string) {
 	return ipdef.IFName("br_" + id), ipdef.IFName("q_" + id)
 }
@@ -437,9 +615,10 @@ func getPublicInterface() (string, error) {
 	return link.Attrs().Name, err
 }
 
-func ofctlReplaceFlows(flows []string) error {
-	cmd := exec.Command("ovs-ofctl", "-O", "OpenFlow13", "replace-flows",
-		quiltBridge, "-")
+// ofctlReplace runs the ovs-ofctl 'command', such as replace-flows, on the quilt bridge
+// with 'lines' as its input.
+func ofctlReplace(command string, lines []string) error {
+	cmd := exec.Command("ovs-ofctl", "-O", "OpenFlow13", command, quiltBridge, "-")
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -450,8 +629,8 @@ func ofctlReplaceFlows(flows []string) error {
 		return err
 	}
 
-	for _, f := range flows {
-		stdin.Write([]byte(f + "\n"))
+	for _, line := range lines {
+		stdin.Write([]byte(line + "\n"))
 	}
 	stdin.Close()
 
@@ -1,10 +1,17 @@
 package network
 
 import (
+	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
 )
 
 func TestMakeIPRule(t *testing.T) {
@@ -158,3 +165,140 @@ func rules() string {
 -A POSTROUTING -s 11.0.0.0/8,10.0.0.0/8 -o eth0 -j MASQUERADE
 -A POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE`
 }
+
+func TestGenerateTargetNatRules(t *testing.T) {
+	containers := []db.Container{
+		{IP: "10.0.0.2", Labels: []string{"dns"}},
+		{IP: "10.0.0.3", Labels: []string{"web"}},
+	}
+	connections := []db.Connection{
+		{From: stitch.PublicInternetLabel, To: "dns", MinPort: 53, MaxPort: 53,
+			Protocol: stitch.UDP},
+		{From: stitch.PublicInternetLabel, To: "dns", Protocol: stitch.ICMP},
+		{From: stitch.PublicInternetLabel, To: "web", MinPort: 80, MaxPort: 80},
+		{From: stitch.PublicInternetLabel, To: "web", MinPort: 8080,
+			MaxPort: 8080, ContainerPort: 80, Protocol: stitch.TCP},
+	}
+
+	// Only UDP is forwarded to the DNS container, and ICMP isn't forwarded.  Port
+	// 8080 of the host is forwarded to port 80 of the web container.
+	var dnat []string
+	rules := generateTargetNatRules("eth0", containers, connections, nil, nil)
+	for _, rule := range rules {
+		if strings.Contains(rule.opts, "DNAT") {
+			dnat = append(dnat, rule.opts)
+		}
+	}
+	sort.Strings(dnat)
+
+	assert.Equal(t, []string{
+		"-i eth0 -p tcp -m tcp --dport 80 -j DNAT --to-destination 10.0.0.3:80",
+		"-i eth0 -p tcp -m tcp --dport 8080 -j DNAT --to-destination 10.0.0.3:80",
+		"-i eth0 -p udp -m udp --dport 53 -j DNAT --to-destination 10.0.0.2:53",
+		"-i eth0 -p udp -m udp --dport 80 -j DNAT --to-destination 10.0.0.3:80",
+	}, dnat)
+}
+
+func TestGenerateIngressRules(t *testing.T) {
+	t.Parallel()
+
+	containers := []db.Container{
+		{IP: "10.0.0.3", Labels: []string{"web"}},
+		{IP: "10.0.0.2", Labels: []string{"web"}},
+		{IP: "10.0.0.4", Labels: []string{"web"}},
+	}
+	connections := []db.Connection{
+		{From: stitch.PublicInternetLabel, To: "web", MinPort: 80, MaxPort: 80,
+			ContainerPort: 8080, Protocol: stitch.TCP, Ingress: true},
+		{From: stitch.PublicInternetLabel, To: "web", MinPort: 443,
+			MaxPort: 443, Protocol: stitch.TCP},
+	}
+	labels := []db.Label{{
+		Label:          "web",
+		ContainerIPs:   []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		UnhealthyIPs:   []string{"10.0.0.4"},
+		HealthyMinions: []string{"1.1.1.1", "1.1.1.1", "2.2.2.2", "2.2.2.2"},
+	}}
+	minions := []db.Minion{
+		{PrivateIP: "1.1.1.1", Role: db.Worker, Self: true},
+		{PrivateIP: "2.2.2.2", Role: db.Worker},
+		{PrivateIP: "3.3.3.3", Role: db.Worker},
+		{PrivateIP: "4.4.4.4", Role: db.Master},
+	}
+
+	// Connections are balanced across the healthy local containers and those of
+	// the other minion running the label, while connections forwarded by the other
+	// workers stay local.
+	rules := generateIngressRules("eth0", containers, connections, labels, minions)
+	assert.Len(t, rules, 9)
+	assert.Equal(t,
+		"-A POSTROUTING -o eth0 -m conntrack --ctstate DNAT -j MASQUERADE",
+		rules[0])
+
+	tag := regexp.MustCompile(" -m comment --comment quilt-ingress-[0-9a-f]{8}")
+	for _, rule := range rules[1:] {
+		assert.Regexp(t, tag, rule)
+	}
+
+	match := "-i eth0 -p tcp -m tcp --dport 80"
+	nth := func(n int) string {
+		return fmt.Sprintf(" -m statistic --mode nth --every %d --packet 0", n)
+	}
+	dnat := " -j DNAT --to-destination "
+
+	var untagged []string
+	for _, rule := range rules[1:] {
+		untagged = append(untagged, tag.ReplaceAllString(rule, ""))
+	}
+	assert.Equal(t, []string{
+		"-A PREROUTING -s 2.2.2.2/32 " + match + nth(2) + dnat + "10.0.0.2:8080",
+		"-A PREROUTING -s 2.2.2.2/32 " + match + dnat + "10.0.0.3:8080",
+		"-A PREROUTING -s 3.3.3.3/32 " + match + nth(2) + dnat + "10.0.0.2:8080",
+		"-A PREROUTING -s 3.3.3.3/32 " + match + dnat + "10.0.0.3:8080",
+		"-A PREROUTING " + match + nth(4) + dnat + "10.0.0.2:8080",
+		"-A PREROUTING " + match + nth(3) + dnat + "10.0.0.3:8080",
+		"-A PREROUTING " + match + nth(2) + dnat + "2.2.2.2:80",
+		"-A PREROUTING " + match + dnat + "2.2.2.2:80",
+	}, untagged)
+
+	// A minion without healthy containers of its own forwards every connection.
+	labels[0].UnhealthyIPs = []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	labels[0].HealthyMinions = []string{"2.2.2.2"}
+	newRules := generateIngressRules("eth0", containers, connections, labels,
+		minions)
+	assert.Len(t, newRules, 2)
+	assert.Equal(t, "-A PREROUTING "+match+dnat+"2.2.2.2:80",
+		tag.ReplaceAllString(newRules[1], ""))
+
+	// Any change to the rules changes all of their tags.
+	assert.NotEqual(t, tag.FindString(rules[1]), tag.FindString(newRules[1]))
+
+	assert.Nil(t, generateIngressRules("eth0", containers, connections[1:], labels,
+		minions))
+}
+
+func TestGenerateLoadBalancers(t *testing.T) {
+	t.Parallel()
+
+	labels := []db.Label{{
+		Label:        "red",
+		IP:           "10.0.0.9",
+		ContainerIPs: []string{"10.0.0.2", "10.0.0.3"},
+		UnhealthyIPs: []string{"10.0.0.2"},
+	}, {
+		Label:        "blue",
+		IP:           "10.0.0.8",
+		ContainerIPs: []string{"10.0.0.2", "10.0.0.4"},
+	}, {
+		Label:        "yellow",
+		ContainerIPs: []string{"10.0.0.5"},
+	}}
+	lbs := generateLoadBalancers(labels)
+	assert.Equal(t, []loadBalancer{
+		{IP: "10.0.0.8", Backends: []string{"10.0.0.2", "10.0.0.4"}},
+		{IP: "10.0.0.9", Backends: []string{"10.0.0.3"}},
+	}, lbs)
+
+	// The caller's slice is shared with the NAT goroutine, so it's left alone.
+	assert.Equal(t, "red", labels[0].Label)
+}
@@ -6,9 +6,11 @@ import (
 
 	"github.com/NetSys/quilt/api"
 	apiServer "github.com/NetSys/quilt/api/server"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/docker"
 	"github.com/NetSys/quilt/minion/etcd"
+	"github.com/NetSys/quilt/minion/health"
 	"github.com/NetSys/quilt/minion/network"
 	"github.com/NetSys/quilt/minion/network/plugin"
 	"github.com/NetSys/quilt/minion/pprofile"
@@ -19,8 +21,11 @@ import (
 	log "github.com/Sirupsen/logrus"
 )
 
-// Run blocks executing the minion.
-func Run() {
+// Run blocks executing the minion.  If 'httpAddr' is non-empty, the HTTP API is
+// served at it.  If 'creds' isn't empty, the minion only accepts connections from
+// peers with credentials signed by the same CA.  The minion's DNS server forwards
+// queries outside of Quilt to 'dnsUpstreams', or to the host's resolvers if it's empty.
+func Run(httpAddr string, creds auth.Credentials, dnsUpstreams []string) {
 	// XXX Uncomment the following line to run the profiler
 	//runProfiler(5 * time.Minute)
 
@@ -32,14 +37,24 @@ func Run() {
 	// Not in a goroutine, want the plugin to start before the scheduler
 	plugin.Run()
 
-	go minionServerRun(conn)
+	go minionServerRun(conn, creds)
 	go supervisor.Run(conn, dk)
 	go scheduler.Run(conn, dk)
-	go network.Run(conn)
+	go health.Run(conn, dk)
+	go network.Run(conn, dnsUpstreams)
 	go etcd.Run(conn)
 	go syncAuthorizedKeys(conn)
 
-	go apiServer.Run(conn, fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort))
+	go apiServer.Run(conn, fmt.Sprintf("tcp://0.0.0.0:%d", api.DefaultRemotePort),
+		creds)
+	if httpAddr != "" {
+		go func() {
+			err := apiServer.RunHTTP(conn, httpAddr, creds)
+			if err != nil {
+				log.WithError(err).Error("Failed to serve the HTTP API.")
+			}
+		}()
+	}
 
 	loopLog := util.NewEventTimer("Minion-Update")
 
@@ -47,6 +62,7 @@ func Run() {
 		loopLog.LogStart()
 		txn := conn.Txn(db.ConnectionTable, db.ContainerTable, db.MinionTable,
 			db.EtcdTable, db.PlacementTable)
+		txn = txn.WithReason("minion: update policy")
 		txn.Run(func(view db.Database) error {
 			minion, err := view.MinionSelf()
 			if err == nil && view.EtcdLeader() {
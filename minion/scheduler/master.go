@@ -1,11 +1,15 @@
 package scheduler
 
 import (
-	"container/heap"
 	"fmt"
+	"math"
+	"reflect"
 	"sort"
+	"time"
 
+	"github.com/NetSys/quilt/cluster/machine"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/NetSys/quilt/util"
 	log "github.com/Sirupsen/logrus"
 )
@@ -16,73 +20,484 @@ type minion struct {
 }
 
 type context struct {
+	policy      policy
+	logger      log.FieldLogger
+	budgets     map[string]int
 	minions     []*minion
 	constraints []db.Placement
 	unassigned  []*db.Container
 	changed     []*db.Container
 }
 
-func runMaster(conn db.Conn) {
-	conn.Txn(db.ContainerTable, db.EtcdTable, db.MinionTable,
-		db.PlacementTable).Run(func(view db.Database) error {
-
+func runMaster(conn db.Conn, moved migrations) {
+	txn := conn.Txn(db.ContainerTable, db.EtcdTable, db.MinionTable,
+		db.PlacementTable).WithReason("scheduler: place containers")
+	txn.Run(func(view db.Database) error {
 		if view.EtcdLeader() {
-			placeContainers(view)
+			placeContainers(view, moved)
 		}
 		return nil
 	})
 }
 
-func placeContainers(view db.Database) {
+func placeContainers(view db.Database, moved migrations) {
 	constraints := view.SelectFromPlacement(nil)
 	containers := view.SelectFromContainer(nil)
 	minions := view.SelectFromMinion(nil)
 
 	ctx := makeContext(minions, constraints, containers)
+	if self, err := view.MinionSelf(); err == nil {
+		ctx.policy = getPolicy(self.Spec)
+		ctx.budgets = disruptionBudgets(self.Spec)
+	}
+
 	cleanupPlacements(ctx)
 	placeUnassigned(ctx)
+	rebalance(ctx, moved, time.Now())
 
 	for _, change := range ctx.changed {
 		view.Commit(*change)
 	}
 }
 
-// Unassign all containers that are placed incorrectly.
+// Unassign all containers that are placed incorrectly.  Containers with volumes are
+// only placed again on the minion that holds their data, as found by volumeMinion.
 func cleanupPlacements(ctx *context) {
 	for _, m := range ctx.minions {
 		var valid []*db.Container
 		for _, dbc := range m.containers {
-			if validPlacement(ctx.constraints, *m, valid, dbc) {
+			if fits(*m, valid, dbc) &&
+				validPlacement(ctx.constraints, *m, valid, dbc) {
 				valid = append(valid, dbc)
 				continue
 			}
-			dbc.Minion = ""
-			ctx.unassigned = append(ctx.unassigned, dbc)
-			ctx.changed = append(ctx.changed, dbc)
+			ctx.unassign(dbc)
+		}
+
+		// A container's affinity may depend on any of its peers, including
+		// those later in the list or just unassigned, so it's checked once the
+		// rest are settled.
+		for evicted := true; evicted; {
+			evicted = false
+			var kept []*db.Container
+			for _, dbc := range valid {
+				if validAffinity(ctx.constraints, valid, dbc) {
+					kept = append(kept, dbc)
+					continue
+				}
+				ctx.unassign(dbc)
+				evicted = true
+			}
+			valid = kept
 		}
 		m.containers = valid
 	}
 }
 
+func (ctx *context) unassign(dbc *db.Container) {
+	if len(dbc.Volumes) > 0 {
+		dbc.VolumeMinion = dbc.Minion
+	}
+	dbc.Minion = ""
+	ctx.unassigned = append(ctx.unassigned, dbc)
+	ctx.changed = append(ctx.changed, dbc)
+}
+
+// placeUnassigned places containers in priority order.  Critical containers that
+// don't fit evict containers of lower priority, which are then placed wherever there
+// is still room for them.
 func placeUnassigned(ctx *context) {
-	minions := minionHeap(ctx.minions)
-	heap.Init(&minions)
-
-Outer:
-	for _, dbc := range ctx.unassigned {
-		for i, m := range minions {
-			if validPlacement(ctx.constraints, *m, m.containers, dbc) {
-				dbc.Minion = m.PrivateIP
-				ctx.changed = append(ctx.changed, dbc)
-				m.containers = append(m.containers, dbc)
-				heap.Fix(&minions, i)
-				log.WithField("container", dbc).Info("Placed container.")
-				continue Outer
+	minions := ctx.minions
+
+	// Containers are placed by priority, then by the size of their reservations,
+	// as described by dbcSlice.
+	sort.Sort(dbcSlice(ctx.unassigned))
+	unassigned := placeAll(ctx, minions, ctx.unassigned)
+
+	critical := stitch.PriorityLevel(stitch.PriorityCritical)
+	var remaining, evicted []*db.Container
+	for _, dbc := range unassigned {
+		if stitch.PriorityLevel(dbc.Priority) < critical {
+			remaining = append(remaining, dbc)
+			continue
+		}
+
+		victims, ok := preempt(ctx, minions, dbc)
+		if !ok {
+			remaining = append(remaining, dbc)
+			continue
+		}
+		evicted = append(evicted, victims...)
+	}
+
+	sort.Sort(dbcSlice(evicted))
+	remaining = append(remaining, placeAll(ctx, minions, evicted)...)
+
+	for _, dbc := range remaining {
+		recordPlacementErrors(ctx, minions, dbc)
+	}
+}
+
+// recordPlacementErrors records why each minion can't accept 'dbc', so that users can
+// find out why it isn't running.
+func recordPlacementErrors(ctx *context, minions []*minion, dbc *db.Container) {
+	errs := map[string][]string{}
+	pinned := volumeMinion(ctx, dbc)
+	for _, m := range minions {
+		errs[m.PrivateIP] = ctx.policy.filter(ctx.constraints, minions, m, dbc,
+			pinned)
+	}
+
+	if len(errs) == 0 {
+		errs = nil
+	}
+
+	if !reflect.DeepEqual(errs, dbc.PlacementErrors) {
+		dbc.PlacementErrors = errs
+		ctx.changed = append(ctx.changed, dbc)
+	}
+
+	ctx.logger.WithFields(log.Fields{
+		"container": dbc,
+		"reasons":   errs,
+	}).Warning("Failed to place container.")
+}
+
+// placeAll places containers in order until none of those left can be placed, and
+// returns those that remain.  Placing a container may allow those that must run
+// alongside it to be placed, so the remaining containers are retried.
+func placeAll(ctx *context, minions []*minion,
+	unassigned []*db.Container) []*db.Container {
+
+	for placed := true; placed; {
+		placed = false
+		var remaining []*db.Container
+		for _, dbc := range unassigned {
+			i := chooseMinion(ctx, minions, dbc)
+			if i < 0 {
+				remaining = append(remaining, dbc)
+				continue
+			}
+
+			place(ctx, minions, i, dbc)
+			placed = true
+		}
+		unassigned = remaining
+
+		// Containers whose affinity rules require each other can't be placed one
+		// at a time, so once nothing else can be placed, they're tried together.
+		for i := 0; !placed && i < len(unassigned); i++ {
+			group := placeGroup(ctx, minions, unassigned[i], unassigned)
+			unassigned = without(unassigned, group)
+			placed = len(group) > 0
+		}
+	}
+	return unassigned
+}
+
+// placeGroup places 'dbc' along with the containers of 'unassigned' it needs to satisfy
+// its affinity rules, and those they need in turn, on the minion with the highest
+// score that accepts all of them.  It returns the containers placed, or nil if no
+// minion accepts them.
+func placeGroup(ctx *context, minions []*minion, dbc *db.Container,
+	unassigned []*db.Container) []*db.Container {
+
+	best := -1
+	var bestGroup []*db.Container
+	var bestScore float64
+	for i, m := range minions {
+		group := affinityGroup(ctx.constraints, m, dbc, unassigned)
+		if len(group) < 2 || !acceptsGroup(ctx, minions, m, group) {
+			continue
+		}
+
+		score := ctx.policy.score(m, dbc)
+		if best < 0 || score > bestScore {
+			best, bestGroup, bestScore = i, group, score
+		}
+	}
+
+	if best < 0 {
+		return nil
+	}
+
+	for _, member := range bestGroup {
+		place(ctx, minions, best, member)
+	}
+	return bestGroup
+}
+
+// affinityGroup returns 'dbc' along with the containers of 'unassigned' that would give
+// it, and each other, the peers their affinity rules require if placed on 'm'.  It
+// returns nil if 'unassigned' lacks a container that's required.
+func affinityGroup(constraints []db.Placement, m *minion, dbc *db.Container,
+	unassigned []*db.Container) []*db.Container {
+
+	group := []*db.Container{dbc}
+	for i := 0; i < len(group); i++ {
+		for {
+			peers := append(append([]*db.Container(nil), m.containers...),
+				group...)
+			unmet := unmetAffinity(constraints, peers, group[i])
+			if len(unmet) == 0 {
+				break
+			}
+
+			var partner *db.Container
+			for _, other := range without(unassigned, group) {
+				if hasLabel(other, unmet[0].OtherLabel) {
+					partner = other
+					break
+				}
+			}
+
+			if partner == nil {
+				return nil
+			}
+			group = append(group, partner)
+		}
+	}
+	return group
+}
+
+// acceptsGroup returns true if the policy allows each container of 'group' to run on
+// 'm' alongside the rest of the group.
+func acceptsGroup(ctx *context, minions []*minion, m *minion,
+	group []*db.Container) bool {
+
+	peers := m.containers
+	defer func() { m.containers = peers }()
+	for _, dbc := range group {
+		m.containers = append(append([]*db.Container(nil), peers...),
+			without(group, []*db.Container{dbc})...)
+		pinned := volumeMinion(ctx, dbc)
+		if len(ctx.policy.filter(ctx.constraints, minions, m, dbc, pinned)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func place(ctx *context, minions []*minion, i int, dbc *db.Container) {
+	m := minions[i]
+	dbc.Minion = m.PrivateIP
+	dbc.Evicted = ""
+	dbc.PlacementErrors = nil
+	ctx.changed = append(ctx.changed, dbc)
+	m.containers = append(m.containers, dbc)
+	ctx.logger.WithField("container", dbc).Info("Placed container.")
+}
+
+// preempt places 'dbc' on the minion that requires the fewest evictions to fit it,
+// and returns the containers evicted to make room.  It returns false if there is no
+// minion on which 'dbc' could be placed even after evicting all of its containers of
+// lower priority.
+func preempt(ctx *context, minions []*minion, dbc *db.Container) ([]*db.Container,
+	bool) {
+
+	best := -1
+	var bestVictims []*db.Container
+	for i, m := range minions {
+		victims, ok := preemptionVictims(ctx, minions, m, dbc)
+		if ok && (best < 0 || len(victims) < len(bestVictims)) {
+			best, bestVictims = i, victims
+		}
+	}
+
+	if best < 0 {
+		return nil, false
+	}
+
+	m := minions[best]
+	m.containers = without(m.containers, bestVictims)
+	reason := fmt.Sprintf("evicted from %s for %s container %s", m.PrivateIP,
+		dbc.Priority, dbc.StitchID)
+	for _, victim := range bestVictims {
+		victim.Evicted = reason
+		ctx.unassign(victim)
+		ctx.logger.WithFields(log.Fields{
+			"container": victim,
+			"preemptor": dbc,
+		}).Warning("Evicted container.")
+	}
+
+	place(ctx, minions, best, dbc)
+	return bestVictims, true
+}
+
+// preemptionVictims returns the fewest containers of lower priority than 'dbc', taking
+// those of the lowest priority first, that must be evicted from 'm' for 'dbc' to be
+// placed there.  Containers with volumes are never evicted, as their data would be
+// left behind.
+func preemptionVictims(ctx *context, minions []*minion, m *minion,
+	dbc *db.Container) ([]*db.Container, bool) {
+
+	pinned := volumeMinion(ctx, dbc)
+	if pinned != nil && pinned != m {
+		return nil, false
+	}
+
+	level := stitch.PriorityLevel(dbc.Priority)
+	var candidates []*db.Container
+	for _, peer := range m.containers {
+		if len(peer.Volumes) == 0 && stitch.PriorityLevel(peer.Priority) < level {
+			candidates = append(candidates, peer)
+		}
+	}
+	sort.Stable(byPriority(candidates))
+
+	peers := m.containers
+	defer func() { m.containers = peers }()
+	for n := 0; n <= len(candidates); n++ {
+		m.containers = without(peers, candidates[:n])
+		errs := ctx.policy.filter(ctx.constraints, minions, m, dbc, pinned)
+		if len(errs) == 0 {
+			return candidates[:n], true
+		}
+	}
+	return nil, false
+}
+
+// without returns the containers in 'dbcs' that aren't in 'remove'.
+func without(dbcs, remove []*db.Container) []*db.Container {
+	var result []*db.Container
+	for _, dbc := range dbcs {
+		removed := false
+		for _, r := range remove {
+			removed = removed || r == dbc
+		}
+
+		if !removed {
+			result = append(result, dbc)
+		}
+	}
+	return result
+}
+
+// chooseMinion returns the index of the minion that passes the policy's filter with
+// the highest score, the first of them if tied, or -1 if no minion can accept 'dbc'.
+func chooseMinion(ctx *context, minions []*minion, dbc *db.Container) int {
+	pinned := volumeMinion(ctx, dbc)
+
+	best := -1
+	var bestScore float64
+	for i, m := range minions {
+		errs := ctx.policy.filter(ctx.constraints, minions, m, dbc, pinned)
+		if len(errs) > 0 {
+			continue
+		}
+
+		score := ctx.policy.score(m, dbc)
+		if best < 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// placeErrors returns the reasons that 'dbc' may not run on 'm' alongside the
+// containers already placed there, or nil if it may.  Containers pinned to a minion by
+// their volumes, as found by volumeMinion, may only run there, and aren't spread.
+func placeErrors(constraints []db.Placement, minions []*minion, m *minion,
+	dbc *db.Container, pinned *minion) []string {
+
+	if pinned != nil && pinned != m {
+		return []string{fmt.Sprintf("volumes are on minion %s", pinned.PrivateIP)}
+	}
+
+	if m.Draining {
+		return []string{"minion is draining"}
+	}
+
+	peers := m.containers
+	errs := fitErrors(*m, peers, dbc)
+	ruleErrs := placementErrors(constraints, *m, peers, dbc)
+	ruleErrs = append(ruleErrs, affinityErrors(constraints, peers, dbc)...)
+	if pinned != nil {
+		// Containers sharing a volume must run together, so they're exempt
+		// from spread rules, but not from the rest.
+		if len(ruleErrs) > 0 {
+			errs = append(errs, fmt.Sprintf("volumes are on minion %s, "+
+				"which conflicts with the placement rules",
+				pinned.PrivateIP))
+		}
+		return append(errs, ruleErrs...)
+	}
+
+	errs = append(errs, ruleErrs...)
+	return append(errs, spreadErrors(constraints, minions, m, dbc)...)
+}
+
+// fits returns true if 'dbc' can run on 'm' alongside 'peers' without reserving more
+// CPU or RAM than the minion has.
+func fits(m minion, peers []*db.Container, dbc *db.Container) bool {
+	return len(fitErrors(m, peers, dbc)) == 0
+}
+
+func fitErrors(m minion, peers []*db.Container, dbc *db.Container) []string {
+	// Allow for the rounding error of summing fractional reservations.
+	const epsilon = 1e-9
+
+	var errs []string
+	cpu, ram := spareCapacity(m, peers)
+	if dbc.CPU > cpu+epsilon {
+		errs = append(errs, fmt.Sprintf("needs %.3g CPUs, but %.3g are free",
+			dbc.CPU, cpu))
+	}
+
+	if dbc.RAM > ram+epsilon {
+		errs = append(errs, fmt.Sprintf("needs %.3gGB of RAM, but %.3gGB is free",
+			dbc.RAM, ram))
+	}
+	return errs
+}
+
+// spareCapacity returns the cores and gigabytes of RAM of 'm' that aren't reserved by
+// 'peers'.  Minions of unknown size are assumed to have unlimited capacity.
+func spareCapacity(m minion, peers []*db.Container) (cpu, ram float64) {
+	desc, ok := machine.Lookup(db.Provider(m.Provider), m.Size)
+	if !ok {
+		return math.Inf(1), math.Inf(1)
+	}
+
+	cpu, ram = float64(desc.CPU), desc.RAM
+	for _, peer := range peers {
+		cpu -= peer.CPU
+		ram -= peer.RAM
+	}
+	return cpu, ram
+}
+
+// volumeMinion returns the minion holding the data of the volumes 'dbc' mounts, as the
+// data of local volumes can't follow containers to other minions.  That's the minion
+// running a container that mounts the same volume, or failing that, the VolumeMinion
+// of 'dbc' or of an unassigned container that does.  It returns nil if there is no
+// such minion.
+func volumeMinion(ctx *context, dbc *db.Container) *minion {
+	if len(dbc.Volumes) == 0 {
+		return nil
+	}
+
+	for _, m := range ctx.minions {
+		for _, peer := range m.containers {
+			if db.SharesVolume(*dbc, *peer) {
+				return m
 			}
 		}
+	}
+
+	for _, other := range append([]*db.Container{dbc}, ctx.unassigned...) {
+		if other != dbc && !db.SharesVolume(*dbc, *other) {
+			continue
+		}
 
-		log.WithField("container", dbc).Warning("Failed to place container.")
+		for _, m := range ctx.minions {
+			if other.VolumeMinion != "" && m.PrivateIP == other.VolumeMinion {
+				return m
+			}
+		}
 	}
+	return nil
 }
 
 // Compute the peer labels map if it is nil, otherwise just return it
@@ -119,16 +534,12 @@ func validExclusion(target, other string, cLabels, pLabels map[string]struct{})
 	return tValid && oValid
 }
 
+// Inclusive label constraints are checked by validAffinity, as they depend on every
+// container placed on the minion.
 func checkExclusionConstraint(constraint db.Placement, cLabels,
 	pLabels map[string]struct{}) bool {
 
 	if !constraint.Exclusive {
-		// XXX: Inclusive OtherLabel is hard because we can't
-		// make placement decisions without considering all the
-		// containers on all of the minions.
-		log.WithField("constraint", constraint).Warning(
-			"Quilt currently does not support inclusive" +
-				" label placement constraints")
 		return true
 	}
 
@@ -136,21 +547,155 @@ func checkExclusionConstraint(constraint db.Placement, cLabels,
 		cLabels, pLabels)
 }
 
+// validAffinity returns true if 'peers' include a container of each label that 'dbc'
+// must be placed with.
+func validAffinity(constraints []db.Placement, peers []*db.Container,
+	dbc *db.Container) bool {
+	return len(affinityErrors(constraints, peers, dbc)) == 0
+}
+
+func affinityErrors(constraints []db.Placement, peers []*db.Container,
+	dbc *db.Container) []string {
+
+	var errs []string
+	for _, constraint := range unmetAffinity(constraints, peers, dbc) {
+		errs = append(errs, fmt.Sprintf("label %s must run with label %s",
+			constraint.TargetLabel, constraint.OtherLabel))
+	}
+	return errs
+}
+
+// unmetAffinity returns the constraints requiring 'dbc' to run with a label that none
+// of 'peers' have.
+func unmetAffinity(constraints []db.Placement, peers []*db.Container,
+	dbc *db.Container) []db.Placement {
+
+	var unmet []db.Placement
+	var peerLabels map[string]struct{}
+	for _, constraint := range constraints {
+		if constraint.Exclusive || constraint.OtherLabel == "" {
+			continue
+		}
+
+		if !hasLabel(dbc, constraint.TargetLabel) {
+			continue
+		}
+
+		peerLabels = computePeerLabels(peerLabels, peers, dbc.ID)
+		if _, ok := peerLabels[constraint.OtherLabel]; !ok {
+			unmet = append(unmet, constraint)
+		}
+	}
+	return unmet
+}
+
+// spreadErrors returns an error for each label that must be spread, whose containers
+// would exceed their maximum skew across the failure domains of 'minions' if 'dbc'
+// were placed on 'm'.  Only the domains with a minion that 'dbc' could run on count,
+// as the others can never catch up.
+func spreadErrors(constraints []db.Placement, minions []*minion, m *minion,
+	dbc *db.Container) []string {
+
+	var errs []string
+
+	for _, constraint := range constraints {
+		if constraint.SpreadAcross == "" ||
+			!hasLabel(dbc, constraint.TargetLabel) {
+			continue
+		}
+
+		domain := spreadDomain(constraint.SpreadAcross, m.Minion)
+		counts := map[string]int{domain: 0}
+		for _, other := range minions {
+			d := spreadDomain(constraint.SpreadAcross, other.Minion)
+			if !other.Draining && validPlacement(constraints, *other,
+				other.containers, dbc) {
+				counts[d] = 0
+			}
+		}
+
+		for _, other := range minions {
+			d := spreadDomain(constraint.SpreadAcross, other.Minion)
+			if _, ok := counts[d]; !ok {
+				continue
+			}
+
+			for _, peer := range other.containers {
+				if peer != dbc && hasLabel(peer, constraint.TargetLabel) {
+					counts[d]++
+				}
+			}
+		}
+
+		min := -1
+		for _, count := range counts {
+			if min < 0 || count < min {
+				min = count
+			}
+		}
+
+		maxSkew := constraint.MaxSkew
+		if maxSkew < 1 {
+			maxSkew = 1
+		}
+
+		if counts[domain]+1-min > maxSkew {
+			errs = append(errs, fmt.Sprintf("label %s must be spread across "+
+				"each %s with a maximum skew of %d",
+				constraint.TargetLabel, constraint.SpreadAcross, maxSkew))
+		}
+	}
+	return errs
+}
+
+// spreadDomain returns the failure domain, of the kind named by 'key', that 'm' is in.
+func spreadDomain(key string, m db.Minion) string {
+	switch key {
+	case stitch.SpreadProvider:
+		return m.Provider
+	case stitch.SpreadRegion:
+		return m.Provider + "/" + m.Region
+	default:
+		return m.PrivateIP
+	}
+}
+
+func hasLabel(dbc *db.Container, label string) bool {
+	for _, l := range dbc.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// validPlacement returns true if the placement rules allow 'dbc' to run on 'm'
+// alongside 'peers'.
 func validPlacement(constraints []db.Placement, m minion, peers []*db.Container,
 	dbc *db.Container) bool {
+	return len(placementErrors(constraints, m, peers, dbc)) == 0
+}
+
+// placementErrors returns the placement rules that forbid 'dbc' from running on 'm'
+// alongside 'peers'.
+func placementErrors(constraints []db.Placement, m minion, peers []*db.Container,
+	dbc *db.Container) []string {
 
 	cLabels := map[string]struct{}{}
 	for _, label := range dbc.Labels {
 		cLabels[label] = struct{}{}
 	}
 
+	var errs []string
 	var peerLabels map[string]struct{}
 	for _, constraint := range constraints {
 		if constraint.OtherLabel != "" {
 			peerLabels = computePeerLabels(peerLabels, peers, dbc.ID)
 			ok := checkExclusionConstraint(constraint, cLabels, peerLabels)
 			if !ok {
-				return false
+				errs = append(errs, fmt.Sprintf(
+					"label %s can't run with label %s",
+					constraint.TargetLabel, constraint.OtherLabel))
 			}
 		}
 
@@ -158,42 +703,34 @@ func validPlacement(constraints []db.Placement, m minion, peers []*db.Container,
 			continue
 		}
 
-		if constraint.Provider != "" {
-			on := constraint.Provider == m.Provider
-			if constraint.Exclusive == on {
-				return false
-			}
+		machineRules := []struct{ attr, rule, actual string }{
+			{"provider", constraint.Provider, m.Provider},
+			{"region", constraint.Region, m.Region},
+			{"size", constraint.Size, m.Size},
+			{"floating IP", constraint.FloatingIP, m.FloatingIP},
 		}
-
-		if constraint.Region != "" {
-			on := constraint.Region == m.Region
-			if constraint.Exclusive == on {
-				return false
-			}
-		}
-
-		if constraint.Size != "" {
-			on := constraint.Size == m.Size
-			if constraint.Exclusive == on {
-				return false
+		for _, mr := range machineRules {
+			on := mr.rule == mr.actual
+			if mr.rule == "" || constraint.Exclusive != on {
+				continue
 			}
-		}
 
-		if constraint.FloatingIP != "" {
-			on := constraint.FloatingIP == m.FloatingIP
-			if constraint.Exclusive == on {
-				return false
+			verb := "must"
+			if constraint.Exclusive {
+				verb = "can't"
 			}
+			errs = append(errs, fmt.Sprintf("label %s %s run on %s %s",
+				constraint.TargetLabel, verb, mr.attr, mr.rule))
 		}
 	}
 
-	return true
+	return errs
 }
 
 func makeContext(minions []db.Minion, constraints []db.Placement,
 	containers []db.Container) *context {
 
-	ctx := context{}
+	ctx := context{policy: spreadPolicy{}, logger: log.StandardLogger()}
 	ctx.constraints = constraints
 
 	ipMinion := map[string]*minion{}
@@ -223,33 +760,22 @@ func makeContext(minions []db.Minion, constraints []db.Placement,
 		minion.containers = append(minion.containers, dbc)
 	}
 
-	// XXX: We sort containers based on their image and command in an effort to
-	// encourage the scheduler to spread them out.  This is somewhat of a hack -- we
-	// need a more clever scheduler at some point.
-	sort.Sort(dbcSlice(ctx.unassigned))
-
 	return &ctx
 }
 
-// Minion Heap.  Minions are sorted based on the number of containers scheduled on them
-// with fewer containers being higher priority.
-type minionHeap []*minion
-
-func (mh minionHeap) Len() int      { return len(mh) }
-func (mh minionHeap) Swap(i, j int) { mh[i], mh[j] = mh[j], mh[i] }
-
-// We don't actually use Push and Pop and the moment.  See Heap docs if needed later.
-func (mh *minionHeap) Push(x interface{}) { panic("Not Reached") }
-func (mh *minionHeap) Pop() interface{}   { panic("Not Reached") }
-
-func (mh minionHeap) Less(i, j int) bool {
-	return len(mh[i].containers) < len(mh[j].containers)
-}
-
 type dbcSlice []*db.Container
 
+// Containers of the highest priority are placed first, followed by those with the
+// largest reservations, as bin-packing them onto the remaining space is hardest.
 func (s dbcSlice) Less(i, j int) bool {
+	pi, pj := stitch.PriorityLevel(s[i].Priority), stitch.PriorityLevel(s[j].Priority)
 	switch {
+	case pi != pj:
+		return pi > pj
+	case s[i].CPU != s[j].CPU:
+		return s[i].CPU > s[j].CPU
+	case s[i].RAM != s[j].RAM:
+		return s[i].RAM > s[j].RAM
 	case s[i].Image != s[j].Image:
 		return s[i].Image < s[j].Image
 	case !util.StrSliceEqual(s[i].Command, s[j].Command):
@@ -266,3 +792,18 @@ func (s dbcSlice) Swap(i, j int) {
 func (s dbcSlice) Len() int {
 	return len(s)
 }
+
+// byPriority sorts containers from lowest to highest priority.
+type byPriority []*db.Container
+
+func (s byPriority) Less(i, j int) bool {
+	return stitch.PriorityLevel(s[i].Priority) < stitch.PriorityLevel(s[j].Priority)
+}
+
+func (s byPriority) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s byPriority) Len() int {
+	return len(s)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
 )
@@ -29,7 +30,7 @@ func TestPlaceContainers(t *testing.T) {
 	})
 
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
-		placeContainers(view)
+		placeContainers(view, migrations{})
 		return nil
 	})
 
@@ -264,12 +265,27 @@ func TestPlaceUnassigned(t *testing.T) {
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
 
+	// The scheduler records why containers can't be placed.
 	placements[0].Exclusive = false
 	placements[0].Region = "Nowhere"
 	containers[0].Minion = ""
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+	assert.Len(t, ctx.changed, 1)
+
+	err := []string{"label 1 must run on region Nowhere"}
+	assert.Equal(t, map[string][]string{"1": err, "2": err, "3": err},
+		containers[0].PlacementErrors)
+
 	ctx = makeContext(minions, placements, containers)
 	placeUnassigned(ctx)
 	assert.Nil(t, ctx.changed)
+
+	placements[0].Region = "Region1"
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+	assert.Equal(t, "1", containers[0].Minion)
+	assert.Nil(t, containers[0].PlacementErrors)
 }
 
 func TestMakeContext(t *testing.T) {
@@ -632,3 +648,359 @@ func TestSort(t *testing.T) {
 func (m minion) String() string {
 	return spew.Sprintf("(%s Containers: %s)", m.Minion, m.containers)
 }
+
+func TestPlaceVolumes(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Region: "Region1", Role: db.Worker},
+		{PrivateIP: "2", Region: "Region2", Role: db.Worker},
+	}
+
+	data := []db.Volume{{Kind: "named", Source: "data", Target: "/data"}}
+	containers := []db.Container{
+		{ID: 1, Labels: []string{"db"}, Minion: "2", Volumes: data},
+		{ID: 2, Labels: []string{"db"}, Volumes: data},
+		{ID: 3, Labels: []string{"web"}},
+	}
+
+	ctx := makeContext(minions, nil, containers)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	assert.Equal(t, "2", containers[1].Minion)
+	assert.NotEmpty(t, containers[2].Minion)
+
+	// The placement would move the database, but its data lives on minion 2, so
+	// neither database is placed.
+	placements := []db.Placement{{
+		Exclusive:   true,
+		TargetLabel: "db",
+		Region:      "Region2",
+	}}
+
+	ctx = makeContext(minions, placements, containers)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	exp := map[string][]string{
+		"1": {"volumes are on minion 2"},
+		"2": {"volumes are on minion 2, which conflicts with the placement " +
+			"rules", "label db can't run on region Region2"},
+	}
+	for _, dbc := range containers[:2] {
+		assert.Empty(t, dbc.Minion)
+		assert.Equal(t, "2", dbc.VolumeMinion)
+		assert.Equal(t, exp, dbc.PlacementErrors)
+	}
+
+	// Once the placement allows it, they're placed with their data again.
+	ctx = makeContext(minions, nil, containers)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	assert.Equal(t, "2", containers[0].Minion)
+	assert.Equal(t, "2", containers[1].Minion)
+
+	// A replacement recorded by the engine as needing the data on minion 1 goes
+	// there, though nothing else uses the volume.
+	other := []db.Volume{{Kind: "named", Source: "other", Target: "/data"}}
+	containers = []db.Container{
+		{ID: 4, Volumes: other, VolumeMinion: "1"},
+	}
+
+	ctx = makeContext(minions, nil, containers)
+	placeUnassigned(ctx)
+	assert.Equal(t, "1", containers[0].Minion)
+}
+
+func TestPlaceResources(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+		{PrivateIP: "2", Provider: "Amazon", Size: "m4.xlarge",
+			Role: db.Worker},
+	}
+
+	containers := []db.Container{
+		{ID: 1, Image: "a", CPU: 2},
+		{ID: 2, Image: "b", CPU: 3},
+		{ID: 3, Image: "c", CPU: 1, RAM: 2},
+		{ID: 4, Image: "d", CPU: 2},
+		{ID: 5, Image: "e"},
+	}
+
+	ctx := makeContext(minions, nil, containers)
+	placeUnassigned(ctx)
+
+	placed := map[int]string{}
+	for _, dbc := range ctx.changed {
+		placed[dbc.ID] = dbc.Minion
+	}
+
+	// The largest reservations are placed first, while there's still room for
+	// them.  Under the default spread policy, each goes to the minion with the
+	// smallest fraction of its capacity reserved, rather than the one it fits
+	// most tightly; TestPolicies covers tight packing.  The last reserved
+	// container would overcommit both minions, while the container without a
+	// reservation can go anywhere.
+	assert.Equal(t, "2", placed[2])
+	assert.Equal(t, "1", placed[1])
+	assert.Equal(t, "2", placed[3])
+	assert.Equal(t, "", placed[4])
+	assert.Contains(t, placed, 5)
+
+	err := []string{"needs 2 CPUs, but 0 are free"}
+	assert.Equal(t, map[string][]string{"1": err, "2": err},
+		containers[3].PlacementErrors)
+
+	// Containers that no longer fit are evicted.
+	containers = []db.Container{
+		{ID: 1, Image: "a", CPU: 2, Minion: "1"},
+		{ID: 2, Image: "b", CPU: 1, Minion: "1"},
+	}
+	ctx = makeContext(minions, nil, containers)
+	cleanupPlacements(ctx)
+	assert.Len(t, ctx.changed, 1)
+	assert.Len(t, ctx.unassigned, 1)
+}
+
+func TestPlaceAffinity(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker},
+		{PrivateIP: "2", Role: db.Worker},
+	}
+	placements := []db.Placement{{TargetLabel: "a", OtherLabel: "b"}}
+
+	// The container of "a" sorts first, but can't be placed until "b" is.
+	containers := []db.Container{
+		{ID: 1, Image: "1", Labels: []string{"a"}},
+		{ID: 2, Image: "2", Labels: []string{"b"}},
+		{ID: 3, Image: "3", Labels: []string{"c"}},
+	}
+	ctx := makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+
+	placed := map[int]string{}
+	for _, dbc := range ctx.changed {
+		placed[dbc.ID] = dbc.Minion
+	}
+	assert.Len(t, placed, 3)
+	assert.Equal(t, placed[2], placed[1])
+
+	// Containers of "a" must move to where "b" runs, regardless of their order
+	// on the minion.
+	containers = []db.Container{
+		{ID: 1, Labels: []string{"a"}, Minion: "1"},
+		{ID: 2, Labels: []string{"a"}, Minion: "2"},
+		{ID: 3, Labels: []string{"b"}, Minion: "2"},
+	}
+	ctx = makeContext(minions, placements, containers)
+	cleanupPlacements(ctx)
+	assert.Len(t, ctx.changed, 1)
+	assert.Equal(t, 1, ctx.changed[0].ID)
+
+	placeUnassigned(ctx)
+	assert.Equal(t, "2", containers[0].Minion)
+
+	// Without "b", there's nowhere to place "a".
+	containers = []db.Container{{ID: 1, Labels: []string{"a"}, Minion: "2"}}
+	ctx = makeContext(minions, placements, containers)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+	assert.Empty(t, containers[0].Minion)
+
+	err := []string{"label a must run with label b"}
+	assert.Equal(t, map[string][]string{"1": err, "2": err},
+		containers[0].PlacementErrors)
+}
+
+func TestPlaceMutualAffinity(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+		{PrivateIP: "2", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+	}
+	placements := []db.Placement{
+		{TargetLabel: "a", OtherLabel: "b"},
+		{TargetLabel: "b", OtherLabel: "a"},
+	}
+
+	// Neither container can be placed before the other, so they're placed
+	// together.
+	containers := []db.Container{
+		{ID: 1, Labels: []string{"a"}},
+		{ID: 2, Labels: []string{"b"}},
+		{ID: 3, Labels: []string{"b"}},
+	}
+	ctx := makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+
+	assert.NotEmpty(t, containers[0].Minion)
+	assert.Equal(t, containers[0].Minion, containers[1].Minion)
+	assert.Equal(t, containers[0].Minion, containers[2].Minion)
+
+	// Containers that don't fit together aren't placed at all.
+	containers = []db.Container{
+		{ID: 1, Labels: []string{"a"}, CPU: 1},
+		{ID: 2, Labels: []string{"b"}, CPU: 2},
+	}
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+
+	assert.Empty(t, containers[0].Minion)
+	assert.Empty(t, containers[1].Minion)
+}
+
+func TestPlaceSpread(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Provider: "Amazon", Region: "east", Role: db.Worker},
+		{PrivateIP: "2", Provider: "Amazon", Region: "east", Role: db.Worker},
+		{PrivateIP: "3", Provider: "Amazon", Region: "east", Role: db.Worker},
+		{PrivateIP: "4", Provider: "Amazon", Region: "west", Role: db.Worker},
+	}
+	placements := []db.Placement{{
+		TargetLabel:  "db",
+		SpreadAcross: stitch.SpreadRegion,
+		MaxSkew:      1,
+	}}
+
+	var containers []db.Container
+	for i := 1; i <= 4; i++ {
+		containers = append(containers, db.Container{
+			ID:     i,
+			Labels: []string{"db"},
+		})
+	}
+
+	// Without the spread constraint, three of the containers would land in the
+	// east, as it has more minions.
+	ctx := makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+
+	regions := map[string]int{}
+	for _, dbc := range containers {
+		switch dbc.Minion {
+		case "1", "2", "3":
+			regions["east"]++
+		case "4":
+			regions["west"]++
+		}
+	}
+	assert.Equal(t, map[string]int{"east": 2, "west": 2}, regions)
+
+	// A container that would exceed the skew is left unplaced, even if the minions
+	// that would keep it in bounds are just full for now.
+	containers = []db.Container{
+		{ID: 1, Labels: []string{"db"}, Minion: "1"},
+		{ID: 2, Labels: []string{"db"}, Minion: "2"},
+		{ID: 3, Labels: []string{"db"}, CPU: 1},
+		{ID: 4, Minion: "4", CPU: 2},
+	}
+	minions = minions[:3]
+	minions = append(minions, db.Minion{PrivateIP: "4", Provider: "Amazon",
+		Region: "west", Role: db.Worker, Size: "m4.large"})
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+	assert.Empty(t, containers[2].Minion)
+
+	// Domains that the containers can't run in don't hold back the others.
+	minions = []db.Minion{
+		{PrivateIP: "1", Region: "us-east-1", Role: db.Worker},
+		{PrivateIP: "2", Region: "us-east-1", Role: db.Worker},
+		{PrivateIP: "3", Region: "us-west-1", Role: db.Worker},
+	}
+	placements = []db.Placement{{
+		TargetLabel:  "db",
+		SpreadAcross: stitch.SpreadRegion,
+		MaxSkew:      1,
+	}, {
+		TargetLabel: "db",
+		Exclusive:   true,
+		Region:      "us-west-1",
+	}}
+	containers = []db.Container{
+		{ID: 1, Labels: []string{"db"}},
+		{ID: 2, Labels: []string{"db"}},
+	}
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+	for _, dbc := range containers {
+		assert.Contains(t, []string{"1", "2"}, dbc.Minion)
+	}
+
+	// Nor do draining minions.
+	minions = []db.Minion{
+		{PrivateIP: "1", Role: db.Worker},
+		{PrivateIP: "2", Role: db.Worker, Draining: true},
+	}
+	placements = []db.Placement{{
+		TargetLabel:  "db",
+		SpreadAcross: stitch.SpreadMachine,
+		MaxSkew:      1,
+	}}
+	containers = []db.Container{
+		{ID: 1, Labels: []string{"db"}},
+		{ID: 2, Labels: []string{"db"}},
+	}
+	ctx = makeContext(minions, placements, containers)
+	placeUnassigned(ctx)
+	for _, dbc := range containers {
+		assert.Equal(t, "1", dbc.Minion)
+	}
+}
+
+func TestPlacePreemption(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+		{PrivateIP: "2", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+	}
+
+	// The critical container only fits on minion 2, as the container with a volume
+	// can't be evicted from minion 1.  The high priority container can't evict
+	// anything.
+	containers := []db.Container{
+		{ID: 1, Image: "a", CPU: 1, Minion: "1"},
+		{ID: 2, Image: "b", CPU: 1, Minion: "1", Priority: stitch.PriorityLow,
+			Volumes: []db.Volume{{Kind: "named", Source: "b"}}},
+		{ID: 3, Image: "c", CPU: 2, Minion: "2", Priority: stitch.PriorityLow},
+		{ID: 4, Image: "d", CPU: 2, Priority: stitch.PriorityHigh},
+		{ID: 5, Image: "e", CPU: 2, Priority: stitch.PriorityCritical,
+			StitchID: "web"},
+	}
+
+	ctx := makeContext(minions, nil, containers)
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+
+	assert.Equal(t, "1", containers[0].Minion)
+	assert.Empty(t, containers[0].Evicted)
+	assert.Equal(t, "1", containers[1].Minion)
+	assert.Empty(t, containers[2].Minion)
+	assert.Equal(t, "evicted from 2 for critical container web",
+		containers[2].Evicted)
+	assert.Empty(t, containers[3].Minion)
+	assert.Equal(t, "2", containers[4].Minion)
+
+	// Evicted containers are placed again once there's room for them.
+	ctx = makeContext(minions, nil, containers[2:3])
+	placeUnassigned(ctx)
+	assert.NotEmpty(t, containers[2].Minion)
+	assert.Empty(t, containers[2].Evicted)
+
+	// Containers are placed in priority order.
+	a := &db.Container{Image: "a", Priority: stitch.PriorityLow}
+	b := &db.Container{Image: "b"}
+	c := &db.Container{Image: "c", Priority: stitch.PriorityCritical}
+	slice := []*db.Container{a, b, c}
+	sort.Sort(dbcSlice(slice))
+	assert.Equal(t, []*db.Container{c, b, a}, slice)
+}
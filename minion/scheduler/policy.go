@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"math/rand"
+
+	"github.com/NetSys/quilt/cluster/machine"
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A policy decides which minion each container is placed on, in two phases.  First,
+// filter returns the reasons each minion can't accept the container.  Then the
+// container is placed on the minion with the highest score of those that remain.
+type policy interface {
+	filter(constraints []db.Placement, minions []*minion, m *minion,
+		dbc *db.Container, pinned *minion) []string
+	score(m *minion, dbc *db.Container) float64
+}
+
+var policies = map[string]policy{
+	stitch.SpreadPolicy:  spreadPolicy{},
+	stitch.BinpackPolicy: binpackPolicy{},
+	stitch.RandomPolicy:  randomPolicy{},
+}
+
+// getPolicy returns the scheduler policy chosen by the deployment 'spec'.
+func getPolicy(spec string) policy {
+	compiled, err := stitch.FromJSON(spec)
+	if err != nil {
+		return spreadPolicy{}
+	}
+	return namedPolicy(compiled.SchedulerPolicy)
+}
+
+// namedPolicy returns the scheduler policy called 'name'.  Deployments that don't
+// choose a policy are spread.
+func namedPolicy(name string) policy {
+	if name == "" {
+		return spreadPolicy{}
+	}
+
+	p, ok := policies[name]
+	if !ok {
+		log.WithField("policy", name).Warning("Unknown scheduler policy.")
+		return spreadPolicy{}
+	}
+	return p
+}
+
+// constraintFilter rules out the minions that would violate a container's placement
+// rules or overcommit its reservations.  All of the built-in policies use it.
+type constraintFilter struct{}
+
+func (constraintFilter) filter(constraints []db.Placement, minions []*minion,
+	m *minion, dbc *db.Container, pinned *minion) []string {
+	return placeErrors(constraints, minions, m, dbc, pinned)
+}
+
+// spreadPolicy places each container on the least loaded minion: the one with the
+// least capacity reserved if the container reserves resources, or the fewest
+// containers otherwise.  Because it's the default, reserving containers are spread
+// unless the deployment asks for the binpack policy, which packs them onto the
+// fullest minion that fits them instead.
+type spreadPolicy struct{ constraintFilter }
+
+func (spreadPolicy) score(m *minion, dbc *db.Container) float64 {
+	if reserves(dbc) {
+		return -utilization(*m, m.containers)
+	}
+	return -float64(len(m.containers))
+}
+
+// binpackPolicy places each container on the most loaded minion that still fits it, so
+// that the deployment runs on as few minions as possible.
+type binpackPolicy struct{ constraintFilter }
+
+func (binpackPolicy) score(m *minion, dbc *db.Container) float64 {
+	if reserves(dbc) {
+		return utilization(*m, m.containers)
+	}
+	return float64(len(m.containers))
+}
+
+// randomPolicy places each container on any minion that accepts it, which is useful for
+// testing that a deployment doesn't depend on where its containers run.
+type randomPolicy struct{ constraintFilter }
+
+func (randomPolicy) score(m *minion, dbc *db.Container) float64 {
+	return rand.Float64()
+}
+
+func reserves(dbc *db.Container) bool {
+	return dbc.CPU > 0 || dbc.RAM > 0
+}
+
+// utilization returns the average of the fractions of the CPU and RAM of 'm' that are
+// reserved by 'peers'.  Minions of unknown size are considered empty.
+func utilization(m minion, peers []*db.Container) float64 {
+	desc, ok := machine.Lookup(db.Provider(m.Provider), m.Size)
+	if !ok || desc.CPU == 0 || desc.RAM == 0 {
+		return 0
+	}
+
+	cpu, ram := spareCapacity(m, peers)
+	return (2 - cpu/float64(desc.CPU) - ram/desc.RAM) / 2
+}
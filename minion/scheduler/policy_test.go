@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
+)
+
+func TestPolicies(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+		{PrivateIP: "2", Provider: "Amazon", Size: "m4.large", Role: db.Worker},
+	}
+
+	// Each policy places the same containers onto the same two minions.
+	place := func(p policy) map[int]string {
+		containers := []db.Container{
+			{ID: 1, Image: "a", CPU: 1},
+			{ID: 2, Image: "b", CPU: 1},
+			{ID: 3, Image: "c"},
+			{ID: 4, Image: "d"},
+		}
+
+		ctx := makeContext(minions, nil, containers)
+		ctx.policy = p
+		placeUnassigned(ctx)
+
+		placed := map[int]string{}
+		for _, dbc := range containers {
+			placed[dbc.ID] = dbc.Minion
+		}
+		return placed
+	}
+
+	spread := place(spreadPolicy{})
+	assert.NotEqual(t, spread[1], spread[2])
+	assert.NotEqual(t, spread[3], spread[4])
+
+	binpack := place(binpackPolicy{})
+	assert.Equal(t, binpack[1], binpack[2])
+	assert.Equal(t, binpack[1], binpack[3])
+	assert.Equal(t, binpack[1], binpack[4])
+
+	for _, ip := range place(randomPolicy{}) {
+		assert.NotEmpty(t, ip)
+	}
+}
+
+func TestGetPolicy(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, spreadPolicy{}, getPolicy(""))
+	assert.Equal(t, spreadPolicy{}, getPolicy(`{"SchedulerPolicy": "fastest"}`))
+
+	spec := stitch.Stitch{SchedulerPolicy: stitch.BinpackPolicy}.String()
+	assert.Equal(t, binpackPolicy{}, getPolicy(spec))
+
+	spec = stitch.Stitch{SchedulerPolicy: stitch.RandomPolicy}.String()
+	assert.Equal(t, randomPolicy{}, getPolicy(spec))
+}
+
+func TestUtilization(t *testing.T) {
+	t.Parallel()
+
+	m := minion{Minion: db.Minion{Provider: "Amazon", Size: "m4.large"}}
+	assert.Equal(t, 0.0, utilization(m, nil))
+
+	peers := []*db.Container{{CPU: 1, RAM: 8}}
+	assert.Equal(t, 0.75, utilization(m, peers))
+
+	m.Size = "unknown"
+	assert.Equal(t, 0.0, utilization(m, peers))
+}
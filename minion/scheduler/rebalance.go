@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A migrated container counts against the disruption budgets of its labels until it
+// has had this long to restart on its new minion.
+const migrationTime = time.Minute
+
+// migrations records when the leader last migrated each container, by stitch ID.  It's
+// kept in memory, so a new leader starts with a full budget.
+type migrations map[string]time.Time
+
+// disruptionBudgets returns the number of containers of each label in the deployment
+// 'spec' that may be migrated at once, for the labels that don't use the default.
+func disruptionBudgets(spec string) map[string]int {
+	compiled, err := stitch.FromJSON(spec)
+	if err != nil {
+		return nil
+	}
+
+	budgets := map[string]int{}
+	for _, label := range compiled.Labels {
+		if label.DisruptionBudget > 0 {
+			budgets[label.Name] = label.DisruptionBudget
+		}
+	}
+	return budgets
+}
+
+type rebalancer struct {
+	ctx     *context
+	minions []*minion
+	moved   migrations
+	now     time.Time
+
+	// The number of containers of each label that are migrating.
+	inFlight map[string]int
+
+	// The containers that were already changed this round.
+	changed map[*db.Container]struct{}
+}
+
+// rebalance migrates containers off of draining minions and, under the spread policy,
+// from the most to the least loaded minions.  The workers kill and boot the migrated
+// containers as they do any others.  Each label may only have as many containers
+// migrating at once as its disruption budget allows, and each container is migrated
+// at most once per migrationTime.  Containers with volumes are never moved, as their
+// data would be left behind.
+func rebalance(ctx *context, moved migrations, now time.Time) {
+	for id, t := range moved {
+		if now.Sub(t) >= migrationTime {
+			delete(moved, id)
+		}
+	}
+
+	r := rebalancer{
+		ctx:      ctx,
+		minions:  ctx.minions,
+		moved:    moved,
+		now:      now,
+		inFlight: map[string]int{},
+		changed:  map[*db.Container]struct{}{},
+	}
+	for _, dbc := range ctx.changed {
+		r.changed[dbc] = struct{}{}
+	}
+
+	for _, m := range r.minions {
+		for _, dbc := range m.containers {
+			if _, ok := moved[dbc.StitchID]; ok {
+				for _, label := range dbc.Labels {
+					r.inFlight[label]++
+				}
+			}
+		}
+	}
+
+	for _, m := range r.minions {
+		if !m.Draining {
+			continue
+		}
+
+		for _, dbc := range append([]*db.Container(nil), m.containers...) {
+			r.migrate(m, dbc, false)
+		}
+	}
+
+	if _, ok := ctx.policy.(spreadPolicy); !ok {
+		return
+	}
+
+	for migrated := true; migrated; {
+		migrated = false
+		for _, m := range r.minions {
+			if m.Draining {
+				continue
+			}
+
+			peers := append([]*db.Container(nil), m.containers...)
+			for _, dbc := range peers {
+				migrated = r.migrate(m, dbc, true) || migrated
+			}
+		}
+	}
+}
+
+// migrate moves 'dbc' from 'from' to the minion the policy prefers for it, if the
+// disruption budgets of its labels allow.  If 'balance' is true, 'dbc' is only moved
+// if its new minion would be less loaded than 'from' is now.  Containers aren't moved
+// if that would break the affinity or spread rules of those left behind.
+func (r *rebalancer) migrate(from *minion, dbc *db.Container, balance bool) bool {
+	if !r.movable(dbc) {
+		return false
+	}
+
+	broken := r.brokenRules(from)
+	peers := from.containers
+	from.containers = without(peers, []*db.Container{dbc})
+
+	i := chooseMinion(r.ctx, r.minions, dbc)
+	if i >= 0 && balance {
+		m := r.minions[i]
+		after := append(append([]*db.Container(nil), m.containers...), dbc)
+		if load(m, after, dbc) >= load(from, peers, dbc) {
+			i = -1
+		}
+	}
+
+	if i < 0 || r.minions[i] == from {
+		from.containers = peers
+		return false
+	}
+
+	to := r.minions[i]
+	toPeers := to.containers
+	to.containers = append(to.containers, dbc)
+	for peer := range r.brokenRules(from) {
+		if _, ok := broken[peer]; !ok {
+			from.containers = peers
+			to.containers = toPeers
+			return false
+		}
+	}
+	dbc.Minion = to.PrivateIP
+
+	r.ctx.changed = append(r.ctx.changed, dbc)
+	r.changed[dbc] = struct{}{}
+	r.moved[dbc.StitchID] = r.now
+	for _, label := range dbc.Labels {
+		r.inFlight[label]++
+	}
+
+	r.ctx.logger.WithFields(log.Fields{
+		"container": dbc,
+		"from":      from.PrivateIP,
+		"to":        to.PrivateIP,
+	}).Info("Migrated container.")
+	return true
+}
+
+// brokenRules returns the containers on 'm' that lack the peers their affinity rules
+// require, or whose labels are spread beyond their maximum skew.
+func (r *rebalancer) brokenRules(m *minion) map[*db.Container]struct{} {
+	broken := map[*db.Container]struct{}{}
+	for _, dbc := range m.containers {
+		if !validAffinity(r.ctx.constraints, m.containers, dbc) ||
+			len(spreadErrors(r.ctx.constraints, r.minions, m, dbc)) > 0 {
+			broken[dbc] = struct{}{}
+		}
+	}
+	return broken
+}
+
+// movable returns true if 'dbc' may be migrated without exceeding the disruption
+// budget of any of its labels.
+func (r *rebalancer) movable(dbc *db.Container) bool {
+	if len(dbc.Volumes) > 0 {
+		return false
+	}
+
+	if _, ok := r.changed[dbc]; ok {
+		return false
+	}
+
+	if _, ok := r.moved[dbc.StitchID]; ok {
+		return false
+	}
+
+	for _, label := range dbc.Labels {
+		budget := r.ctx.budgets[label]
+		if budget == 0 {
+			budget = 1
+		}
+
+		if r.inFlight[label] >= budget {
+			return false
+		}
+	}
+	return true
+}
+
+// load returns how loaded 'm' is with 'peers', as the spread policy measures it for
+// 'dbc': the fraction of its capacity reserved if 'dbc' reserves resources, or its
+// number of containers otherwise.
+func load(m *minion, peers []*db.Container, dbc *db.Container) float64 {
+	if reserves(dbc) {
+		return utilization(*m, peers)
+	}
+	return float64(len(peers))
+}
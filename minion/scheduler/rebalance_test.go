@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/stitch"
+)
+
+func TestRebalanceDraining(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker, Draining: true},
+		{PrivateIP: "2", Role: db.Worker},
+	}
+
+	containers := []db.Container{
+		{ID: 1, StitchID: "a", Labels: []string{"web"}, Minion: "1"},
+		{ID: 2, StitchID: "b", Labels: []string{"web"}, Minion: "1"},
+		{ID: 3, StitchID: "c", Labels: []string{"db"}, Minion: "1",
+			Volumes: []db.Volume{{Kind: "named", Source: "c"}}},
+		{ID: 4, StitchID: "d", Labels: []string{"web"}},
+	}
+
+	// New containers aren't placed on draining minions, and only one container
+	// of the "web" label may migrate at once.
+	now := time.Now()
+	moved := migrations{}
+	ctx := makeContext(minions, nil, containers)
+	placeUnassigned(ctx)
+	rebalance(ctx, moved, now)
+	assert.Equal(t, "2", containers[3].Minion)
+	assert.Equal(t, "2", containers[0].Minion)
+	assert.Equal(t, "1", containers[1].Minion)
+	assert.Equal(t, "1", containers[2].Minion)
+	assert.Equal(t, migrations{"a": now}, moved)
+
+	ctx = makeContext(minions, nil, containers)
+	rebalance(ctx, moved, now.Add(time.Second))
+	assert.Empty(t, ctx.changed)
+
+	// Once the migration has had time to finish, the next container may move.
+	// Containers with volumes stay where they are.
+	later := now.Add(migrationTime)
+	ctx = makeContext(minions, nil, containers)
+	rebalance(ctx, moved, later)
+	assert.Equal(t, "2", containers[1].Minion)
+	assert.Equal(t, "1", containers[2].Minion)
+	assert.Equal(t, migrations{"b": later}, moved)
+
+	// Labels with a larger budget may migrate more containers at once.
+	for i := range containers[:2] {
+		containers[i].Minion = "1"
+	}
+	ctx = makeContext(minions, nil, containers)
+	ctx.budgets = map[string]int{"web": 2}
+	rebalance(ctx, migrations{}, now)
+	assert.Equal(t, "2", containers[0].Minion)
+	assert.Equal(t, "2", containers[1].Minion)
+}
+
+func TestRebalanceSpread(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker},
+		{PrivateIP: "2", Role: db.Worker},
+	}
+
+	var containers []db.Container
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		containers = append(containers, db.Container{ID: i + 1, StitchID: id,
+			Labels: []string{id}, Minion: "1"})
+	}
+
+	// The binpack policy leaves the containers where they are.
+	ctx := makeContext(minions, nil, containers)
+	ctx.policy = binpackPolicy{}
+	rebalance(ctx, migrations{}, time.Now())
+	assert.Empty(t, ctx.changed)
+
+	// The spread policy moves containers off of the hot minion until moving
+	// another wouldn't improve the balance.
+	ctx = makeContext(minions, nil, containers)
+	rebalance(ctx, migrations{}, time.Now())
+	assert.Len(t, ctx.changed, 2)
+
+	count := map[string]int{}
+	for _, dbc := range containers {
+		count[dbc.Minion]++
+	}
+	assert.Equal(t, map[string]int{"1": 3, "2": 2}, count)
+
+	ctx = makeContext(minions, nil, containers)
+	rebalance(ctx, migrations{}, time.Now())
+	assert.Empty(t, ctx.changed)
+}
+
+func TestRebalanceAffinity(t *testing.T) {
+	t.Parallel()
+
+	minions := []db.Minion{
+		{PrivateIP: "1", Role: db.Worker},
+		{PrivateIP: "2", Role: db.Worker},
+	}
+	placements := []db.Placement{{TargetLabel: "a", OtherLabel: "b"}}
+
+	// Moving "b" would balance the minions, but would leave "a" without it.
+	containers := []db.Container{
+		{ID: 1, StitchID: "a", Labels: []string{"a"}, Minion: "1"},
+		{ID: 2, StitchID: "b", Labels: []string{"b"}, Minion: "1"},
+	}
+	ctx := makeContext(minions, placements, containers)
+	rebalance(ctx, migrations{}, time.Now())
+	assert.Empty(t, ctx.changed)
+	assert.Equal(t, "1", containers[1].Minion)
+
+	// Once another container of "b" runs with "a", either may move.
+	containers = append(containers, db.Container{ID: 3, StitchID: "c",
+		Labels: []string{"b"}, Minion: "1"})
+	ctx = makeContext(minions, placements, containers)
+	rebalance(ctx, migrations{}, time.Now())
+	assert.Len(t, ctx.changed, 1)
+	assert.Equal(t, "1", containers[0].Minion)
+}
+
+func TestDisruptionBudgets(t *testing.T) {
+	t.Parallel()
+
+	spec := stitch.Stitch{Labels: []stitch.Label{
+		{Name: "web", DisruptionBudget: 3},
+		{Name: "db"},
+	}}
+	assert.Equal(t, map[string]int{"web": 3}, disruptionBudgets(spec.String()))
+	assert.Nil(t, disruptionBudgets("not json"))
+}
@@ -24,6 +24,7 @@ func Run(conn db.Conn, dk docker.Client) {
 		log.WithError(err).Fatal("Failed to configure network plugin")
 	}
 
+	moved := migrations{}
 	loopLog := util.NewEventTimer("Scheduler")
 	trig := conn.TriggerTick(60, db.MinionTable, db.ContainerTable,
 		db.PlacementTable, db.EtcdTable).C
@@ -38,7 +39,7 @@ func Run(conn db.Conn, dk docker.Client) {
 		if minion.Role == db.Worker {
 			runWorker(conn, dk, minion.PrivateIP)
 		} else if minion.Role == db.Master {
-			runMaster(conn)
+			runMaster(conn, moved)
 		}
 		loopLog.LogEnd()
 	}
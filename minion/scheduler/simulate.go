@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"io/ioutil"
+
+	"github.com/NetSys/quilt/db"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Simulate places 'containers' on 'minions' as the leader would, following
+// 'placements' and the scheduler policy called 'policyName', and returns a copy of the
+// containers with their ID, and Minion or PlacementErrors, filled in.  Nothing is
+// logged or committed to a database.
+func Simulate(minions []db.Minion, placements []db.Placement,
+	containers []db.Container, policyName string) []db.Container {
+
+	// The containers need distinct IDs, as rows do, to be told apart from their
+	// peers.
+	result := make([]db.Container, len(containers))
+	for i, dbc := range containers {
+		dbc.ID = i + 1
+		result[i] = dbc
+	}
+
+	ctx := makeContext(minions, placements, result)
+	ctx.policy = namedPolicy(policyName)
+	ctx.logger = &log.Logger{Out: ioutil.Discard, Level: log.PanicLevel}
+
+	cleanupPlacements(ctx)
+	placeUnassigned(ctx)
+	return result
+}
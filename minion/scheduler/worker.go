@@ -1,13 +1,16 @@
 package scheduler
 
 import (
+	"math"
 	"sync"
+	"time"
 
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/join"
 	"github.com/NetSys/quilt/minion/docker"
 	"github.com/NetSys/quilt/minion/ipdef"
 	"github.com/NetSys/quilt/minion/network/plugin"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/NetSys/quilt/util"
 	log "github.com/Sirupsen/logrus"
 )
@@ -17,6 +20,13 @@ const labelValue = "scheduler"
 const labelPair = labelKey + "=" + labelValue
 const concurrencyLimit = 32
 
+// Containers that keep failing are restarted with exponential backoff, so that a
+// crash loop doesn't hog the minion.
+const (
+	minRestartBackoff = 5 * time.Second
+	maxRestartBackoff = 5 * time.Minute
+)
+
 func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 	if myIP == "" {
 		return
@@ -26,16 +36,20 @@ func runWorker(conn db.Conn, dk docker.Client, myIP string) {
 
 	var toBoot, toKill []interface{}
 	for i := 0; i < 2; i++ {
-		dkcs, err := dk.List(filter)
+		// Exited containers are listed so that they can be restarted under
+		// their restart policies, or removed.
+		dkcs, err := dk.ListAll(filter)
 		if err != nil {
 			log.WithError(err).Warning("Failed to list docker containers.")
 			return
 		}
 
-		conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
-			dbcs := view.SelectFromContainer(func(dbc db.Container) bool {
-				return dbc.IP != "" && dbc.Minion == myIP
-			})
+		txn := conn.Txn(db.ContainerTable).WithReason("scheduler: sync docker")
+		txn.Run(func(view db.Database) error {
+			dbcs := view.SelectFromContainerByMinion(myIP,
+				func(dbc db.Container) bool {
+					return dbc.IP != ""
+				})
 
 			var changed []db.Container
 			changed, toBoot, toKill = syncWorker(dbcs, dkcs)
@@ -64,6 +78,19 @@ func syncWorker(dbcs []db.Container, dkcs []docker.Container) (
 		dbc := pair.L.(db.Container)
 		dkc := pair.R.(docker.Container)
 
+		// The health of a container that was restarted must be checked anew.
+		if dbc.DockerID != dkc.ID {
+			dbc.Health = ""
+		}
+
+		// Containers that stay up for longer than the maximum backoff are
+		// considered stable, so their next failure is retried promptly.
+		// Those of unknown age are left alone.
+		if !dkc.Created.IsZero() &&
+			time.Since(dkc.Created) > maxRestartBackoff {
+			dbc.Restarts = 0
+		}
+
 		dbc.DockerID = dkc.ID
 		dbc.EndpointID = dkc.EID
 		dbc.Status = dkc.Status
@@ -71,8 +98,17 @@ func syncWorker(dbcs []db.Container, dkcs []docker.Container) (
 		changed = append(changed, dbc)
 	}
 
+	dkcsByID := map[string]docker.Container{}
+	for _, dkc := range dkcs {
+		dkcsByID[dkc.ID] = dkc
+	}
+
 	for _, i := range dbci {
 		dbc := i.(db.Container)
+		if dkc, ok := dkcsByID[dbc.DockerID]; ok && needsRestart(dbc, dkc) {
+			dbc.Restarts++
+			changed = append(changed, dbc)
+		}
 		toBoot = append(toBoot, dbc)
 	}
 
@@ -107,6 +143,9 @@ func dockerRun(dk docker.Client, in chan interface{}) {
 			Image:       dbc.Image,
 			Args:        dbc.Command,
 			Env:         dbc.Env,
+			Binds:       volumeBinds(dbc.Volumes),
+			CPU:         dbc.CPU,
+			Memory:      ramBytes(dbc.RAM),
 			Labels:      map[string]string{labelKey: labelValue},
 			IP:          dbc.IP,
 			NetworkMode: plugin.NetworkName,
@@ -136,6 +175,61 @@ func dockerKill(dk docker.Client, in chan interface{}) {
 	}
 }
 
+// needsRestart returns true if 'dbc's restart policy requires that 'dkc', which runs
+// it, be restarted.
+func needsRestart(dbc db.Container, dkc docker.Container) bool {
+	// The recorded health describes the docker container 'dbc' last ran in, so it
+	// says nothing of a replacement that hasn't been checked yet.
+	unhealthy := dbc.Health == db.Unhealthy && dbc.DockerID == dkc.ID
+	exited := dkc.Status == "exited"
+	failed := unhealthy || exited && dkc.ExitCode != 0
+
+	switch dbc.RestartPolicy {
+	case stitch.RestartNever:
+		return false
+	case stitch.RestartAlways:
+		return failed || exited
+	default:
+		return failed
+	}
+}
+
+// restartDue returns true if 'dkc' needs to be restarted, and has run for long enough
+// since it was last restarted.  The wait doubles with each restart of 'dbc'.
+func restartDue(dbc db.Container, dkc docker.Container) bool {
+	if !needsRestart(dbc, dkc) {
+		return false
+	}
+
+	backoff := minRestartBackoff
+	for i := 0; i < dbc.Restarts && backoff < maxRestartBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxRestartBackoff {
+		backoff = maxRestartBackoff
+	}
+	return time.Since(dkc.Created) >= backoff
+}
+
+// volumeBinds converts 'volumes' into the format expected by docker.RunOptions.
+func volumeBinds(volumes []db.Volume) []string {
+	var binds []string
+	for _, v := range volumes {
+		bind := v.Source + ":" + v.Target
+		if v.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+	return binds
+}
+
+// ramBytes converts 'ram' gigabytes into bytes.
+func ramBytes(ram float64) int64 {
+	return int64(ram * (1 << 30))
+}
+
 func syncJoinScore(left, right interface{}) int {
 	dbc := left.(db.Container)
 	dkc := right.(docker.Container)
@@ -144,12 +238,27 @@ func syncJoinScore(left, right interface{}) int {
 		return -1
 	}
 
+	// Refusing to pair a container that must be restarted causes it to be killed
+	// and booted again.
+	if restartDue(dbc, dkc) {
+		return -1
+	}
+
 	for key, value := range dbc.Env {
 		if dkc.Env[key] != value {
 			return -1
 		}
 	}
 
+	if !util.StrSliceEqual(volumeBinds(dbc.Volumes), dkc.Binds) {
+		return -1
+	}
+
+	// Docker only limits CPU usage to within a hundred-thousandth of a core.
+	if math.Abs(dbc.CPU-dkc.CPU) >= 1e-5 || ramBytes(dbc.RAM) != dkc.Memory {
+		return -1
+	}
+
 	// Depending on the container, the command in the database could be
 	// either the command plus it's arguments, or just it's arguments.  To
 	// handle that case, we check both.
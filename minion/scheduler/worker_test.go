@@ -2,9 +2,11 @@ package scheduler
 
 import (
 	"testing"
+	"time"
 
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/docker"
+	"github.com/NetSys/quilt/stitch"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
 )
@@ -177,4 +179,173 @@ func TestSyncJoinScore(t *testing.T) {
 	score = syncJoinScore(dbc, dkc)
 	assert.Equal(t, -1, score)
 	dbc.Env = dkc.Env
+
+	dbc.Volumes = []db.Volume{{Kind: "named", Source: "data", Target: "/data"}}
+	score = syncJoinScore(dbc, dkc)
+	assert.Equal(t, -1, score)
+
+	dkc.Binds = []string{"data:/data"}
+	score = syncJoinScore(dbc, dkc)
+	assert.Zero(t, score)
+
+	dbc.CPU = 1.5
+	dbc.RAM = 0.5
+	score = syncJoinScore(dbc, dkc)
+	assert.Equal(t, -1, score)
+
+	dkc.CPU = 1.5
+	dkc.Memory = 1 << 29
+	score = syncJoinScore(dbc, dkc)
+	assert.Zero(t, score)
+}
+
+func TestVolumeBinds(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, volumeBinds(nil))
+	assert.Equal(t, []string{"data:/var/lib/mysql", "/etc/conf:/conf:ro"},
+		volumeBinds([]db.Volume{
+			{Kind: "named", Source: "data", Target: "/var/lib/mysql"},
+			{Kind: "host", Source: "/etc/conf", Target: "/conf",
+				ReadOnly: true},
+		}))
+}
+
+func TestNeedsRestart(t *testing.T) {
+	t.Parallel()
+
+	running := docker.Container{ID: "a", Status: "running"}
+	exited := docker.Container{ID: "a", Status: "exited"}
+	failed := docker.Container{ID: "a", Status: "exited", ExitCode: 1}
+
+	dbc := db.Container{DockerID: "a"}
+	assert.False(t, needsRestart(dbc, running))
+	assert.False(t, needsRestart(dbc, exited))
+	assert.True(t, needsRestart(dbc, failed))
+
+	dbc.Health = db.Unhealthy
+	assert.True(t, needsRestart(dbc, running))
+
+	// The health of the container's previous incarnation doesn't count.
+	assert.False(t, needsRestart(dbc, docker.Container{ID: "b"}))
+
+	dbc.RestartPolicy = stitch.RestartAlways
+	dbc.Health = ""
+	assert.False(t, needsRestart(dbc, running))
+	assert.True(t, needsRestart(dbc, exited))
+
+	dbc.RestartPolicy = stitch.RestartNever
+	dbc.Health = db.Unhealthy
+	assert.False(t, needsRestart(dbc, failed))
+}
+
+func TestSyncWorkerRestart(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{{ID: 1, Image: "a", DockerID: "1", Health: db.Unhealthy}}
+	dkcs := []docker.Container{{ID: "1", Image: "a", Status: "running"}}
+
+	restarted := dbcs[0]
+	restarted.Restarts = 1
+
+	changed, toBoot, toKill := syncWorker(dbcs, dkcs)
+	assert.Equal(t, []db.Container{restarted}, changed)
+	assert.Equal(t, []interface{}{restarted}, toBoot)
+	assert.Equal(t, []interface{}{dkcs[0]}, toKill)
+
+	// Once restarted, the container's health is unknown until checked again.
+	dkcs = []docker.Container{{ID: "2", Image: "a", Status: "running"}}
+	changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+	assert.Empty(t, toBoot)
+	assert.Empty(t, toKill)
+	assert.Len(t, changed, 1)
+	assert.Equal(t, "2", changed[0].DockerID)
+	assert.Empty(t, changed[0].Health)
+
+	// Containers that were restarted recently wait before being restarted again.
+	dbcs = []db.Container{{ID: 1, Image: "a", DockerID: "2", Restarts: 1}}
+	dkcs = []docker.Container{{ID: "2", Image: "a", Status: "exited",
+		ExitCode: 1, Created: time.Now().Add(-5 * time.Second)}}
+	changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+	assert.Empty(t, toBoot)
+	assert.Empty(t, toKill)
+	assert.Equal(t, "exited", changed[0].Status)
+
+	dkcs[0].Created = time.Now().Add(-10 * time.Second)
+	changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+	assert.Len(t, toBoot, 1)
+	assert.Equal(t, []interface{}{dkcs[0]}, toKill)
+	assert.Equal(t, 2, changed[0].Restarts)
+
+	// Containers that have run for longer than the maximum backoff are no longer
+	// penalized for their past restarts.
+	dbcs = []db.Container{{ID: 1, Image: "a", DockerID: "3", Restarts: 4}}
+	dkcs = []docker.Container{{ID: "3", Image: "a", Status: "running",
+		Created: time.Now().Add(-time.Minute)}}
+	changed, _, _ = syncWorker(dbcs, dkcs)
+	assert.Equal(t, 4, changed[0].Restarts)
+
+	dkcs[0].Created = time.Now().Add(-maxRestartBackoff - time.Second)
+	changed, toBoot, toKill = syncWorker(dbcs, dkcs)
+	assert.Empty(t, toBoot)
+	assert.Empty(t, toKill)
+	assert.Equal(t, 0, changed[0].Restarts)
+}
+
+func TestRunWorkerExited(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.Image = "Image"
+		dbc.Minion = "1.2.3.4"
+		dbc.IP = "10.0.0.2"
+		view.Commit(dbc)
+		return nil
+	})
+
+	runWorker(conn, dk, "1.2.3.4")
+	dkcs, err := dk.List(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	crashed := dkcs[0].ID
+
+	// A container that exits with an error is replaced.
+	md.StopContainer(crashed)
+	md.Containers[crashed].State.ExitCode = 1
+	md.Containers[crashed].Created = time.Now().Add(-time.Minute)
+
+	runWorker(conn, dk, "1.2.3.4")
+	dkcs, err = dk.ListAll(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	assert.NotEqual(t, crashed, dkcs[0].ID)
+
+	dbcs := conn.SelectFromContainer(nil)
+	assert.Equal(t, 1, dbcs[0].Restarts)
+	assert.Equal(t, dkcs[0].ID, dbcs[0].DockerID)
+
+	// One that exits cleanly isn't, but is recorded as exited.
+	exited := dkcs[0].ID
+	md.StopContainer(exited)
+
+	runWorker(conn, dk, "1.2.3.4")
+	dkcs, err = dk.ListAll(nil)
+	assert.NoError(t, err)
+	assert.Len(t, dkcs, 1)
+	assert.Equal(t, exited, dkcs[0].ID)
+	assert.Equal(t, "exited", conn.SelectFromContainer(nil)[0].Status)
+
+	// Exited containers that aren't in the database are removed.
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		view.Remove(view.SelectFromContainer(nil)[0])
+		return nil
+	})
+
+	runWorker(conn, dk, "1.2.3.4")
+	dkcs, err = dk.ListAll(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, dkcs)
 }
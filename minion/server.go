@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/pb"
 
@@ -19,7 +20,13 @@ type server struct {
 	db.Conn
 }
 
-func minionServerRun(conn db.Conn) {
+func minionServerRun(conn db.Conn, creds auth.Credentials) {
+	opts, err := creds.ServerOptions()
+	if err != nil {
+		log.WithError(err).Error("Failed to load credentials.")
+		return
+	}
+
 	var sock net.Listener
 	server := server{conn}
 	for {
@@ -34,7 +41,7 @@ func minionServerRun(conn db.Conn) {
 		time.Sleep(30 * time.Second)
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(opts...)
 	pb.RegisterMinionServer(s, server)
 	s.Serve(sock)
 }
@@ -68,6 +75,11 @@ func (s server) GetMinionConfig(cts context.Context,
 
 func (s server) SetMinionConfig(ctx context.Context,
 	msg *pb.MinionConfig) (*pb.Reply, error) {
+
+	if err := auth.CheckDaemonRPC(ctx); err != nil {
+		return nil, err
+	}
+
 	go s.Txn(db.EtcdTable,
 		db.MinionTable).Run(func(view db.Database) error {
 
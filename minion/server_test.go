@@ -6,7 +6,11 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/minion/pb"
 )
@@ -35,7 +39,7 @@ func TestSetMinionConfig(t *testing.T) {
 		Region:         "region",
 		AuthorizedKeys: "key1\nkey2",
 	}
-	_, err := s.SetMinionConfig(nil, &cfg)
+	_, err := s.SetMinionConfig(context.Background(), &cfg)
 	assert.NoError(t, err)
 	checkMinionEquals(t, s.Conn, expMinion)
 	checkEtcdEquals(t, s.Conn, db.Etcd{
@@ -46,12 +50,26 @@ func TestSetMinionConfig(t *testing.T) {
 	cfg.Spec = "new"
 	expMinion.Spec = "new"
 	cfg.EtcdMembers = []string{"etcd3"}
-	_, err = s.SetMinionConfig(nil, &cfg)
+	_, err = s.SetMinionConfig(context.Background(), &cfg)
 	assert.NoError(t, err)
 	checkMinionEquals(t, s.Conn, expMinion)
 	checkEtcdEquals(t, s.Conn, db.Etcd{
 		EtcdIPs: []string{"etcd3"},
 	})
+
+	// Only the daemon may configure the minion.
+	ca, err := auth.NewCA()
+	assert.NoError(t, err)
+
+	peerCreds, err := ca.IssueMinion()
+	assert.NoError(t, err)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: *auth.PeerState(peerCreds)},
+	})
+	_, err = s.SetMinionConfig(ctx, &pb.MinionConfig{Spec: "evil"})
+	assert.EqualError(t, err, "only the daemon is authorized")
+	checkMinionEquals(t, s.Conn, expMinion)
 }
 
 func checkMinionEquals(t *testing.T, conn db.Conn, exp db.Minion) {
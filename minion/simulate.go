@@ -0,0 +1,60 @@
+package minion
+
+import (
+	"strconv"
+
+	"github.com/NetSys/quilt/cluster/machine"
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/minion/scheduler"
+	"github.com/NetSys/quilt/stitch"
+)
+
+// A Simulation is the result of scheduling the containers of a stitch onto its worker
+// machines, without booting either.
+type Simulation struct {
+	// The simulated workers.  Each is identified by the position of its machine in
+	// the stitch, counting from 1, in place of a private IP.
+	Minions    []db.Minion
+	Containers []db.Container
+}
+
+// Schedulable returns true if the scheduler can place every container of 'spec' on
+// its worker machines.  It's a stitch.Scheduler.
+func Schedulable(spec stitch.Stitch) bool {
+	for _, dbc := range Simulate(spec).Containers {
+		if dbc.Minion == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Simulate runs the scheduler on the containers and worker machines of 'spec'.
+// Machines without a size are given the one the cluster would boot.
+func Simulate(spec stitch.Stitch) Simulation {
+	var minions []db.Minion
+	for i, m := range spec.Machines {
+		if role, _ := db.ParseRole(m.Role); role != db.Worker {
+			continue
+		}
+
+		size := m.Size
+		provider, err := db.ParseProvider(m.Provider)
+		if size == "" && err == nil {
+			size = machine.ChooseSize(provider, m.RAM, m.CPU, spec.MaxPrice)
+		}
+
+		minions = append(minions, db.Minion{
+			Role:       db.Worker,
+			PrivateIP:  strconv.Itoa(i + 1),
+			Provider:   m.Provider,
+			Region:     m.Region,
+			Size:       size,
+			FloatingIP: m.FloatingIP,
+		})
+	}
+
+	containers := scheduler.Simulate(minions, queryPlacements(spec),
+		queryContainers(spec), spec.SchedulerPolicy)
+	return Simulation{Minions: minions, Containers: containers}
+}
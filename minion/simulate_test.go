@@ -0,0 +1,57 @@
+package minion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/stitch"
+)
+
+func TestSimulate(t *testing.T) {
+	pre := `var db = new Service("db", new Container("mysql").replicate(2));
+	db.place(new LabelRule(true, db));
+	var web = new Service("web", [new Container("nginx")]);
+	deployment.deploy([db, web]);
+	deployment.deploy(new Machine({role: "Master", provider: "Amazon"}));
+	deployment.deploy([
+		new Machine({role: "Worker", provider: "Amazon", region: "us-west-1",
+			size: "m4.large"}),
+		new Machine({role: "Worker", provider: "Amazon", region: "us-east-1",
+			size: "m4.large"})
+	]);`
+
+	spec, err := compile(pre + `deployment.assert(enough, true);`)
+	assert.NoError(t, err)
+
+	sim := Simulate(spec)
+	assert.Len(t, sim.Minions, 2)
+	assert.Equal(t, "2", sim.Minions[0].PrivateIP)
+	assert.Equal(t, "m4.large", sim.Minions[0].Size)
+
+	dbMinions := map[string]struct{}{}
+	for _, dbc := range sim.Containers {
+		assert.NotEmpty(t, dbc.Minion)
+		if dbc.Image == "mysql" {
+			dbMinions[dbc.Minion] = struct{}{}
+		}
+	}
+	assert.Len(t, dbMinions, 2)
+
+	// There are enough machines for each database to run apart, but not within
+	// the region they're limited to.
+	_, err = compile(pre + `db.place(new MachineRule(false,
+		{region: "us-west-1"}));
+	deployment.assert(enough, true);`)
+	assert.EqualError(t, err, "invariant failed: enough true")
+
+	_, err = compile(pre + `db.place(new MachineRule(false,
+		{region: "us-west-1"}));
+	deployment.assert(enough, false);`)
+	assert.NoError(t, err)
+}
+
+func compile(spec string) (stitch.Stitch, error) {
+	return stitch.NewWithScheduler("<raw_string>", spec, stitch.DefaultImportGetter,
+		Schedulable)
+}
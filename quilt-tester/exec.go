@@ -14,6 +14,7 @@ import (
 
 	"github.com/NetSys/quilt/api"
 	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/util"
 )
@@ -166,7 +167,7 @@ func scp(host string, source string, target string) error {
 }
 
 func queryMachines() ([]db.Machine, error) {
-	c, err := client.New(api.DefaultSocket)
+	c, err := client.New(api.DefaultSocket, auth.Credentials{})
 	if err != nil {
 		return []db.Machine{}, err
 	}
@@ -51,10 +51,11 @@ func main() {
 func test(machines []db.Machine, containers []db.Container,
 	connections []db.Connection) bool {
 
-	// Map of label to its publicly exposed ports.
+	// Map of label to its publicly exposed TCP ports.
 	pubConns := map[string][]int{}
 	for _, conn := range connections {
-		if conn.From == "public" {
+		tcp := conn.Protocol == "" || conn.Protocol == "tcp"
+		if conn.From == "public" && tcp {
 			for port := conn.MinPort; port <= conn.MaxPort; port++ {
 				pubConns[conn.To] = append(pubConns[conn.To], port)
 			}
@@ -24,8 +24,11 @@ func main() {
 			"[log-file=<log_output_file>] " +
 			"[daemon | inspect <stitch> | run <stitch> | minion | " +
 			"stop <namespace> | get <import_path> | " +
-			"machines | containers | ps | ssh <id> [command] | " +
-			"logs <container>]")
+			"simulate <stitch> | machines | containers | ps | " +
+			"ssh <id> [command] | drain <machine> | " +
+			"logs <container> | describe <container> | " +
+			"audit [container] | history | " +
+			"rollback <revision>]")
 		fmt.Println("\nWhen provided a stitch, quilt takes responsibility\n" +
 			"for deploying it as specified.  Alternatively, quilt may be\n" +
 			"instructed to stop all deployments in a given namespace,\n" +
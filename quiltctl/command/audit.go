@@ -0,0 +1,140 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/api/client/getter"
+	"github.com/NetSys/quilt/db"
+)
+
+// Audit contains the options for querying the transaction history of the database.
+type Audit struct {
+	leader   bool
+	table    string
+	stitchID string
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewAuditCommand creates a new Audit command instance.
+func NewAuditCommand() *Audit {
+	return &Audit{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (aCmd *Audit) InstallFlags(flags *flag.FlagSet) {
+	aCmd.common.InstallFlags(flags)
+
+	flags.BoolVar(&aCmd.leader, "leader", false,
+		"query the history of the cluster leader rather than the daemon")
+	flags.StringVar(&aCmd.table, "table", "", "only show changes to this table")
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt audit [-H=<daemon_host>] [-leader] " +
+			"[-table=<table>] [<stitch_id>]")
+		fmt.Println("`audit` displays the recent transactions committed to " +
+			"the database, along with the reason for each and the fields " +
+			"it changed.")
+		fmt.Println("When given a stitch ID, `audit` shows the history of " +
+			"that container, as recorded by the cluster leader.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the audit command.
+func (aCmd *Audit) Parse(args []string) error {
+	if len(args) > 0 {
+		aCmd.stitchID = args[0]
+	}
+	return nil
+}
+
+// Run retrieves and prints the requested history.
+func (aCmd *Audit) Run() int {
+	localClient, err := aCmd.clientGetter.Client(aCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer localClient.Close()
+
+	// Containers are only tracked by the leader's database.
+	c := localClient
+	table := db.TableType(aCmd.table)
+	if aCmd.stitchID != "" {
+		table = db.ContainerTable
+	}
+
+	if aCmd.leader || aCmd.stitchID != "" {
+		c, err = aCmd.clientGetter.LeaderClient(localClient)
+		if err != nil {
+			log.WithError(err).Error("Error connecting to leader.")
+			return 1
+		}
+		defer c.Close()
+	}
+
+	records, err := c.QueryHistory(table)
+	if err != nil {
+		log.WithError(err).Error("Unable to query history.")
+		return 1
+	}
+
+	if aCmd.stitchID != "" {
+		records = filterHistory(records, aCmd.stitchID)
+	}
+
+	writeHistory(os.Stdout, records)
+	return 0
+}
+
+// filterHistory restricts 'records' to the changes made to the container with the
+// given stitch ID.
+func filterHistory(records []db.TxnRecord, stitchID string) []db.TxnRecord {
+	var result []db.TxnRecord
+	for _, rec := range records {
+		rec = rec.Filter(func(rd db.RowDiff) bool {
+			return rd.Table == db.ContainerTable && rd.StitchID == stitchID
+		})
+
+		if len(rec.Rows) > 0 {
+			result = append(result, rec)
+		}
+	}
+	return result
+}
+
+func writeHistory(fd io.Writer, records []db.TxnRecord) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "REVISION\tTIME\tREASON\tCHANGE\tROW")
+
+	for _, rec := range records {
+		reason := rec.Reason
+		if reason == "" {
+			reason = "-"
+		}
+
+		for _, rd := range rec.Rows {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", rec.Revision,
+				rec.Time.Local().Format("2006-01-02 15:04:05"), reason,
+				rd.Change, rd.Row)
+
+			for _, fd := range rd.Fields {
+				fmt.Fprintf(w, "\t\t\t\t    %s: %q -> %q\n",
+					fd.Field, fd.Old, fd.New)
+			}
+		}
+	}
+}
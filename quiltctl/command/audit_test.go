@@ -0,0 +1,106 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/NetSys/quilt/api/client/mocks"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestAuditFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewAuditCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-leader", "-table", "Machine"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.True(t, cmd.leader)
+	assert.Equal(t, "Machine", cmd.table)
+	assert.Equal(t, "", cmd.stitchID)
+
+	cmd = NewAuditCommand()
+	err = parseHelper(cmd, []string{"abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", cmd.stitchID)
+}
+
+func TestAuditContainer(t *testing.T) {
+	t.Parallel()
+
+	records := []db.TxnRecord{{
+		Revision: 1,
+		Rows: []db.RowDiff{
+			{Table: db.ContainerTable, StitchID: "abcd"},
+			{Table: db.ContainerTable, StitchID: "efgh"},
+		},
+	}, {
+		Revision: 2,
+		Rows: []db.RowDiff{
+			{Table: db.ContainerTable, StitchID: "efgh"},
+			{Table: db.ContainerTable, StitchID: "abcde"},
+			{Table: db.ContainerTable, StitchID: "abcd", Row: "run a"},
+		},
+	}}
+
+	localClient := &clientMock.Client{}
+	leaderClient := &clientMock.Client{HistoryReturn: records}
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(localClient, nil)
+	mockGetter.On("LeaderClient", localClient).Return(leaderClient, nil)
+
+	cmd := NewAuditCommand()
+	cmd.clientGetter = mockGetter
+	cmd.stitchID = "abcd"
+
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, db.ContainerTable, leaderClient.HistoryArg)
+	mockGetter.AssertExpectations(t)
+
+	// Only whole stitch IDs match.
+	assert.Equal(t, []db.TxnRecord{{
+		Revision: 1,
+		Rows: []db.RowDiff{
+			{Table: db.ContainerTable, StitchID: "abcd"},
+		},
+	}, {
+		Revision: 2,
+		Rows: []db.RowDiff{
+			{Table: db.ContainerTable, StitchID: "abcd", Row: "run a"},
+		},
+	}}, filterHistory(records, "abcd"))
+	assert.Empty(t, filterHistory(records, "ab"))
+}
+
+func TestWriteHistory(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	records := []db.TxnRecord{{
+		Revision: 3,
+		Time:     now,
+		Reason:   "api: deploy",
+		Rows: []db.RowDiff{{
+			Table:  db.ClusterTable,
+			ID:     1,
+			Change: db.RowModified,
+			Row:    "Cluster-1{}",
+			Fields: []db.FieldDiff{{Field: "Spec", Old: "a", New: "b"}},
+		}},
+	}}
+
+	var b bytes.Buffer
+	writeHistory(&b, records)
+
+	timeStr := now.Local().Format("2006-01-02 15:04:05")
+	exp := "REVISION    TIME                   REASON         CHANGE    ROW\n" +
+		"3           " + timeStr + "    api: deploy    modify    Cluster-1{}\n" +
+		"                                                                " +
+		"Spec: \"a\" -> \"b\"\n"
+	assert.Equal(t, exp, b.String())
+}
@@ -84,6 +84,10 @@ func TestContainerOutput(t *testing.T) {
 			Command: []string{"cmd", "3", "4"},
 			Labels:  []string{"label1"}},
 		{ID: 5, StitchID: "8", Image: "image1"},
+		{ID: 6, StitchID: "9", Image: "image2", Evicted: "evicted"},
+		{ID: 7, StitchID: "10", Image: "image3",
+			PlacementErrors: map[string][]string{
+				"1.1.1.1": {"label a must run with label b"}}},
 	}
 
 	machines := []db.Machine{
@@ -116,7 +120,9 @@ ________________________________________________________________________________
 ____________________________________________________________________________________
 7____________6__________image1_cmd_3_4____label1____________scheduled_______________
 ____________________________________________________________________________________
+10___________7__________image3______________________________unplaced________________
 8____________7__________image1______________________________________________________
+9____________7__________image2______________________________evicted_________________
 `
 
 	assert.Equal(t, expected, result)
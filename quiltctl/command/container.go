@@ -151,6 +151,10 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			status := dbc.Status
 			if dbc.Status == "" && dbc.Minion != "" {
 				status = "scheduled"
+			} else if dbc.Status == "" && dbc.Evicted != "" {
+				status = "evicted"
+			} else if dbc.Status == "" && len(dbc.PlacementErrors) > 0 {
+				status = "unplaced"
 			}
 			created := ""
 			if !dbc.Created.IsZero() {
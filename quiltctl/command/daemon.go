@@ -5,14 +5,35 @@ import (
 	"fmt"
 
 	"github.com/NetSys/quilt/api/server"
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/cluster"
+	"github.com/NetSys/quilt/cluster/cloudcfg"
+	"github.com/NetSys/quilt/cluster/foreman"
 	"github.com/NetSys/quilt/db"
 	"github.com/NetSys/quilt/engine"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 // Daemon contains the options for running the Quilt daemon.
 type Daemon struct {
 	common *commonFlags
+
+	// The directory in which to persist the database.  If empty, the database is
+	// kept only in memory.
+	dbDir string
+
+	// The address at which to serve the HTTP API.  If empty, it isn't served.
+	httpAddr string
+
+	// The directory in which the certificate authority and the daemon's own
+	// credentials are stored.  If empty, communication with minions is neither
+	// encrypted nor authenticated.
+	tlsDir string
+
+	// The comma-separated resolvers that the minions forward DNS queries outside
+	// of Quilt to.  If empty, each minion uses its host's resolvers.
+	dnsUpstream string
 }
 
 // NewDaemonCommand creates a new Daemon command instance.
@@ -25,8 +46,20 @@ func NewDaemonCommand() *Daemon {
 // InstallFlags sets up parsing for command line flags
 func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
 	dCmd.common.InstallFlags(flags)
+	flags.StringVar(&dCmd.dbDir, "db-dir", "",
+		"directory in which to persist the database across restarts")
+	flags.StringVar(&dCmd.httpAddr, "http", "",
+		"the address at which to serve the HTTP API, e.g. tcp://0.0.0.0:9001")
+	flags.StringVar(&dCmd.tlsDir, "tls-dir", auth.DefaultDir(),
+		"the directory in which to store the credentials used to secure "+
+			"communication with minions, or empty to disable security")
+	flags.StringVar(&dCmd.dnsUpstream, "dns-upstream", "",
+		"the comma-separated resolvers that minions forward DNS queries "+
+			"outside of Quilt to, e.g. 8.8.8.8,8.8.4.4:53")
 	flags.Usage = func() {
-		fmt.Println("usage: quilt daemon [-H=<daemon_host>]")
+		fmt.Println("usage: quilt daemon [-H=<daemon_host>] " +
+			"[-db-dir=<directory>] [-http=<http_host>] " +
+			"[-tls-dir=<directory>] [-dns-upstream=<resolvers>]")
 		fmt.Println("`daemon` starts the quilt daemon, which listens for" +
 			"quilt API requests")
 
@@ -41,9 +74,62 @@ func (dCmd *Daemon) Parse(args []string) error {
 
 // Run starts the daemon.
 func (dCmd *Daemon) Run() int {
-	conn := db.New()
+	var conn db.Conn
+	if dCmd.dbDir == "" {
+		conn = db.New()
+	} else {
+		var err error
+		if conn, err = db.Open(dCmd.dbDir); err != nil {
+			log.WithError(err).Error("Failed to open the database.")
+			return 1
+		}
+	}
+
+	var creds auth.Credentials
+	if dCmd.tlsDir != "" {
+		var err error
+		if creds, err = setupTLS(dCmd.tlsDir); err != nil {
+			log.WithError(err).Error("Failed to set up TLS.")
+			return 1
+		}
+	}
+
+	cloudcfg.SetDNSUpstreams(dnsUpstreams(dCmd.dnsUpstream))
+
 	go engine.Run(conn)
-	go server.Run(conn, dCmd.common.host)
+	go server.Run(conn, dCmd.common.host, creds)
+	if dCmd.httpAddr != "" {
+		go runHTTP(conn, dCmd.httpAddr, creds)
+	}
 	cluster.Run(conn)
 	return 0
 }
+
+// setupTLS makes the daemon the certificate authority of the minions it boots.  It
+// issues credentials for itself, which are also stored in 'dir' so that quiltctl
+// may authenticate to the minions.
+func setupTLS(dir string) (auth.Credentials, error) {
+	ca, err := auth.LoadOrCreateCA(dir)
+	if err != nil {
+		return auth.Credentials{}, err
+	}
+
+	creds, err := ca.IssueDaemon()
+	if err != nil {
+		return auth.Credentials{}, err
+	}
+
+	if err := auth.Save(dir, creds); err != nil {
+		return auth.Credentials{}, err
+	}
+
+	cloudcfg.SetCA(ca)
+	foreman.SetCredentials(creds)
+	return creds, nil
+}
+
+func runHTTP(conn db.Conn, addr string, creds auth.Credentials) {
+	if err := server.RunHTTP(conn, addr, creds); err != nil {
+		log.WithError(err).Error("Failed to serve the HTTP API.")
+	}
+}
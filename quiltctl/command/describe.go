@@ -0,0 +1,125 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/api/client/getter"
+	"github.com/NetSys/quilt/api/util"
+	"github.com/NetSys/quilt/db"
+)
+
+// Describe contains the options for describing a container.
+type Describe struct {
+	targetContainer string
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewDescribeCommand creates a new Describe command instance.
+func NewDescribeCommand() *Describe {
+	return &Describe{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (dCmd *Describe) InstallFlags(flags *flag.FlagSet) {
+	dCmd.common.InstallFlags(flags)
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt describe [-H=<daemon_host>] <stitch_id>")
+		fmt.Println("`describe` displays the details of a container, as " +
+			"recorded by the cluster leader.  If the container couldn't be " +
+			"placed, it shows why each minion was ruled out.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the describe command.
+func (dCmd *Describe) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify a target container")
+	}
+
+	dCmd.targetContainer = args[0]
+	return nil
+}
+
+// Run retrieves and prints the description of the target container.
+func (dCmd *Describe) Run() int {
+	localClient, err := dCmd.clientGetter.Client(dCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer localClient.Close()
+
+	leaderClient, err := dCmd.clientGetter.LeaderClient(localClient)
+	if err != nil {
+		log.WithError(err).Error("Error connecting to leader.")
+		return 1
+	}
+	defer leaderClient.Close()
+
+	container, err := util.GetContainer(leaderClient, dCmd.targetContainer)
+	if err != nil {
+		log.WithError(err).Error("Error getting container information.")
+		return 1
+	}
+
+	writeDescription(os.Stdout, container)
+	return 0
+}
+
+func writeDescription(fd io.Writer, dbc db.Container) {
+	w := tabwriter.NewWriter(fd, 0, 0, 1, ' ', 0)
+	defer w.Flush()
+
+	minion := dbc.Minion
+	if minion == "" {
+		minion = "-"
+	}
+
+	fmt.Fprintf(w, "Stitch ID:\t%s\n", dbc.StitchID)
+	fmt.Fprintf(w, "Command:\t%s\n", containerStr(dbc.Image, dbc.Command))
+	fmt.Fprintf(w, "Labels:\t%s\n", strings.Join(dbc.Labels, ", "))
+	fmt.Fprintf(w, "Minion:\t%s\n", minion)
+	if dbc.Priority != "" {
+		fmt.Fprintf(w, "Priority:\t%s\n", dbc.Priority)
+	}
+	if dbc.Evicted != "" {
+		fmt.Fprintf(w, "Evicted:\t%s\n", dbc.Evicted)
+	}
+
+	if len(dbc.PlacementErrors) == 0 {
+		return
+	}
+
+	var minions []string
+	for ip := range dbc.PlacementErrors {
+		minions = append(minions, ip)
+	}
+	sort.Strings(minions)
+
+	fmt.Fprintln(w, "Placement:")
+	for _, ip := range minions {
+		for i, err := range dbc.PlacementErrors[ip] {
+			if i > 0 {
+				ip = ""
+			}
+			fmt.Fprintf(w, "    %s\t%s\n", ip, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	clientMock "github.com/NetSys/quilt/api/client/mocks"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestDescribeFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewDescribeCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "abc", cmd.targetContainer)
+
+	cmd = NewDescribeCommand()
+	err = parseHelper(cmd, nil)
+	assert.EqualError(t, err, "must specify a target container")
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	localClient := &clientMock.Client{}
+	leaderClient := &clientMock.Client{
+		ContainerReturn: []db.Container{{StitchID: "abcd"}},
+	}
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(localClient, nil)
+	mockGetter.On("LeaderClient", localClient).Return(leaderClient, nil)
+
+	cmd := NewDescribeCommand()
+	cmd.clientGetter = mockGetter
+	cmd.targetContainer = "ab"
+	assert.Equal(t, 0, cmd.Run())
+	mockGetter.AssertExpectations(t)
+
+	cmd.targetContainer = "ef"
+	assert.Equal(t, 1, cmd.Run())
+
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(localClient, nil)
+	mockGetter.On("LeaderClient", localClient).Return(nil, errors.New("err"))
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestWriteDescription(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{
+		StitchID: "abcd",
+		Image:    "nginx",
+		Command:  []string{"run"},
+		Labels:   []string{"web", "public"},
+		Priority: "critical",
+		PlacementErrors: map[string][]string{
+			"2.2.2.2": {"label web must run on region west"},
+			"1.1.1.1": {
+				"needs 2 CPUs, but 1 are free",
+				"label web can't run with label db",
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	writeDescription(&b, dbc)
+
+	exp := `Stitch ID: abcd
+Command:   nginx run
+Labels:    web, public
+Minion:    -
+Priority:  critical
+Placement:
+    1.1.1.1 needs 2 CPUs, but 1 are free
+            label web can't run with label db
+    2.2.2.2 label web must run on region west
+`
+	assert.Equal(t, exp, b.String())
+}
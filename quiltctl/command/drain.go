@@ -0,0 +1,90 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/NetSys/quilt/api"
+	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/api/client/getter"
+)
+
+// Drain contains the options for draining a machine.
+type Drain struct {
+	target string
+	undo   bool
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewDrainCommand creates a new Drain command instance.
+func NewDrainCommand() *Drain {
+	return &Drain{
+		clientGetter: getter.New(),
+		common:       &commonFlags{},
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (dCmd *Drain) InstallFlags(flags *flag.FlagSet) {
+	dCmd.common.InstallFlags(flags)
+	flags.BoolVar(&dCmd.undo, "undo", false,
+		"allow containers to be placed on the machine again")
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt drain [-H=<daemon_host>] [-undo] <machine_id>")
+		fmt.Println("`drain` stops new containers from being placed on a " +
+			"worker machine, and migrates the containers it runs to other " +
+			"machines within the disruption budgets of their labels.  " +
+			"Containers with volumes stay where they are.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the drain command.
+func (dCmd *Drain) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify a target machine")
+	}
+
+	dCmd.target = args[0]
+	return nil
+}
+
+// Run marks the target machine as draining, or clears the mark.
+func (dCmd *Drain) Run() int {
+	localClient, err := dCmd.clientGetter.Client(dCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer localClient.Close()
+
+	m, err := getMachine(localClient, dCmd.target)
+	if err != nil {
+		log.WithError(err).Error("Error getting machine information.")
+		return 1
+	}
+
+	if m.PublicIP == "" {
+		log.Error("The machine hasn't booted yet.")
+		return 1
+	}
+
+	minionClient, err := dCmd.clientGetter.Client(api.RemoteAddress(m.PublicIP))
+	if err != nil {
+		log.WithError(err).Error("Error connecting to machine.")
+		return 1
+	}
+	defer minionClient.Close()
+
+	if err := minionClient.Drain(!dCmd.undo); err != nil {
+		log.WithError(err).Error("Error draining machine.")
+		return 1
+	}
+	return 0
+}
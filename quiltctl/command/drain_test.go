@@ -0,0 +1,59 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	clientMock "github.com/NetSys/quilt/api/client/mocks"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestDrainFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewDrainCommand()
+	err := parseHelper(cmd, []string{"-H", "IP", "-undo", "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IP", cmd.common.host)
+	assert.Equal(t, "abc", cmd.target)
+	assert.True(t, cmd.undo)
+
+	cmd = NewDrainCommand()
+	err = parseHelper(cmd, nil)
+	assert.EqualError(t, err, "must specify a target machine")
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	localClient := &clientMock.Client{
+		MachineReturn: []db.Machine{
+			{StitchID: "abcd", PublicIP: "8.8.8.8"},
+			{StitchID: "efgh"},
+		},
+	}
+	minionClient := &clientMock.Client{}
+
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", "").Return(localClient, nil)
+	mockGetter.On("Client", "tcp://8.8.8.8:9000").Return(minionClient, nil)
+
+	cmd := NewDrainCommand()
+	cmd.clientGetter = mockGetter
+	cmd.target = "ab"
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, true, *minionClient.DrainArg)
+
+	cmd.undo = true
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, false, *minionClient.DrainArg)
+
+	cmd.target = "ef"
+	assert.Equal(t, 1, cmd.Run())
+
+	cmd.target = "ab"
+	minionClient.DrainErr = errors.New("err")
+	assert.Equal(t, 1, cmd.Run())
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/api/client/getter"
+	"github.com/NetSys/quilt/db"
+)
+
+// History contains the options for listing the deployment history.
+type History struct {
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewHistoryCommand creates a new History command instance.
+func NewHistoryCommand() *History {
+	return &History{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (hCmd *History) InstallFlags(flags *flag.FlagSet) {
+	hCmd.common.InstallFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("usage: quilt history [-H=<daemon_host>]")
+		fmt.Println("`history` lists every stitch deployed to the Quilt " +
+			"daemon, by revision.  Any revision may be redeployed with " +
+			"`quilt rollback`.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the history command.
+func (hCmd *History) Parse(args []string) error {
+	return nil
+}
+
+// Run retrieves and prints the deployment history.
+func (hCmd *History) Run() int {
+	c, err := hCmd.clientGetter.Client(hCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	deployments, err := c.QueryDeployments()
+	if err != nil {
+		log.WithError(err).Error("Unable to query deployments.")
+		return 1
+	}
+
+	writeDeployments(os.Stdout, deployments)
+	return 0
+}
+
+func writeDeployments(fd io.Writer, deployments []db.Deployment) {
+	sort.Sort(deploymentsByRevision(deployments))
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "REVISION\tTIME\tMESSAGE")
+
+	for _, d := range deployments {
+		message := d.Message
+		if message == "" {
+			message = "-"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", d.Revision,
+			d.Time.Local().Format("2006-01-02 15:04:05"), message)
+	}
+}
+
+type deploymentsByRevision []db.Deployment
+
+func (ds deploymentsByRevision) Len() int {
+	return len(ds)
+}
+
+func (ds deploymentsByRevision) Swap(i, j int) {
+	ds[i], ds[j] = ds[j], ds[i]
+}
+
+func (ds deploymentsByRevision) Less(i, j int) bool {
+	return ds[i].Revision < ds[j].Revision
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/db"
+)
+
+func TestWriteDeployments(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	deployments := []db.Deployment{
+		{Revision: 2, Time: now},
+		{Revision: 1, Time: now, Message: "initial"},
+	}
+
+	var b bytes.Buffer
+	writeDeployments(&b, deployments)
+
+	timeStr := now.Local().Format("2006-01-02 15:04:05")
+	exp := "REVISION    TIME                   MESSAGE\n" +
+		"1           " + timeStr + "    initial\n" +
+		"2           " + timeStr + "    -\n"
+	assert.Equal(t, exp, b.String())
+}
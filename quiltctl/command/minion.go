@@ -2,15 +2,45 @@ package command
 
 import (
 	"flag"
+	"fmt"
+	"net"
+	"strings"
 
+	"github.com/NetSys/quilt/auth"
 	"github.com/NetSys/quilt/minion"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 // Minion contains the options for running the Quilt minion.
-type Minion struct{}
+type Minion struct {
+	// The address at which to serve the HTTP API.  If empty, it isn't served.
+	httpAddr string
+
+	// The directory containing the minion's credentials.  If empty, the minion
+	// accepts unauthenticated connections.
+	tlsDir string
+
+	// The comma-separated resolvers that DNS queries outside of Quilt are forwarded
+	// to.  If empty, the host's resolvers are used.
+	dnsUpstream string
+}
 
 // InstallFlags sets up parsing for command line flags.
 func (mCmd *Minion) InstallFlags(flags *flag.FlagSet) {
+	flags.StringVar(&mCmd.httpAddr, "http", "",
+		"the address at which to serve the HTTP API, e.g. tcp://0.0.0.0:9001")
+	flags.StringVar(&mCmd.tlsDir, "tls-dir", "",
+		"the directory containing the credentials issued by the daemon")
+	flags.StringVar(&mCmd.dnsUpstream, "dns-upstream", "",
+		"the comma-separated resolvers to forward DNS queries outside of Quilt "+
+			"to, e.g. 8.8.8.8,8.8.4.4:53")
+	flags.Usage = func() {
+		fmt.Println("usage: quilt minion [-http=<http_host>] " +
+			"[-tls-dir=<directory>] [-dns-upstream=<resolvers>]")
+		fmt.Println("`minion` starts the quilt minion.")
+		flags.PrintDefaults()
+	}
 }
 
 // Parse parses the command line arguments for the minion command.
@@ -20,6 +50,36 @@ func (mCmd *Minion) Parse(args []string) error {
 
 // Run starts the minion.
 func (mCmd *Minion) Run() int {
-	minion.Run()
+	var creds auth.Credentials
+	if mCmd.tlsDir != "" {
+		var err error
+		if creds, err = auth.Load(mCmd.tlsDir); err != nil {
+			log.WithError(err).Error("Failed to load credentials.")
+			return 1
+		} else if !creds.Enabled() {
+			log.Errorf("No credentials found in %s.", mCmd.tlsDir)
+			return 1
+		}
+	}
+
+	minion.Run(mCmd.httpAddr, creds, dnsUpstreams(mCmd.dnsUpstream))
 	return 0
 }
+
+// dnsUpstreams parses the comma-separated resolvers in 'flag' into addresses, using
+// the DNS port for those that don't specify one.
+func dnsUpstreams(flag string) []string {
+	var upstreams []string
+	for _, upstream := range strings.Split(flag, ",") {
+		upstream = strings.TrimSpace(upstream)
+		if upstream == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams
+}
@@ -0,0 +1,15 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSUpstreams(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, dnsUpstreams(""))
+	assert.Equal(t, []string{"8.8.8.8:53", "8.8.4.4:5353", "[::1]:53"},
+		dnsUpstreams("8.8.8.8, 8.8.4.4:5353,,::1"))
+}
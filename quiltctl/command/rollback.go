@@ -0,0 +1,99 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/NetSys/quilt/api/client"
+	"github.com/NetSys/quilt/api/client/getter"
+)
+
+// Rollback contains the options for redeploying an earlier revision.
+type Rollback struct {
+	revision int
+	message  string
+
+	common       *commonFlags
+	clientGetter client.Getter
+}
+
+// NewRollbackCommand creates a new Rollback command instance.
+func NewRollbackCommand() *Rollback {
+	return &Rollback{
+		common:       &commonFlags{},
+		clientGetter: getter.New(),
+	}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (rCmd *Rollback) InstallFlags(flags *flag.FlagSet) {
+	rCmd.common.InstallFlags(flags)
+	flags.StringVar(&rCmd.message, "m", "",
+		"the message to record in the deployment history")
+
+	flags.Usage = func() {
+		fmt.Println("usage: quilt rollback [-H=<daemon_host>] [-m=<message>] " +
+			"<revision>")
+		fmt.Println("`rollback` redeploys the stitch deployed at the given " +
+			"revision, as listed by `quilt history`.  The redeployment is " +
+			"recorded as a new revision.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the rollback command.
+func (rCmd *Rollback) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify a revision")
+	}
+
+	revision, err := strconv.Atoi(args[0])
+	if err != nil || revision <= 0 {
+		return fmt.Errorf("malformed revision: %s", args[0])
+	}
+
+	rCmd.revision = revision
+	return nil
+}
+
+// Run redeploys the requested revision.
+func (rCmd *Rollback) Run() int {
+	c, err := rCmd.clientGetter.Client(rCmd.common.host)
+	if err != nil {
+		log.Error(err)
+		return 1
+	}
+	defer c.Close()
+
+	revStr := strconv.Itoa(rCmd.revision)
+	deployments, err := c.QueryDeployments(client.Filter{
+		Fields: map[string][]string{"Revision": {revStr}},
+	})
+	if err != nil {
+		log.WithError(err).Error("Unable to query deployments.")
+		return 1
+	}
+
+	if len(deployments) == 0 {
+		log.Errorf("No deployment with revision %d.", rCmd.revision)
+		return 1
+	}
+
+	message := rCmd.message
+	if message == "" {
+		message = "rollback to revision " + revStr
+	}
+
+	revision, err := c.Deploy(deployments[0].Spec, message)
+	if err != nil {
+		log.WithError(err).Error("Unable to roll back.")
+		return 1
+	}
+
+	fmt.Printf("Redeployed revision %d as revision %d.\n", rCmd.revision, revision)
+	return 0
+}
@@ -0,0 +1,64 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/NetSys/quilt/api/client"
+	clientMock "github.com/NetSys/quilt/api/client/mocks"
+	"github.com/NetSys/quilt/db"
+)
+
+func TestRollbackFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewRollbackCommand()
+	err := parseHelper(cmd, []string{"-m", "msg", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cmd.revision)
+	assert.Equal(t, "msg", cmd.message)
+
+	err = parseHelper(NewRollbackCommand(), []string{})
+	assert.EqualError(t, err, "must specify a revision")
+
+	err = parseHelper(NewRollbackCommand(), []string{"zero"})
+	assert.EqualError(t, err, "malformed revision: zero")
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	c := &clientMock.Client{
+		DeploymentReturn: []db.Deployment{{Revision: 2, Spec: "spec"}},
+		RevisionReturn:   5,
+	}
+	mockGetter := new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+
+	cmd := NewRollbackCommand()
+	cmd.clientGetter = mockGetter
+	cmd.revision = 2
+
+	assert.Equal(t, 0, cmd.Run())
+	assert.Equal(t, []client.Filter{{
+		Fields: map[string][]string{"Revision": {"2"}},
+	}}, c.Filters)
+	assert.Equal(t, "spec", c.DeployArg)
+	assert.Equal(t, "rollback to revision 2", c.MessageArg)
+
+	c = &clientMock.Client{}
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+	assert.Empty(t, c.DeployArg)
+
+	c = &clientMock.Client{DeploymentErr: errors.New("err")}
+	mockGetter = new(clientMock.Getter)
+	mockGetter.On("Client", mock.Anything).Return(c, nil)
+	cmd.clientGetter = mockGetter
+	assert.Equal(t, 1, cmd.Run())
+}
@@ -19,13 +19,17 @@ import (
 
 	"github.com/NetSys/quilt/api/client"
 	"github.com/NetSys/quilt/api/client/getter"
+	"github.com/NetSys/quilt/engine"
+	"github.com/NetSys/quilt/minion"
 	"github.com/NetSys/quilt/stitch"
+	"github.com/NetSys/quilt/util"
 )
 
 // Run contains the options for running Stitches.
 type Run struct {
-	stitch string
-	force  bool
+	stitch  string
+	force   bool
+	message string
 
 	common       *commonFlags
 	clientGetter client.Getter
@@ -45,14 +49,17 @@ func (rCmd *Run) InstallFlags(flags *flag.FlagSet) {
 
 	flags.StringVar(&rCmd.stitch, "stitch", "", "the stitch to run")
 	flags.BoolVar(&rCmd.force, "f", false, "deploy without confirming changes")
+	flags.StringVar(&rCmd.message, "m", "",
+		"the message to record in the deployment history")
 
 	flags.Usage = func() {
-		fmt.Println("usage: quilt run [-H=<daemon_host>] [-f] " +
+		fmt.Println("usage: quilt run [-H=<daemon_host>] [-f] [-m=<message>] " +
 			"[-stitch=<stitch>] <stitch>")
 		fmt.Println("`run` compiles the provided stitch, and sends the " +
 			"result to the Quilt daemon to be executed. Confirmation is " +
 			"required if deploying the stitch would cause changes to an " +
-			"existing cluster. Confirmation can be skipped with the " +
+			"existing cluster, in which case the planned changes are " +
+			"shown first. Confirmation can be skipped with the " +
 			"`-f` flag.")
 		flags.PrintDefaults()
 	}
@@ -74,24 +81,9 @@ var errNoCluster = errors.New("no cluster")
 
 // Run starts the run for the provided Stitch.
 func (rCmd *Run) Run() int {
-	stitchPath := rCmd.stitch
-	compiled, err := stitch.FromFile(stitchPath, stitch.DefaultImportGetter)
-	if err != nil && os.IsNotExist(err) && !filepath.IsAbs(stitchPath) {
-		// Automatically add the ".js" file suffix if it's not provided.
-		if !strings.HasSuffix(stitchPath, ".js") {
-			stitchPath += ".js"
-		}
-		compiled, err = stitch.FromFile(
-			filepath.Join(stitch.GetQuiltPath(), stitchPath),
-			stitch.DefaultImportGetter)
-	}
+	compiled, err := compileStitch(rCmd.stitch)
 	if err != nil {
-		// Print the stacktrace if it's an Otto error.
-		if ottoError, ok := err.(*otto.Error); ok {
-			log.Error(ottoError.String())
-		} else {
-			log.Error(err)
-		}
+		logStitchError(err)
 		return 1
 	}
 	deployment := compiled.String()
@@ -121,6 +113,18 @@ func (rCmd *Run) Run() int {
 		} else {
 			fmt.Println(colorizeDiff(diff))
 		}
+
+		plan, err := c.PlanDeployment(deployment)
+		if err != nil {
+			log.WithError(err).Error("Unable to plan deployment.")
+			return 1
+		}
+
+		if !plan.Empty() {
+			fmt.Println("Planned changes:")
+			fmt.Print(colorizeDiff(formatPlan(plan)))
+		}
+
 		shouldDeploy, err := confirm(os.Stdin, "Continue with deployment?")
 		if err != nil {
 			log.WithError(err).Error("Unable to get user response.")
@@ -133,16 +137,51 @@ func (rCmd *Run) Run() int {
 		}
 	}
 
-	err = c.Deploy(deployment)
+	revision, err := c.Deploy(deployment, rCmd.message)
 	if err != nil {
 		log.WithError(err).Error("Error while starting run.")
 		return 1
 	}
 
-	log.Debug("Successfully started run")
+	log.WithField("revision", revision).Debug("Successfully started run")
 	return 0
 }
 
+// compileStitch compiles the stitch at 'stitchPath'.  If there's no such file, the
+// path is tried relative to the QUILT_PATH, with the ".js" suffix added if it's missing.
+func compileStitch(stitchPath string) (stitch.Stitch, error) {
+	compiled, err := compileStitchFile(stitchPath)
+	if err != nil && os.IsNotExist(err) && !filepath.IsAbs(stitchPath) {
+		// Automatically add the ".js" file suffix if it's not provided.
+		if !strings.HasSuffix(stitchPath, ".js") {
+			stitchPath += ".js"
+		}
+		compiled, err = compileStitchFile(
+			filepath.Join(stitch.GetQuiltPath(), stitchPath))
+	}
+	return compiled, err
+}
+
+// compileStitchFile compiles the stitch in 'filename', checking its schedulability
+// invariants with a simulation of the scheduler.
+func compileStitchFile(filename string) (stitch.Stitch, error) {
+	specStr, err := util.ReadFile(filename)
+	if err != nil {
+		return stitch.Stitch{}, err
+	}
+	return stitch.NewWithScheduler(filename, specStr, stitch.DefaultImportGetter,
+		minion.Schedulable)
+}
+
+func logStitchError(err error) {
+	// Print the stacktrace if it's an Otto error.
+	if ottoError, ok := err.(*otto.Error); ok {
+		log.Error(ottoError.String())
+	} else {
+		log.Error(err)
+	}
+}
+
 func getCurrentDeployment(c client.Client) (stitch.Stitch, error) {
 	clusters, err := c.QueryClusters()
 	if err != nil {
@@ -178,6 +217,51 @@ func diffDeployment(currRaw, newRaw string) (string, error) {
 	return difflib.GetUnifiedDiffString(diff)
 }
 
+// formatPlan describes 'plan' with a line per change.  Additions are prefixed with
+// "+" and removals with "-", so that the result may be colorized like a diff.
+func formatPlan(plan engine.Plan) string {
+	var buf bytes.Buffer
+	for _, m := range plan.Boot {
+		fmt.Fprintf(&buf, "+ boot %s\n", m)
+	}
+	for _, m := range plan.Terminate {
+		fmt.Fprintf(&buf, "- stop %s\n", m)
+	}
+	if len(plan.Keep) > 0 {
+		fmt.Fprintf(&buf, "  keep %d machine(s)\n", len(plan.Keep))
+	}
+
+	for _, acl := range plan.ACL.AddAdmin {
+		fmt.Fprintf(&buf, "+ admin ACL %s\n", acl)
+	}
+	for _, acl := range plan.ACL.RemoveAdmin {
+		fmt.Fprintf(&buf, "- admin ACL %s\n", acl)
+	}
+	for _, ports := range plan.ACL.AddPorts {
+		fmt.Fprintf(&buf, "+ public port %s\n", ports)
+	}
+	for _, ports := range plan.ACL.RemovePorts {
+		fmt.Fprintf(&buf, "- public port %s\n", ports)
+	}
+
+	for _, label := range plan.Containers {
+		name := label.Label
+		if name == "" {
+			name = "<unlabeled>"
+		}
+
+		for _, c := range label.Add {
+			fmt.Fprintf(&buf, "+ %s: start %s (%s)\n", name, c.Image,
+				c.StitchID)
+		}
+		for _, c := range label.Remove {
+			fmt.Fprintf(&buf, "- %s: stop %s (%s)\n", name, c.Image,
+				c.StitchID)
+		}
+	}
+	return buf.String()
+}
+
 func prettifyJSON(toPrettify string) (string, error) {
 	var prettified bytes.Buffer
 	err := json.Indent(&prettified, []byte(toPrettify), "", "\t")
@@ -17,6 +17,7 @@ import (
 
 	clientMock "github.com/NetSys/quilt/api/client/mocks"
 	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/engine"
 	"github.com/NetSys/quilt/stitch"
 	"github.com/NetSys/quilt/util"
 )
@@ -256,6 +257,39 @@ func TestColorize(t *testing.T) {
 	}
 }
 
+func TestFormatPlan(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", formatPlan(engine.Plan{}))
+
+	plan := engine.Plan{
+		Boot:      []db.Machine{{Role: db.Worker, Provider: db.Amazon}},
+		Terminate: []db.Machine{{Role: db.Master, Provider: db.Google}},
+		Keep:      []db.Machine{{}, {}},
+		ACL: engine.ACLPlan{
+			AddAdmin:    []string{"1.2.3.4/32"},
+			RemovePorts: []db.PortRange{{MinPort: 80, MaxPort: 81}},
+		},
+		Containers: []engine.LabelPlan{{
+			Label:  "red",
+			Add:    []db.Container{{StitchID: "1", Image: "nginx"}},
+			Remove: []db.Container{{StitchID: "2", Image: "alpine"}},
+		}, {
+			Add: []db.Container{{StitchID: "3", Image: "ubuntu"}},
+		}},
+	}
+
+	exp := "+ boot " + plan.Boot[0].String() + "\n" +
+		"- stop " + plan.Terminate[0].String() + "\n" +
+		"  keep 2 machine(s)\n" +
+		"+ admin ACL 1.2.3.4/32\n" +
+		"- public port 80-81\n" +
+		"+ red: start nginx (1)\n" +
+		"- red: stop alpine (2)\n" +
+		"+ <unlabeled>: start ubuntu (3)\n"
+	assert.Equal(t, exp, formatPlan(plan))
+}
+
 type confirmTest struct {
 	inputs []string
 	exp    bool
@@ -328,6 +362,7 @@ func TestPromptsUser(t *testing.T) {
 		runCmd.stitch = "test.js"
 		runCmd.Run()
 		assert.Equal(t, confirmResp, c.DeployArg != "")
+		assert.NotEmpty(t, c.PlanArg)
 	}
 }
 
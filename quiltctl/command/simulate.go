@@ -0,0 +1,142 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NetSys/quilt/cluster/machine"
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/minion"
+)
+
+// Simulate contains the options for simulating the placement of a Stitch.
+type Simulate struct {
+	stitch string
+}
+
+// NewSimulateCommand creates a new Simulate command instance.
+func NewSimulateCommand() *Simulate {
+	return &Simulate{}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (sCmd *Simulate) InstallFlags(flags *flag.FlagSet) {
+	flags.Usage = func() {
+		fmt.Println("usage: quilt simulate <stitch>")
+		fmt.Println("`simulate` places the containers of a stitch on its " +
+			"worker machines as the cluster's scheduler would, without " +
+			"booting anything.  It shows the load on each machine, where " +
+			"each container is placed, and why any containers can't be.  " +
+			"It exits with status 1 if a container can't be placed.")
+		flags.PrintDefaults()
+	}
+}
+
+// Parse parses the command line arguments for the simulate command.
+func (sCmd *Simulate) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no spec specified")
+	}
+
+	sCmd.stitch = args[0]
+	return nil
+}
+
+// Run simulates the placement of the provided Stitch.
+func (sCmd *Simulate) Run() int {
+	compiled, err := compileStitch(sCmd.stitch)
+	if err != nil {
+		logStitchError(err)
+		return 1
+	}
+
+	sim := minion.Simulate(compiled)
+	writeSimulation(os.Stdout, sim)
+
+	for _, dbc := range sim.Containers {
+		if dbc.Minion == "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+func writeSimulation(fd io.Writer, sim minion.Simulation) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+
+	containers := db.ContainerSlice(sim.Containers)
+	sort.Sort(containers)
+
+	fmt.Fprintln(w, "MACHINE\tPROVIDER\tREGION\tSIZE\tCONTAINERS\tCPU\tRAM")
+	for _, m := range sim.Minions {
+		var count int
+		var cpu, ram float64
+		for _, dbc := range containers {
+			if dbc.Minion == m.PrivateIP {
+				count++
+				cpu += dbc.CPU
+				ram += dbc.RAM
+			}
+		}
+
+		totalCPU, totalRAM := "-", "-"
+		desc, ok := machine.Lookup(db.Provider(m.Provider), m.Size)
+		if ok {
+			totalCPU = fmt.Sprintf("%d", desc.CPU)
+			totalRAM = fmt.Sprintf("%gGB", desc.RAM)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%g/%s\t%gGB/%s\n", m.PrivateIP,
+			m.Provider, m.Region, m.Size, count, cpu, totalCPU, ram, totalRAM)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "CONTAINER\tMACHINE\tCOMMAND\tLABELS")
+	for _, dbc := range containers {
+		if dbc.Minion != "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", dbc.StitchID, dbc.Minion,
+				containerStr(dbc.Image, dbc.Command),
+				strings.Join(dbc.Labels, ", "))
+		}
+	}
+
+	var unplaced []db.Container
+	for _, dbc := range containers {
+		if dbc.Minion == "" {
+			unplaced = append(unplaced, dbc)
+		}
+	}
+
+	if len(unplaced) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "UNPLACED\tMACHINE\tREASON")
+	for _, dbc := range unplaced {
+		var minions []string
+		for ip := range dbc.PlacementErrors {
+			minions = append(minions, ip)
+		}
+		sort.Strings(minions)
+
+		id := dbc.StitchID
+		if len(minions) == 0 {
+			fmt.Fprintf(w, "%s\t-\tthere are no worker machines\n", id)
+		}
+
+		for _, ip := range minions {
+			for _, err := range dbc.PlacementErrors[ip] {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", id, ip, err)
+				id, ip = "", ""
+			}
+		}
+	}
+}
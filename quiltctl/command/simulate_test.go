@@ -0,0 +1,91 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/NetSys/quilt/db"
+	"github.com/NetSys/quilt/minion"
+	"github.com/NetSys/quilt/util"
+)
+
+func TestSimulateFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewSimulateCommand()
+	assert.NoError(t, parseHelper(cmd, []string{"spec.js"}))
+	assert.Equal(t, "spec.js", cmd.stitch)
+
+	cmd = NewSimulateCommand()
+	assert.EqualError(t, parseHelper(cmd, nil), "no spec specified")
+}
+
+func TestSimulateRun(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	spec := `var c = new Container("nginx").withResources({cpu: 2});
+	deployment.deploy(new Service("web", [c, c.clone()]));
+	deployment.deploy(new Machine({role: "Worker", provider: "Amazon",
+		size: "m4.large"}));`
+	util.WriteFile("/spec.js", []byte(spec), 0644)
+
+	cmd := NewSimulateCommand()
+	cmd.stitch = "/spec.js"
+	assert.Equal(t, 1, cmd.Run())
+
+	util.WriteFile("/spec.js", []byte(`deployment.deploy(new Service("web",
+		[new Container("nginx")]));`), 0644)
+	assert.Equal(t, 1, cmd.Run())
+
+	util.WriteFile("/spec.js", []byte(`deployment.deploy(new Machine({
+		role: "Worker"}));`), 0644)
+	assert.Equal(t, 0, cmd.Run())
+
+	cmd.stitch = "/dne.js"
+	assert.Equal(t, 1, cmd.Run())
+}
+
+func TestWriteSimulation(t *testing.T) {
+	t.Parallel()
+
+	sim := minion.Simulation{
+		Minions: []db.Minion{
+			{PrivateIP: "1", Provider: "Amazon", Region: "us-west-1",
+				Size: "m4.large"},
+			{PrivateIP: "2", Provider: "Vagrant"},
+		},
+		Containers: []db.Container{
+			{StitchID: "a", Minion: "1", Image: "nginx", CPU: 1.5, RAM: 2,
+				Labels: []string{"web"}},
+			{StitchID: "b", Minion: "2", Image: "redis",
+				Labels: []string{"db"}},
+			{StitchID: "c", Image: "mysql",
+				PlacementErrors: map[string][]string{
+					"1": {"needs 1 CPUs, but 0.5 are free",
+						"label db can't run with label web"},
+					"2": {"label db must run on provider Amazon"},
+				}},
+		},
+	}
+
+	var b bytes.Buffer
+	writeSimulation(&b, sim)
+
+	exp := `MACHINE    PROVIDER    REGION       SIZE        CONTAINERS    CPU      RAM
+1          Amazon      us-west-1    m4.large    1             1.5/2    2GB/8GB
+2          Vagrant                              1             0/-      0GB/-
+
+CONTAINER    MACHINE    COMMAND    LABELS
+a            1          nginx      web
+b            2          redis      db
+
+UNPLACED    MACHINE    REASON
+c           1          needs 1 CPUs, but 0.5 are free
+                       label db can't run with label web
+            2          label db must run on provider Amazon
+`
+	assert.Equal(t, exp, b.String())
+}
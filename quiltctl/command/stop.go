@@ -92,7 +92,7 @@ func (sCmd *Stop) Run() int {
 		}
 	}
 
-	if err = c.Deploy(newCluster.String()); err != nil {
+	if _, err = c.Deploy(newCluster.String(), "quilt stop"); err != nil {
 		log.WithError(err).Error("Unable to stop namespace.")
 		return 1
 	}
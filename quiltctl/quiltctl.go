@@ -10,15 +10,21 @@ import (
 )
 
 var commands = map[string]command.SubCommand{
+	"audit":      command.NewAuditCommand(),
 	"containers": command.NewContainerCommand(),
 	"daemon":     command.NewDaemonCommand(),
+	"describe":   command.NewDescribeCommand(),
+	"drain":      command.NewDrainCommand(),
 	"get":        &command.Get{},
+	"history":    command.NewHistoryCommand(),
 	"inspect":    &command.Inspect{},
 	"logs":       command.NewLogCommand(),
 	"machines":   command.NewMachineCommand(),
 	"minion":     &command.Minion{},
 	"ps":         command.NewPsCommand(),
+	"rollback":   command.NewRollbackCommand(),
 	"run":        command.NewRunCommand(),
+	"simulate":   command.NewSimulateCommand(),
 	"ssh":        command.NewSSHCommand(),
 	"stop":       command.NewStopCommand(),
 }
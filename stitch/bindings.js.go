@@ -19,6 +19,13 @@ function Deployment(deploymentOpts) {
     this.namespace = deploymentOpts.namespace || "default-namespace";
     this.adminACL = deploymentOpts.adminACL || [];
 
+    var policy = deploymentOpts.schedulerPolicy;
+    if (policy !== undefined &&
+            !_.contains(["spread", "binpack", "random"], policy)) {
+        throw "unknown scheduler policy: " + policy;
+    }
+    this.schedulerPolicy = policy;
+
     this.machines = [];
     this.containers = {};
     this.services = [];
@@ -96,11 +103,18 @@ Deployment.prototype.toQuiltRepresentation = function() {
             containerMap[container.id] = container;
         });
 
-        services.push({
+        var label = {
             name: service.name,
             ids: ids,
             annotations: service.annotations
-        });
+        };
+        if (service.priority) {
+            label.priority = service.priority;
+        }
+        if (service.disruptionBudget) {
+            label.disruptionBudget = service.disruptionBudget;
+        }
+        services.push(label);
     });
 
     var containers = [];
@@ -118,7 +132,8 @@ Deployment.prototype.toQuiltRepresentation = function() {
 
         namespace: this.namespace,
         adminACL: this.adminACL,
-        maxPrice: this.maxPrice
+        maxPrice: this.maxPrice,
+        schedulerPolicy: this.schedulerPolicy
     };
 };
 
@@ -151,9 +166,9 @@ Deployment.prototype.vet = function() {
         });
 
         if (hasFloatingIp && service.incomingPublic.length
-            && service.containers.length > 1) {
+            && service.containers.length > 1 && !service.ingress) {
             throw service.name + " has a floating IP and multiple containers. " +
-              "This is not yet supported."
+              "This is only supported with enableIngress()."
         }
     });
 };
@@ -204,6 +219,34 @@ Service.prototype.children = function() {
     return res;
 };
 
+// Set the priority class of the service's containers to "low", "normal", "high", or
+// "critical".  Containers of higher priority are placed first, and critical
+// containers that don't otherwise fit evict containers of lower priority.
+Service.prototype.setPriority = function(priority) {
+    if (!_.contains(["low", "normal", "high", "critical"], priority)) {
+        throw "unknown priority class: " + priority;
+    }
+    this.priority = priority;
+};
+
+// Set the number of the service's containers that may be migrated between machines
+// at once when the cluster rebalances or drains machines.  By default, one at a time.
+Service.prototype.setDisruptionBudget = function(budget) {
+    if (!(budget >= 1 && Math.floor(budget) === budget)) {
+        throw "disruption budget must be a positive integer: " + budget;
+    }
+    this.disruptionBudget = budget;
+};
+
+// Balance the service's connections from the public internet across all of its
+// healthy containers.  By default, a machine only forwards them to the service's
+// containers that it runs.  With ingress enabled, any worker that receives a
+// connection, such as one with a floating IP, forwards it to a container of the
+// service on any machine.
+Service.prototype.enableIngress = function() {
+    this.ingress = true;
+};
+
 Service.prototype.annotate = function(annotation) {
     this.annotations.push(annotation);
 };
@@ -232,20 +275,24 @@ Service.prototype.deploy = function(deployment) {
     deployment.services.push(this);
 };
 
-Service.prototype.connect = function(range, to) {
+// Allow the service to connect to "to" on the ports in "range".  If "protocol" is
+// given, only "tcp", "udp", or "icmp" traffic is allowed, otherwise all three are.
+// ICMP connections have no ports, so "range" is ignored for them.
+Service.prototype.connect = function(range, to, protocol) {
     range = boxRange(range);
     if (to === publicInternet) {
-        return this.connectToPublic(range);
+        return this.connectToPublic(range, protocol);
     }
-    this.connections.push(new Connection(range, to));
+    checkNoMapping(range);
+    this.connections.push(new Connection(range, to, protocol));
 };
 
 // publicInternet is an object that looks like another service that can be
 // connected to or from. However, it is actually just syntactic sugar to hide
 // the connectToPublic and connectFromPublic functions.
 var publicInternet = {
-    connect: function(range, to) {
-        to.connectFromPublic(range);
+    connect: function(range, to, protocol) {
+        to.connectFromPublic(range, protocol);
     },
     canReach: function(to) {
         return reachable(publicInternetLabel, to.name);
@@ -253,56 +300,66 @@ var publicInternet = {
 };
 
 // Allow outbound traffic from the service to public internet.
-Service.prototype.connectToPublic = function(range) {
+Service.prototype.connectToPublic = function(range, protocol) {
     range = boxRange(range);
     if (range.min != range.max) {
         throw "public internet cannot connect on port ranges";
     }
-    this.outgoingPublic.push(range);
+    checkNoMapping(range);
+    this.outgoingPublic.push(new Connection(range, publicInternet, protocol));
 };
 
-// Allow inbound traffic from public internet to the service.
-Service.prototype.connectFromPublic = function(range) {
+// Allow inbound traffic from public internet to the service.  If "range" is a
+// PortMapping, traffic to its public port is forwarded to its container port.
+Service.prototype.connectFromPublic = function(range, protocol) {
     range = boxRange(range);
     if (range.min != range.max) {
         throw "public internet cannot connect on port ranges";
     }
-    this.incomingPublic.push(range);
+    this.incomingPublic.push(new Connection(range, publicInternet, protocol));
 };
 
 Service.prototype.place = function(rule) {
     this.placements.push(rule);
 };
 
+// Spread the service's containers across the failure domains given by "key", which
+// is "provider", "region", or "machine".  The number of containers in any two
+// domains never differs by more than "maxSkew", which defaults to 1.
+Service.prototype.spreadAcross = function(key, maxSkew) {
+    if (!_.contains(["provider", "region", "machine"], key)) {
+        throw "cannot spread across unknown key: " + key;
+    }
+    if (maxSkew !== undefined && (!_.isNumber(maxSkew) || maxSkew < 1)) {
+        throw "maxSkew must be a positive number: " + maxSkew;
+    }
+
+    this.placements.push({
+        exclusive: false,
+        spreadAcross: key,
+        maxSkew: maxSkew || 1
+    });
+};
+
 Service.prototype.getQuiltConnections = function() {
     var connections = [];
     var that = this;
 
     this.connections.forEach(function(conn) {
-        connections.push({
-            from: that.name,
-            to: conn.to.name,
-            minPort: conn.minPort,
-            maxPort: conn.maxPort
-        });
+        connections.push(conn.toQuiltRepresentation(that.name, conn.to.name));
     });
 
-    this.outgoingPublic.forEach(function(rng) {
-        connections.push({
-            from: that.name,
-            to: publicInternetLabel,
-            minPort: rng.min,
-            maxPort: rng.max
-        });
+    this.outgoingPublic.forEach(function(conn) {
+        connections.push(conn.toQuiltRepresentation(that.name,
+            publicInternetLabel));
     });
 
-    this.incomingPublic.forEach(function(rng) {
-        connections.push({
-            from: publicInternetLabel,
-            to: that.name,
-            minPort: rng.min,
-            maxPort: rng.max
-        });
+    this.incomingPublic.forEach(function(conn) {
+        var quiltConn = conn.toQuiltRepresentation(publicInternetLabel, that.name);
+        if (that.ingress) {
+            quiltConn.ingress = true;
+        }
+        connections.push(quiltConn);
     });
 
     return connections;
@@ -320,7 +377,9 @@ Service.prototype.getQuiltPlacements = function() {
             provider: placement.provider || "",
             size: placement.size || "",
             region: placement.region || "",
-            floatingIp: placement.floatingIp || ""
+            floatingIp: placement.floatingIp || "",
+            spreadAcross: placement.spreadAcross || "",
+            maxSkew: placement.maxSkew || 0
         });
     });
     return placements;
@@ -415,6 +474,21 @@ function Container(image, command) {
 Container.prototype.clone = function() {
     var cloned = new Container(this.image, _.clone(this.command));
     cloned.env = _.clone(this.env);
+    if (this.volumes) {
+        cloned.volumes = _.map(this.volumes, _.clone);
+    }
+    if (this.cpu) {
+        cloned.cpu = this.cpu;
+    }
+    if (this.ram) {
+        cloned.ram = this.ram;
+    }
+    if (this.healthCheck) {
+        cloned.healthCheck = _.clone(this.healthCheck);
+    }
+    if (this.restartPolicy) {
+        cloned.restartPolicy = this.restartPolicy;
+    }
     return cloned;
 };
 
@@ -438,6 +512,135 @@ Container.prototype.withEnv = function(env) {
     return cloned;
 };
 
+// Create a new Container that reserves "resources.cpu" cores and "resources.ram"
+// gigabytes of memory.  The scheduler only places the container on a machine with
+// enough unreserved capacity, and the container is limited to its reservation.
+Container.prototype.withResources = function(resources) {
+    var cpu = resources.cpu || 0;
+    var ram = resources.ram || 0;
+    if (cpu < 0 || ram < 0) {
+        throw "container resources must be non-negative";
+    }
+
+    var cloned = this.clone();
+    delete cloned.cpu;
+    delete cloned.ram;
+    if (cpu) {
+        cloned.cpu = cpu;
+    }
+    if (ram) {
+        cloned.ram = ram;
+    }
+    return cloned;
+};
+
+// Create a new Container whose health is checked by the minion running it.  Exactly
+// one of "check.command" (an array run inside the container), "check.http" (a port
+// to send an HTTP GET for "check.path"), or "check.tcp" (a port to connect to) must
+// be given.  The probe runs every "check.interval" seconds, and fails if it takes
+// longer than "check.timeout" seconds.  The container becomes healthy after
+// "check.healthyThreshold" successes in a row, and unhealthy after
+// "check.unhealthyThreshold" failures in a row.  Unhealthy containers are removed
+// from DNS, and restarted according to the container's restart policy.
+Container.prototype.withHealthCheck = function(check) {
+    var kinds = _.filter(["command", "http", "tcp"], function(kind) {
+        return check[kind] !== undefined;
+    });
+    if (kinds.length !== 1) {
+        throw "a health check requires exactly one of command, http, or tcp";
+    }
+
+    var healthCheck = {
+        kind: kinds[0],
+        interval: check.interval || 10,
+        timeout: check.timeout || 5,
+        healthyThreshold: check.healthyThreshold || 1,
+        unhealthyThreshold: check.unhealthyThreshold || 3
+    };
+
+    if (healthCheck.kind === "command") {
+        if (!_.isArray(check.command) || check.command.length === 0) {
+            throw "health check command must be a non-empty array";
+        }
+        healthCheck.command = check.command;
+    } else if (healthCheck.kind === "http") {
+        healthCheck.port = checkPort(check.http);
+        healthCheck.path = check.path || "/";
+    } else {
+        healthCheck.port = checkPort(check.tcp);
+    }
+
+    var cloned = this.clone();
+    cloned.healthCheck = healthCheck;
+    return cloned;
+};
+
+function checkPort(port) {
+    if (!_.isNumber(port) || port <= 0 || port > 65535) {
+        throw "health check port must be between 1 and 65535: " + port;
+    }
+    return port;
+}
+
+// Create a new Container with the given restart "policy".  Containers restarted
+// "on-failure" are restarted when they exit with an error or become unhealthy, which
+// is the default.  Containers restarted "always" are also restarted when they exit
+// cleanly, and those restarted "never" are left as they are.
+Container.prototype.withRestartPolicy = function(policy) {
+    if (!_.contains(["always", "on-failure", "never"], policy)) {
+        throw "unknown restart policy: " + policy;
+    }
+
+    var cloned = this.clone();
+    cloned.restartPolicy = policy;
+    return cloned;
+};
+
+// Create a new Container that mounts a Volume at the absolute path "target".
+Container.prototype.mount = function(volume, target, readOnly) {
+    if (!(volume instanceof Volume)) {
+        throw "mount requires a Volume";
+    }
+    if (!target || target[0] !== "/") {
+        throw "volume mount target must be an absolute path: " + target;
+    }
+
+    // Volumes are only set once mounted, so that they don't affect the IDs of
+    // containers without any.
+    var cloned = this.clone();
+    cloned.volumes = cloned.volumes || [];
+    cloned.volumes.push({
+        kind: volume.kind,
+        source: volume.source,
+        target: target,
+        readOnly: readOnly || false
+    });
+    return cloned;
+};
+
+// A Volume is storage that outlives the containers that mount it.  A volume with a
+// "name" is managed by Docker, while one with a "hostPath" exposes a directory of
+// the machine the container runs on.  Either way the data stays on that machine, so
+// containers that mount a volume are pinned to it.
+function Volume(volumeOpts) {
+    if (volumeOpts.name && volumeOpts.hostPath) {
+        throw "a volume cannot have both a name and a hostPath";
+    }
+
+    if (volumeOpts.name) {
+        this.kind = "named";
+        this.source = volumeOpts.name;
+    } else if (volumeOpts.hostPath) {
+        if (volumeOpts.hostPath[0] !== "/") {
+            throw "volume hostPath must be absolute: " + volumeOpts.hostPath;
+        }
+        this.kind = "host";
+        this.source = volumeOpts.hostPath;
+    } else {
+        throw "a volume requires a name or a hostPath";
+    }
+}
+
 var enough = { form: "enough" };
 var between = invariantType("between");
 var neighbor = invariantType("reachDirect");
@@ -488,10 +691,48 @@ function MachineRule(exclusive, optionalArgs) {
     }
 }
 
-function Connection(ports, to) {
+function Connection(ports, to, protocol) {
+    if (protocol !== undefined &&
+        !_.contains(["tcp", "udp", "icmp"], protocol)) {
+        throw "unknown protocol: " + protocol;
+    }
+
     this.minPort = ports.min;
     this.maxPort = ports.max;
     this.to = to;
+    if (protocol === "icmp") {
+        this.minPort = 0;
+        this.maxPort = 0;
+    }
+    if (protocol) {
+        this.protocol = protocol;
+    }
+    if (ports.containerPort !== undefined && protocol !== "icmp") {
+        this.containerPort = ports.containerPort;
+    }
+}
+
+Connection.prototype.toQuiltRepresentation = function(from, to) {
+    var conn = {
+        from: from,
+        to: to,
+        minPort: this.minPort,
+        maxPort: this.maxPort
+    };
+    if (this.protocol) {
+        conn.protocol = this.protocol;
+    }
+    if (this.containerPort) {
+        conn.containerPort = this.containerPort;
+    }
+    return conn;
+};
+
+function checkNoMapping(range) {
+    if (range.containerPort !== undefined) {
+        throw "port mappings are only supported for connections from the " +
+            "public internet";
+    }
 }
 
 function Range(min, max) {
@@ -503,5 +744,17 @@ function Port(p) {
     return new PortRange(p, p);
 }
 
+// A public port whose traffic is forwarded to a different port of the containers it's
+// connected to.  For use with publicInternet.connect.
+function PortMapping(publicPort, containerPort) {
+    if (!_.isNumber(publicPort) || !_.isNumber(containerPort)) {
+        throw "port mappings require a public and a container port";
+    }
+
+    var mapping = new Range(publicPort, publicPort);
+    mapping.containerPort = containerPort;
+    return mapping;
+}
+
 var PortRange = Range;
 `
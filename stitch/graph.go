@@ -23,7 +23,14 @@ type Graph struct {
 	Availability []AvailabilitySet
 	// Constraints on which containers can be placed together.
 	Placement map[string][]string
-	Machines  []Machine
+	// The labels each container must be placed with.
+	Affinity map[string][]string
+	Machines []Machine
+
+	// The stitch the graph was built from, and the scheduler with which
+	// invariants may simulate it.
+	spec        Stitch
+	schedulable Scheduler
 }
 
 // InitializeGraph queries the Stitch to fill in the Graph structure.
@@ -33,7 +40,9 @@ func InitializeGraph(spec Stitch) (Graph, error) {
 		// One global availability set by default.
 		Availability: []AvailabilitySet{{}},
 		Placement:    map[string][]string{},
+		Affinity:     map[string][]string{},
 		Machines:     []Machine{},
+		spec:         spec,
 	}
 
 	for _, label := range spec.Labels {
@@ -57,6 +66,12 @@ func InitializeGraph(spec Stitch) (Graph, error) {
 		}
 	}
 
+	// Affinities are placed last, as separating containers could otherwise pull
+	// apart those that were placed together.
+	if err := g.placeAffinities(); err != nil {
+		return Graph{}, err
+	}
+
 	for _, m := range spec.Machines {
 		g.Machines = append(g.Machines, m)
 	}
@@ -84,7 +99,8 @@ func (g Graph) copyGraph() Graph {
 	newAvail := make([]AvailabilitySet, len(g.Availability))
 	copy(newAvail, g.Availability)
 
-	return Graph{Nodes: newNodes, Availability: newAvail}
+	return Graph{Nodes: newNodes, Availability: newAvail, spec: g.spec,
+		schedulable: g.schedulable}
 }
 
 func (g *Graph) addConnection(from string, to string) error {
@@ -211,6 +211,12 @@ pathsAny:
 }
 
 func schedulabilityImpl(graph Graph, inv invariant) bool {
+	if graph.schedulable != nil {
+		return graph.schedulable(graph.spec) == inv.Target
+	}
+
+	// Without a scheduler, only compare the number of machines to the number of
+	// sets of containers that must be placed apart.
 	machines := graph.Machines
 	avSets := graph.Availability
 	if _, ok := graph.Nodes["public"]; ok {
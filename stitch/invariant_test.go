@@ -2,6 +2,8 @@ package stitch
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func initSpec(src string) (Stitch, error) {
@@ -172,6 +174,39 @@ func TestNested(t *testing.T) {
 	}
 }
 
+func TestAffinity(t *testing.T) {
+	pre := `var a = new Service("a", [new Container("ubuntu")]);
+	var b = new Service("b", [new Container("ubuntu")]);
+	var c = new Service("c", new Container("ubuntu").replicate(2));
+	c.place(new LabelRule(true, c));
+	deployment.deploy([a, b, c]);
+	deployment.deploy(new Machine({role: "Worker"}).replicate(2));`
+
+	// The containers of "c" each need their own machine, and "a" and "b" fit
+	// alongside them.
+	_, err := initSpec(pre + `a.place(new LabelRule(false, c));
+	b.place(new LabelRule(false, a));
+	deployment.assert(enough, true);`)
+	assert.NoError(t, err)
+
+	// Keeping "b" apart from "c" requires a third machine.
+	_, err = initSpec(pre + `a.place(new LabelRule(false, b));
+	b.place(new LabelRule(true, c));
+	deployment.assert(enough, true);`)
+	assert.EqualError(t, err, "invariant failed: enough true")
+
+	_, err = initSpec(pre + `a.place(new LabelRule(false, c));
+	b.place(new LabelRule(true, c));
+	b.place(new LabelRule(false, a));
+	deployment.assert(enough, true);`)
+	assert.EqualError(t, err, "unable to place b on the same machine as a")
+
+	_, err = initSpec(pre + `a.place(new LabelRule(false, c));
+	c.place(new LabelRule(true, a));`)
+	assert.EqualError(t, err, "conflicting placement rules: "+
+		"a must be placed both with and apart from c")
+}
+
 func TestPlacementInvs(t *testing.T) {
 	t.Skip("wait for scheduler, use the new scheduling algorithm")
 	stc := `(label "a" (docker "ubuntu"))
@@ -1,7 +1,10 @@
 package stitch
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // AvailabilitySet represents a set of containers which can be placed together on a VM.
@@ -80,6 +83,13 @@ func (g Graph) findAvailabilitySet(label string) AvailabilitySet {
 // Merge all placement rules such that each label appears as a target only once
 func (g *Graph) addPlacementRule(rule Placement) error {
 	if !rule.Exclusive {
+		if rule.OtherLabel != "" {
+			targetNodes, _ := validateRule(rule, *g)
+			for _, target := range targetNodes {
+				g.Affinity[target] = append(g.Affinity[target],
+					rule.OtherLabel)
+			}
+		}
 		return nil
 	}
 
@@ -190,3 +200,120 @@ func (g *Graph) placeNodes() {
 		}
 	}
 }
+
+// placeAffinities moves each node that must be placed with other labels into an
+// availability set that holds a node of each of them.  Moving a node may break the
+// affinity of another, so nodes are moved until every affinity holds.
+func (g *Graph) placeAffinities() error {
+	var nodes []string
+	for node := range g.Affinity {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	for pass := 0; pass <= len(nodes); pass++ {
+		moved := false
+		for _, node := range nodes {
+			labels := g.Affinity[node]
+			av := g.findAvailabilitySet(node)
+			if g.hasLabels(av, node, labels) {
+				continue
+			}
+
+			dest := g.findAffinitySet(node, labels)
+			if dest == nil {
+				return fmt.Errorf("unable to place %s on the same "+
+					"machine as %s", g.Nodes[node].Label,
+					strings.Join(labels, " and "))
+			}
+
+			av.Remove(node)
+			dest.Insert(node)
+			moved = true
+		}
+
+		if !moved {
+			g.dropEmptyAvailabilitySets()
+			return nil
+		}
+	}
+	return errors.New("unable to satisfy label placement rules")
+}
+
+// findAffinitySet returns an availability set holding a node of each of 'labels',
+// and no node that 'node' must be kept apart from.
+func (g Graph) findAffinitySet(node string, labels []string) AvailabilitySet {
+	for _, av := range g.Availability {
+		if !g.hasLabels(av, node, labels) {
+			continue
+		}
+
+		conflict := false
+		for _, avoid := range g.Placement[node] {
+			if av.Check(avoid) {
+				conflict = true
+				break
+			}
+		}
+
+		if !conflict {
+			return av
+		}
+	}
+	return nil
+}
+
+// hasLabels returns true if 'av' holds a node, other than 'node', of each of 'labels'.
+func (g Graph) hasLabels(av AvailabilitySet, node string, labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for member := range av {
+			if member != node && g.Nodes[member].Label == label {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dropEmptyAvailabilitySets removes the availability sets that no longer hold any
+// nodes, so that they aren't counted as needing a machine.
+func (g *Graph) dropEmptyAvailabilitySets() {
+	var avs []AvailabilitySet
+	for _, av := range g.Availability {
+		if len(av) > 0 {
+			avs = append(avs, av)
+		}
+	}
+	g.Availability = avs
+}
+
+// checkPlacementRules returns an error if any label must be placed both with and
+// apart from another label.
+func checkPlacementRules(placements []Placement) error {
+	exclusive := map[[2]string]struct{}{}
+	for _, p := range placements {
+		if p.Exclusive && p.OtherLabel != "" {
+			exclusive[[2]string{p.TargetLabel, p.OtherLabel}] = struct{}{}
+			exclusive[[2]string{p.OtherLabel, p.TargetLabel}] = struct{}{}
+		}
+	}
+
+	for _, p := range placements {
+		if p.Exclusive || p.OtherLabel == "" {
+			continue
+		}
+
+		if _, ok := exclusive[[2]string{p.TargetLabel, p.OtherLabel}]; ok {
+			return fmt.Errorf("conflicting placement rules: %s must "+
+				"be placed both with and apart from %s",
+				p.TargetLabel, p.OtherLabel)
+		}
+	}
+	return nil
+}
@@ -28,6 +28,8 @@ type Stitch struct {
 	MaxPrice  float64  `json:",omitempty"`
 	Namespace string   `json:",omitempty"`
 
+	SchedulerPolicy string `json:",omitempty"`
+
 	Invariants []invariant `json:",omitempty"`
 }
 
@@ -46,30 +48,172 @@ type Placement struct {
 	Size       string `json:",omitempty"`
 	Region     string `json:",omitempty"`
 	FloatingIP string `json:",omitempty"`
+
+	// Spread Constraint
+	SpreadAcross string `json:",omitempty"`
+	MaxSkew      int    `json:",omitempty"`
 }
 
+// The failure domains that containers may be spread across.
+const (
+	SpreadProvider = "provider"
+	SpreadRegion   = "region"
+	SpreadMachine  = "machine"
+)
+
 // A Container may be instantiated in the stitch and queried by users.
 type Container struct {
 	ID      string            `json:",omitempty"`
 	Image   string            `json:",omitempty"`
 	Command []string          `json:",omitempty"`
 	Env     map[string]string `json:",omitempty"`
+	Volumes []Volume          `json:",omitempty"`
+
+	// The number of cores, and gigabytes of memory, reserved for the container.
+	CPU float64 `json:",omitempty"`
+	RAM float64 `json:",omitempty"`
+
+	HealthCheck   *HealthCheck `json:",omitempty"`
+	RestartPolicy string       `json:",omitempty"`
+}
+
+// A HealthCheck probes a container every Interval seconds.  A container is healthy
+// once HealthyThreshold probes in a row succeed, and unhealthy once
+// UnhealthyThreshold probes in a row fail.  Probes that take longer than Timeout
+// seconds fail.
+type HealthCheck struct {
+	Kind string `json:",omitempty"`
+
+	// The command run inside the container by CommandCheck probes.
+	Command []string `json:",omitempty"`
+
+	// The port probed by HTTPCheck and TCPCheck probes, and the path requested by
+	// HTTPCheck probes.
+	Port int    `json:",omitempty"`
+	Path string `json:",omitempty"`
+
+	Interval           int `json:",omitempty"`
+	Timeout            int `json:",omitempty"`
+	HealthyThreshold   int `json:",omitempty"`
+	UnhealthyThreshold int `json:",omitempty"`
 }
 
+// The kinds of HealthCheck.  Command probes succeed if the command exits with status
+// zero, HTTP probes if the response status is below 400, and TCP probes if a
+// connection can be opened.
+const (
+	CommandCheck = "command"
+	HTTPCheck    = "http"
+	TCPCheck     = "tcp"
+)
+
+// The restart policies of containers.  Containers restarted "on-failure" are
+// restarted if they exit with a non-zero status or become unhealthy, which is the
+// default.  Containers restarted "always" are also restarted if they exit cleanly.
+const (
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+	RestartNever     = "never"
+)
+
+// A Volume is storage mounted into a container at Target.  Named volumes are managed
+// by Docker, while host volumes bind the directory Source of the host machine.
+type Volume struct {
+	Kind     string `json:",omitempty"`
+	Source   string `json:",omitempty"`
+	Target   string `json:",omitempty"`
+	ReadOnly bool   `json:",omitempty"`
+}
+
+// The kinds of Volume.
+const (
+	NamedVolume = "named"
+	HostVolume  = "host"
+)
+
 // A Label represents a logical group of containers.
 type Label struct {
 	Name        string   `json:",omitempty"`
 	IDs         []string `json:",omitempty"`
 	Annotations []string `json:",omitempty"`
+	Priority    string   `json:",omitempty"`
+
+	// The number of the label's containers that may be migrated at once.  Zero
+	// means the default of one.
+	DisruptionBudget int `json:",omitempty"`
+}
+
+// The scheduler policies that deployments may choose.  Spread places each container on
+// the least loaded minion, binpack on the most loaded minion that fits it, and random
+// on any minion that accepts it.
+const (
+	SpreadPolicy  = "spread"
+	BinpackPolicy = "binpack"
+	RandomPolicy  = "random"
+)
+
+// The priority classes of labels.  The scheduler places containers of higher
+// priority first, and evicts containers of lower priority to make room for critical
+// ones.  Labels without a priority are of normal priority.
+const (
+	PriorityLow      = "low"
+	PriorityNormal   = "normal"
+	PriorityHigh     = "high"
+	PriorityCritical = "critical"
+)
+
+// PriorityLevel orders the priority classes, from lowest to highest.
+func PriorityLevel(priority string) int {
+	switch priority {
+	case PriorityLow:
+		return -1
+	case PriorityHigh:
+		return 1
+	case PriorityCritical:
+		return 2
+	default:
+		return 0
+	}
 }
 
 // A Connection allows containers implementing the From label to speak to containers
-// implementing the To label in ports in the range [MinPort, MaxPort]
+// implementing the To label in ports in the range [MinPort, MaxPort].  Connections
+// with a Protocol only allow traffic of that protocol, while the others allow TCP,
+// UDP and ICMP.  ICMP connections have no ports.
+//
+// Connections from the public internet forward their public port, MinPort, to
+// ContainerPort of the To label's containers, or to the same port if it's unset.
+// Without Ingress, a machine only forwards to the containers it runs.  With it, any
+// worker balances the connections it receives across all of the label's healthy
+// containers.
 type Connection struct {
-	From    string `json:",omitempty"`
-	To      string `json:",omitempty"`
-	MinPort int    `json:",omitempty"`
-	MaxPort int    `json:",omitempty"`
+	From          string `json:",omitempty"`
+	To            string `json:",omitempty"`
+	MinPort       int    `json:",omitempty"`
+	MaxPort       int    `json:",omitempty"`
+	Protocol      string `json:",omitempty"`
+	ContainerPort int    `json:",omitempty"`
+	Ingress       bool   `json:",omitempty"`
+}
+
+// The protocols that a Connection may be restricted to.
+const (
+	TCP  = "tcp"
+	UDP  = "udp"
+	ICMP = "icmp"
+)
+
+// PortProtocols returns the protocols with ports that 'protocol' allows: UDP and TCP
+// if it's empty, none for ICMP, or just 'protocol' otherwise.
+func PortProtocols(protocol string) []string {
+	switch protocol {
+	case "":
+		return []string{UDP, TCP}
+	case ICMP:
+		return nil
+	default:
+		return []string{protocol}
+	}
 }
 
 // A ConnectionSlice allows for slices of Collections to be used in joins
@@ -145,8 +289,21 @@ func runSpec(vm *otto.Otto, filename string, spec string) (otto.Value, error) {
 	return run(vm, filename, exec)
 }
 
+// A Scheduler returns true if every container of 'spec' can be placed on its
+// machines.
+type Scheduler func(spec Stitch) bool
+
 // New parses and executes a stitch (in text form), and returns an abstract Dsl handle.
 func New(filename string, specStr string, getter ImportGetter) (Stitch, error) {
+	return NewWithScheduler(filename, specStr, getter, nil)
+}
+
+// NewWithScheduler is like New, but checks the schedulability invariant by running
+// 'schedulable' on the stitch.  If 'schedulable' is nil, the invariant only compares
+// the number of machines to the number of containers that must be placed apart.
+func NewWithScheduler(filename string, specStr string, getter ImportGetter,
+	schedulable Scheduler) (Stitch, error) {
+
 	vm, err := newVM(getter)
 	if err != nil {
 		return Stitch{}, err
@@ -162,6 +319,10 @@ func New(filename string, specStr string, getter ImportGetter) (Stitch, error) {
 	}
 	spec.createPortRules()
 
+	if err := checkPlacementRules(spec.Placements); err != nil {
+		return Stitch{}, err
+	}
+
 	if len(spec.Invariants) == 0 {
 		return spec, nil
 	}
@@ -170,6 +331,7 @@ func New(filename string, specStr string, getter ImportGetter) (Stitch, error) {
 	if err != nil {
 		return Stitch{}, err
 	}
+	graph.schedulable = schedulable
 
 	if err := checkInvariants(graph, spec.Invariants); err != nil {
 		return Stitch{}, err
@@ -216,9 +378,9 @@ func parseContext(vm *otto.Otto) (stc Stitch, err error) {
 }
 
 // createPortRules creates exclusive placement rules such that no two containers
-// listening on the same public port get placed on the same machine.
+// listening on the same public port and protocol get placed on the same machine.
 func (stitch *Stitch) createPortRules() {
-	ports := make(map[int][]string)
+	ports := make(map[string][]string)
 	for _, c := range stitch.Connections {
 		if c.From != PublicInternetLabel && c.To != PublicInternetLabel {
 			continue
@@ -229,8 +391,10 @@ func (stitch *Stitch) createPortRules() {
 			target = c.To
 		}
 
-		min := c.MinPort
-		ports[min] = append(ports[min], target)
+		for _, protocol := range PortProtocols(c.Protocol) {
+			key := fmt.Sprintf("%d/%s", c.MinPort, protocol)
+			ports[key] = append(ports[key], target)
+		}
 	}
 
 	for _, labels := range ports {
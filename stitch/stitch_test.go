@@ -188,6 +188,40 @@ func TestContainer(t *testing.T) {
 		})
 }
 
+func TestPortRules(t *testing.T) {
+	t.Parallel()
+
+	// Services exposing the same public port on different protocols may share a
+	// machine, but not those whose protocols overlap.
+	excludes := func(code string) bool {
+		spec, err := FromJavascript(`var dns = new Service("dns", []);
+		var web = new Service("web", []);
+		deployment.deploy([dns, web]);`+code, DefaultImportGetter)
+		assert.NoError(t, err)
+
+		for _, plcm := range spec.Placements {
+			if plcm.Exclusive && plcm.TargetLabel == "dns" &&
+				plcm.OtherLabel == "web" {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, excludes(`publicInternet.connect(53, dns, "udp");
+	publicInternet.connect(53, web, "tcp");`))
+	assert.True(t, excludes(`publicInternet.connect(53, dns);
+	publicInternet.connect(53, web, "tcp");`))
+	assert.True(t, excludes(`publicInternet.connect(53, dns, "udp");
+	publicInternet.connect(53, web, "udp");`))
+
+	// Only the public port matters, not the port it's forwarded to.
+	assert.False(t, excludes(`publicInternet.connect(80, dns);
+	publicInternet.connect(new PortMapping(8080, 80), web);`))
+	assert.True(t, excludes(`publicInternet.connect(new PortMapping(80, 53), dns);
+	publicInternet.connect(80, web);`))
+}
+
 func TestPlacement(t *testing.T) {
 	t.Parallel()
 
@@ -239,6 +273,29 @@ func TestPlacement(t *testing.T) {
 				FloatingIP:  "xxx.xxx.xxx.xxx",
 			},
 		})
+
+	checkPlacements(t, pre+`target.spreadAcross("region", 2);`+post,
+		[]Placement{
+			{
+				TargetLabel:  "target",
+				SpreadAcross: SpreadRegion,
+				MaxSkew:      2,
+			},
+		})
+
+	checkPlacements(t, pre+`target.spreadAcross("machine");`+post,
+		[]Placement{
+			{
+				TargetLabel:  "target",
+				SpreadAcross: SpreadMachine,
+				MaxSkew:      1,
+			},
+		})
+
+	checkError(t, pre+`target.spreadAcross("rack");`,
+		"cannot spread across unknown key: rack")
+	checkError(t, pre+`target.spreadAcross("region", 0);`,
+		"maxSkew must be a positive number: 0")
 }
 
 func TestLabel(t *testing.T) {
@@ -290,6 +347,36 @@ func TestLabel(t *testing.T) {
 			},
 		})
 
+	checkLabels(t, `var foo = new Service("foo", []);
+	foo.setPriority("critical");
+	deployment.deploy(foo);`,
+		map[string]Label{
+			"foo": {
+				Name:        "foo",
+				IDs:         []string{},
+				Annotations: []string{},
+				Priority:    PriorityCritical,
+			},
+		})
+	checkError(t, `new Service("foo", []).setPriority("urgent");`,
+		"unknown priority class: urgent")
+
+	checkLabels(t, `var foo = new Service("foo", []);
+	foo.setDisruptionBudget(3);
+	deployment.deploy(foo);`,
+		map[string]Label{
+			"foo": {
+				Name:             "foo",
+				IDs:              []string{},
+				Annotations:      []string{},
+				DisruptionBudget: 3,
+			},
+		})
+	checkError(t, `new Service("foo", []).setDisruptionBudget(0);`,
+		"disruption budget must be a positive integer: 0")
+	checkError(t, `new Service("foo", []).setDisruptionBudget(1.5);`,
+		"disruption budget must be a positive integer: 1.5")
+
 	expHostname := "foo.q"
 	checkJavascript(t, `(function() {
 		var foo = new Service("foo", []);
@@ -365,6 +452,160 @@ func TestConnect(t *testing.T) {
 		"public internet cannot connect on port ranges")
 	checkError(t, pre+`publicInternet.connect(new PortRange(80, 81), foo);`,
 		"public internet cannot connect on port ranges")
+
+	checkConnections(t, pre+`foo.connect(53, bar, "udp");
+	foo.connect(80, bar, "icmp");
+	publicInternet.connect(53, foo, "tcp");`,
+		[]Connection{
+			{
+				From:     "foo",
+				To:       "bar",
+				MinPort:  53,
+				MaxPort:  53,
+				Protocol: UDP,
+			},
+			{
+				From:     "foo",
+				To:       "bar",
+				Protocol: ICMP,
+			},
+			{
+				From:     "public",
+				To:       "foo",
+				MinPort:  53,
+				MaxPort:  53,
+				Protocol: TCP,
+			},
+		})
+	checkError(t, pre+`foo.connect(80, bar, "sctp");`, "unknown protocol: sctp")
+
+	checkConnections(t, pre+`publicInternet.connect(new PortMapping(8080, 80), foo);`,
+		[]Connection{
+			{
+				From:          "public",
+				To:            "foo",
+				MinPort:       8080,
+				MaxPort:       8080,
+				ContainerPort: 80,
+			},
+		})
+	checkError(t, pre+`foo.connect(new PortMapping(8080, 80), bar);`,
+		"port mappings are only supported for connections from the public "+
+			"internet")
+	checkError(t, pre+`foo.connect(new PortMapping(8080, 80), publicInternet);`,
+		"port mappings are only supported for connections from the public "+
+			"internet")
+	checkError(t, pre+`publicInternet.connect(new PortMapping(8080), foo);`,
+		"port mappings require a public and a container port")
+}
+
+func TestVolumes(t *testing.T) {
+	t.Parallel()
+
+	code := `var plain = new Container("mysql");
+	var c = plain.mount(new Volume({name: "data"}), "/var/lib/mysql")
+		.mount(new Volume({hostPath: "/etc/mysql"}), "/etc/mysql", true);
+	deployment.deploy(new Service("db", c.replicate(2)));
+	deployment.deploy(new Service("plain", [plain]));`
+
+	stc, err := FromJavascript(code, DefaultImportGetter)
+	assert.NoError(t, err)
+	assert.Len(t, stc.Containers, 3)
+
+	// Mounting a volume returns a new container, leaving the original unchanged.
+	var mounted int
+	for _, c := range stc.Containers {
+		if len(c.Volumes) == 0 {
+			continue
+		}
+
+		mounted++
+		assert.Equal(t, []Volume{{
+			Kind:   NamedVolume,
+			Source: "data",
+			Target: "/var/lib/mysql",
+		}, {
+			Kind:     HostVolume,
+			Source:   "/etc/mysql",
+			Target:   "/etc/mysql",
+			ReadOnly: true,
+		}}, c.Volumes)
+	}
+	assert.Equal(t, 2, mounted)
+
+	checkError(t, `new Volume({})`, "a volume requires a name or a hostPath")
+	checkError(t, `new Volume({name: "a", hostPath: "/a"})`,
+		"a volume cannot have both a name and a hostPath")
+	checkError(t, `new Volume({hostPath: "a"})`,
+		"volume hostPath must be absolute: a")
+	checkError(t, `new Container("a").mount(new Volume({name: "a"}), "a")`,
+		"volume mount target must be an absolute path: a")
+	checkError(t, `new Container("a").mount("a", "/a")`,
+		"mount requires a Volume")
+}
+
+func TestResources(t *testing.T) {
+	t.Parallel()
+
+	code := `var c = new Container("a").withResources({cpu: 2, ram: 0.5});
+	deployment.deploy(new Service("a", [c, new Container("b")]));`
+
+	stc, err := FromJavascript(code, DefaultImportGetter)
+	assert.NoError(t, err)
+	assert.Len(t, stc.Containers, 2)
+	for _, c := range stc.Containers {
+		switch c.Image {
+		case "a":
+			assert.Equal(t, 2.0, c.CPU)
+			assert.Equal(t, 0.5, c.RAM)
+		case "b":
+			assert.Zero(t, c.CPU)
+			assert.Zero(t, c.RAM)
+		}
+	}
+
+	checkError(t, `new Container("a").withResources({cpu: -1})`,
+		"container resources must be non-negative")
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	code := `var c = new Container("a").withHealthCheck({
+		http: 80, path: "/health", interval: 5, unhealthyThreshold: 2
+	}).withRestartPolicy("always");
+	deployment.deploy(new Service("a", [c, new Container("b")]));`
+
+	stc, err := FromJavascript(code, DefaultImportGetter)
+	assert.NoError(t, err)
+	assert.Len(t, stc.Containers, 2)
+	for _, c := range stc.Containers {
+		switch c.Image {
+		case "a":
+			assert.Equal(t, &HealthCheck{
+				Kind:               HTTPCheck,
+				Port:               80,
+				Path:               "/health",
+				Interval:           5,
+				Timeout:            5,
+				HealthyThreshold:   1,
+				UnhealthyThreshold: 2,
+			}, c.HealthCheck)
+			assert.Equal(t, RestartAlways, c.RestartPolicy)
+		case "b":
+			assert.Nil(t, c.HealthCheck)
+			assert.Empty(t, c.RestartPolicy)
+		}
+	}
+
+	checkError(t, `new Container("a").withHealthCheck({tcp: 80, http: 80})`,
+		"a health check requires exactly one of command, http, or tcp")
+	checkError(t, `new Container("a").withHealthCheck({command: "true"})`,
+		"health check command must be a non-empty array")
+	checkError(t, `new Container("a").withHealthCheck({tcp: 0})`,
+		"health check port must be between 1 and 65535: 0")
+	checkError(t, `new Container("a").withRestartPolicy("sometimes")`,
+		"unknown restart policy: sometimes")
 }
 
 func TestVet(t *testing.T) {
@@ -388,8 +629,33 @@ func TestVet(t *testing.T) {
 		}));
 		foo.connectFromPublic(80);
 		deployment.deploy([foo]);
-	`, "foo has a floating IP and multiple containers. This is "+
-		"not yet supported.")
+	`, "foo has a floating IP and multiple containers. This is only "+
+		"supported with enableIngress().")
+
+	checkConnections(t, `
+		var foo = new Service("foo", new Container("image").replicate(2));
+		foo.place(new MachineRule(false, {
+			floatingIp: "123",
+		}));
+		foo.enableIngress();
+		foo.connectFromPublic(80);
+		foo.connectToPublic(443);
+		deployment.deploy([foo]);
+	`, []Connection{
+		{
+			From:    "foo",
+			To:      "public",
+			MinPort: 443,
+			MaxPort: 443,
+		},
+		{
+			From:    "public",
+			To:      "foo",
+			MinPort: 80,
+			MaxPort: 80,
+			Ingress: true,
+		},
+	})
 }
 
 func TestCustomDeploy(t *testing.T) {
@@ -457,6 +723,9 @@ func TestQuery(t *testing.T) {
 	adminACLChecker := queryChecker(func(handle Stitch) interface{} {
 		return handle.AdminACL
 	})
+	policyChecker := queryChecker(func(handle Stitch) interface{} {
+		return handle.SchedulerPolicy
+	})
 
 	namespaceChecker(t, `createDeployment({namespace: "myNamespace"});`,
 		"myNamespace")
@@ -465,6 +734,12 @@ func TestQuery(t *testing.T) {
 	maxPriceChecker(t, ``, 0.0)
 	adminACLChecker(t, `createDeployment({adminACL: ["local"]});`, []string{"local"})
 	adminACLChecker(t, ``, []string{})
+	policyChecker(t, `createDeployment({schedulerPolicy: "binpack"});`,
+		BinpackPolicy)
+	policyChecker(t, ``, "")
+
+	checkError(t, `createDeployment({schedulerPolicy: "fastest"});`,
+		"unknown scheduler policy: fastest")
 }
 
 func TestMarshal(t *testing.T) {